@@ -0,0 +1,205 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/autoscaling"
+	astypes "github.com/aws/aws-sdk-go-v2/service/autoscaling/types"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"go.uber.org/zap"
+
+	"firefly-ec2-drift-detector/models"
+)
+
+// maxAutoScalingGroupNames is the number of names DescribeAutoScalingGroups
+// accepts in a single call.
+const maxAutoScalingGroupNames = 100
+
+type AutoScalingClient interface {
+	DescribeAutoScalingGroups(ctx context.Context, params *autoscaling.DescribeAutoScalingGroupsInput, optFns ...func(*autoscaling.Options)) (*autoscaling.DescribeAutoScalingGroupsOutput, error)
+}
+
+// GetAutoScalingGroupStates fetches Auto Scaling groups by name together with
+// their members, the live state of each member and the version numbers of
+// the launch templates they use. Groups that do not exist are absent from the
+// result.
+func (p *EC2StateProvider) GetAutoScalingGroupStates(ctx context.Context, names []string) (map[string]*models.AutoScalingGroupState, error) {
+	if p.client.autoScalingClient == nil {
+		return nil, errors.New("auto scaling client is not configured")
+	}
+
+	p.client.logger.Info("fetching auto scaling group states from AWS",
+		zap.Int("group_count", len(names)),
+	)
+
+	states := make(map[string]*models.AutoScalingGroupState, len(names))
+
+	for i := 0; i < len(names); i += maxAutoScalingGroupNames {
+		end := min(i+maxAutoScalingGroupNames, len(names))
+
+		input := &autoscaling.DescribeAutoScalingGroupsInput{
+			AutoScalingGroupNames: names[i:end],
+		}
+
+		for {
+			<-p.rateLimiter.C
+
+			result, err := p.client.autoScalingClient.DescribeAutoScalingGroups(ctx, input)
+			if err != nil {
+				return nil, classifyError("auto-scaling-groups", err)
+			}
+
+			for _, group := range result.AutoScalingGroups {
+				state := mapToAutoScalingGroupState(group)
+				states[state.Name] = state
+			}
+
+			if aws.ToString(result.NextToken) == "" {
+				break
+			}
+			input.NextToken = result.NextToken
+		}
+	}
+
+	if err := p.describeLaunchTemplates(ctx, states); err != nil {
+		return nil, err
+	}
+
+	if err := p.describeMembers(ctx, states); err != nil {
+		return nil, err
+	}
+
+	return states, nil
+}
+
+func mapToAutoScalingGroupState(group astypes.AutoScalingGroup) *models.AutoScalingGroupState {
+	state := &models.AutoScalingGroupState{
+		Name:            aws.ToString(group.AutoScalingGroupName),
+		MinSize:         int(aws.ToInt32(group.MinSize)),
+		MaxSize:         int(aws.ToInt32(group.MaxSize)),
+		DesiredCapacity: int(aws.ToInt32(group.DesiredCapacity)),
+	}
+
+	switch {
+	case group.LaunchTemplate != nil:
+		state.LaunchTemplate = mapLaunchTemplateSpec(group.LaunchTemplate)
+	case group.MixedInstancesPolicy != nil && group.MixedInstancesPolicy.LaunchTemplate != nil:
+		state.LaunchTemplate = mapLaunchTemplateSpec(group.MixedInstancesPolicy.LaunchTemplate.LaunchTemplateSpecification)
+	}
+
+	for _, instance := range group.Instances {
+		lifecycle := string(instance.LifecycleState)
+		// Instances on their way out are expected to differ.
+		if strings.HasPrefix(lifecycle, "Terminat") {
+			continue
+		}
+
+		state.Instances = append(state.Instances, models.AutoScalingInstance{
+			InstanceID:     aws.ToString(instance.InstanceId),
+			LifecycleState: lifecycle,
+			LaunchTemplate: mapLaunchTemplateSpec(instance.LaunchTemplate),
+		})
+	}
+
+	return state
+}
+
+func mapLaunchTemplateSpec(spec *astypes.LaunchTemplateSpecification) models.LaunchTemplateSpec {
+	if spec == nil {
+		return models.LaunchTemplateSpec{}
+	}
+	return models.LaunchTemplateSpec{
+		ID:      aws.ToString(spec.LaunchTemplateId),
+		Name:    aws.ToString(spec.LaunchTemplateName),
+		Version: aws.ToString(spec.Version),
+	}
+}
+
+// describeLaunchTemplates attaches each group's live launch template, which
+// is needed to resolve $Latest and $Default to version numbers.
+func (p *EC2StateProvider) describeLaunchTemplates(ctx context.Context, states map[string]*models.AutoScalingGroupState) error {
+	var ids []string
+	seen := make(map[string]bool)
+	for _, state := range states {
+		id := state.LaunchTemplate.ID
+		if id != "" && !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	templates := make(map[string]*models.LaunchTemplateState, len(ids))
+	input := &ec2.DescribeLaunchTemplatesInput{LaunchTemplateIds: ids}
+
+	for {
+		<-p.rateLimiter.C
+
+		result, err := p.client.ec2Client.DescribeLaunchTemplates(ctx, input)
+		if err != nil {
+			return classifyError("launch-templates", err)
+		}
+
+		for _, lt := range result.LaunchTemplates {
+			id := aws.ToString(lt.LaunchTemplateId)
+			templates[id] = &models.LaunchTemplateState{
+				ID:             id,
+				Name:           aws.ToString(lt.LaunchTemplateName),
+				LatestVersion:  int(aws.ToInt64(lt.LatestVersionNumber)),
+				DefaultVersion: int(aws.ToInt64(lt.DefaultVersionNumber)),
+			}
+		}
+
+		if aws.ToString(result.NextToken) == "" {
+			break
+		}
+		input.NextToken = result.NextToken
+	}
+
+	for _, state := range states {
+		state.Template = templates[state.LaunchTemplate.ID]
+	}
+
+	return nil
+}
+
+// describeMembers fills in the live state of every group member. Members that
+// cannot be described keep a nil State and are only checked for their
+// launch template version.
+func (p *EC2StateProvider) describeMembers(ctx context.Context, states map[string]*models.AutoScalingGroupState) error {
+	var ids []string
+	for _, state := range states {
+		for _, member := range state.Instances {
+			ids = append(ids, member.InstanceID)
+		}
+	}
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	instances, err := p.GetInstanceStatesBatch(ctx, ids)
+	if err != nil {
+		if len(instances) == 0 {
+			return fmt.Errorf("failed to describe auto scaling group members: %w", err)
+		}
+		p.client.logger.Warn("some auto scaling group members could not be described",
+			zap.Error(err),
+		)
+	}
+
+	for _, state := range states {
+		for i := range state.Instances {
+			state.Instances[i].State = instances[state.Instances[i].InstanceID]
+		}
+	}
+
+	return nil
+}
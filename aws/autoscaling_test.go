@@ -0,0 +1,110 @@
+package aws
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/autoscaling"
+	astypes "github.com/aws/aws-sdk-go-v2/service/autoscaling/types"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+type MockAutoScalingClient struct {
+	DescribeAutoScalingGroupsFunc func(ctx context.Context, params *autoscaling.DescribeAutoScalingGroupsInput, optFns ...func(*autoscaling.Options)) (*autoscaling.DescribeAutoScalingGroupsOutput, error)
+}
+
+func (m *MockAutoScalingClient) DescribeAutoScalingGroups(ctx context.Context, params *autoscaling.DescribeAutoScalingGroupsInput, optFns ...func(*autoscaling.Options)) (*autoscaling.DescribeAutoScalingGroupsOutput, error) {
+	if m.DescribeAutoScalingGroupsFunc == nil {
+		return &autoscaling.DescribeAutoScalingGroupsOutput{}, nil
+	}
+	return m.DescribeAutoScalingGroupsFunc(ctx, params, optFns...)
+}
+
+func TestEC2StateProvider_GetAutoScalingGroupStates(t *testing.T) {
+	spec := func(version string) *astypes.LaunchTemplateSpecification {
+		return &astypes.LaunchTemplateSpecification{
+			LaunchTemplateId:   aws.String("lt-1"),
+			LaunchTemplateName: aws.String("web"),
+			Version:            aws.String(version),
+		}
+	}
+
+	asgClient := &MockAutoScalingClient{
+		DescribeAutoScalingGroupsFunc: func(ctx context.Context, params *autoscaling.DescribeAutoScalingGroupsInput, optFns ...func(*autoscaling.Options)) (*autoscaling.DescribeAutoScalingGroupsOutput, error) {
+			return &autoscaling.DescribeAutoScalingGroupsOutput{
+				AutoScalingGroups: []astypes.AutoScalingGroup{{
+					AutoScalingGroupName: aws.String("web-asg"),
+					MinSize:              aws.Int32(1),
+					MaxSize:              aws.Int32(3),
+					LaunchTemplate:       spec("$Latest"),
+					Instances: []astypes.Instance{
+						{InstanceId: aws.String("i-1"), LifecycleState: astypes.LifecycleStateInService, LaunchTemplate: spec("3")},
+						{InstanceId: aws.String("i-2"), LifecycleState: astypes.LifecycleStateInService, LaunchTemplate: spec("2")},
+						{InstanceId: aws.String("i-3"), LifecycleState: astypes.LifecycleStateTerminating, LaunchTemplate: spec("1")},
+					},
+				}},
+			}, nil
+		},
+	}
+
+	ec2Client := &MockEC2Client{
+		DescribeLaunchTemplatesFunc: func(ctx context.Context, params *ec2.DescribeLaunchTemplatesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeLaunchTemplatesOutput, error) {
+			return &ec2.DescribeLaunchTemplatesOutput{
+				LaunchTemplates: []types.LaunchTemplate{{
+					LaunchTemplateId:     aws.String("lt-1"),
+					LaunchTemplateName:   aws.String("web"),
+					LatestVersionNumber:  aws.Int64(3),
+					DefaultVersionNumber: aws.Int64(1),
+				}},
+			}, nil
+		},
+		DescribeInstancesFunc: func(ctx context.Context, params *ec2.DescribeInstancesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInstancesOutput, error) {
+			if len(params.InstanceIds) != 2 {
+				t.Errorf("expected terminating members to be skipped, got %v", params.InstanceIds)
+			}
+			return &ec2.DescribeInstancesOutput{
+				Reservations: []types.Reservation{{
+					Instances: []types.Instance{
+						{InstanceId: aws.String("i-1"), InstanceType: types.InstanceTypeT3Micro},
+					},
+				}},
+			}, nil
+		},
+	}
+
+	provider := NewStateProvider(newTestAWSClient(ec2Client, WithAutoScalingClient(asgClient)))
+
+	states, err := provider.GetAutoScalingGroupStates(context.Background(), []string{"web-asg", "gone"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	group := states["web-asg"]
+	if group == nil || len(states) != 1 {
+		t.Fatalf("expected only web-asg, got %v", states)
+	}
+
+	if group.LaunchTemplate.Version != "$Latest" || group.Template == nil || group.Template.LatestVersion != 3 {
+		t.Errorf("unexpected launch template: %+v, %+v", group.LaunchTemplate, group.Template)
+	}
+
+	if len(group.Instances) != 2 {
+		t.Fatalf("expected 2 members, got %d", len(group.Instances))
+	}
+	if group.Instances[0].State == nil || group.Instances[0].State.InstanceType != "t3.micro" {
+		t.Errorf("expected i-1 to be described, got %+v", group.Instances[0].State)
+	}
+	if group.Instances[1].State != nil {
+		t.Errorf("expected i-2 to have no state, got %+v", group.Instances[1].State)
+	}
+}
+
+func TestEC2StateProvider_GetAutoScalingGroupStates_NotConfigured(t *testing.T) {
+	provider := NewStateProvider(newTestAWSClient(&MockEC2Client{}))
+
+	if _, err := provider.GetAutoScalingGroupStates(context.Background(), []string{"web-asg"}); err == nil {
+		t.Error("expected an error without an auto scaling client")
+	}
+}
@@ -6,16 +6,40 @@ import (
 )
 
 type AWSClient struct {
-	_         struct{}
-	region    string
-	logger    *flog.Logger
-	ec2Client EC2Client
+	_                 struct{}
+	region            string
+	logger            *flog.Logger
+	ec2Client         EC2Client
+	autoScalingClient AutoScalingClient
+	observer          APIObserver
 }
 
-func NewAWSClient(ctx context.Context, region string, ec2Client EC2Client, logger *flog.Logger) (*AWSClient, error) {
-	return &AWSClient{
+// ClientOption configures optional service clients and hooks on an
+// AWSClient.
+type ClientOption func(*AWSClient)
+
+// WithAutoScalingClient enables Auto Scaling group lookups.
+func WithAutoScalingClient(client AutoScalingClient) ClientOption {
+	return func(c *AWSClient) {
+		c.autoScalingClient = client
+	}
+}
+
+func NewAWSClient(ctx context.Context, region string, ec2Client EC2Client, logger *flog.Logger, opts ...ClientOption) (*AWSClient, error) {
+	client := &AWSClient{
 		region:    region,
 		logger:    logger,
 		ec2Client: ec2Client,
-	}, nil
+	}
+	for _, opt := range opts {
+		opt(client)
+	}
+
+	if client.observer != nil {
+		client.ec2Client = observedEC2Client{client: client.ec2Client, observer: client.observer}
+		if client.autoScalingClient != nil {
+			client.autoScalingClient = observedAutoScalingClient{client: client.autoScalingClient, observer: client.observer}
+		}
+	}
+	return client, nil
 }
@@ -4,13 +4,17 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"maps"
 	"math"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 
 	"firefly-ec2-drift-detector/models"
@@ -27,6 +31,10 @@ const (
 type (
 	EC2Client interface {
 		DescribeInstances(ctx context.Context, params *ec2.DescribeInstancesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInstancesOutput, error)
+		DescribeVolumes(ctx context.Context, params *ec2.DescribeVolumesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeVolumesOutput, error)
+		DescribeSecurityGroups(ctx context.Context, params *ec2.DescribeSecurityGroupsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSecurityGroupsOutput, error)
+		DescribeSecurityGroupRules(ctx context.Context, params *ec2.DescribeSecurityGroupRulesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSecurityGroupRulesOutput, error)
+		DescribeLaunchTemplates(ctx context.Context, params *ec2.DescribeLaunchTemplatesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeLaunchTemplatesOutput, error)
 	}
 
 	EC2Error struct {
@@ -56,6 +64,31 @@ type EC2StateProvider struct {
 	rateLimiter *time.Ticker
 }
 
+// FetchOption tunes what an instance fetch describes.
+type FetchOption func(*fetchOptions)
+
+type fetchOptions struct {
+	rootVolumes bool
+}
+
+// WithRootVolumes also describes the root volume of each instance.
+// DescribeInstances leaves out its size, type, IOPS, throughput and
+// encryption, and filling them in costs a DescribeVolumes call, so it is
+// only worth asking for when a RootBlockDevice attribute is compared.
+func WithRootVolumes() FetchOption {
+	return func(o *fetchOptions) {
+		o.rootVolumes = true
+	}
+}
+
+func newFetchOptions(opts []FetchOption) fetchOptions {
+	var o fetchOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
 func NewStateProvider(client *AWSClient) *EC2StateProvider {
 	return &EC2StateProvider{
 		client:      client,
@@ -63,11 +96,29 @@ func NewStateProvider(client *AWSClient) *EC2StateProvider {
 	}
 }
 
-func (p *EC2StateProvider) GetInstanceState(ctx context.Context, instanceID string) (*models.InstanceState, error) {
+func (p *EC2StateProvider) GetInstanceState(ctx context.Context, instanceID string, opts ...FetchOption) (*models.InstanceState, error) {
+	state, err := p.describeInstance(ctx, instanceID)
+	if err != nil {
+		return nil, err
+	}
+	if newFetchOptions(opts).rootVolumes {
+		p.enrichRootVolumes(ctx, []*models.InstanceState{state})
+	}
+	return state, nil
+}
+
+// describeInstance describes one instance, retrying errors AWS may not
+// return again.
+func (p *EC2StateProvider) describeInstance(ctx context.Context, instanceID string) (*models.InstanceState, error) {
 	p.client.logger.Info("fetching instance state from AWS",
 		zap.String("instance_id", instanceID),
 	)
 
+	// One span covers every attempt; retries are events on it.
+	ctx, span := startAPISpan(ctx, "EC2", "DescribeInstances",
+		attribute.String("aws.ec2.instance_id", instanceID))
+	defer span.End()
+
 	var lastErr error
 	backoff := initialBackoff
 
@@ -78,15 +129,23 @@ func (p *EC2StateProvider) GetInstanceState(ctx context.Context, instanceID stri
 				zap.Int("attempt", attempt),
 				zap.Duration("backoff", backoff),
 			)
+			p.client.retrying("DescribeInstances", attempt, lastErr)
+			span.AddEvent("retry", trace.WithAttributes(
+				attribute.Int("attempt", attempt),
+				attribute.String("backoff", backoff.String()),
+				attribute.String("error", lastErr.Error()),
+			))
 
 			select {
 			case <-ctx.Done():
-				return nil, &EC2Error{
+				err := &EC2Error{
 					InstanceID:  instanceID,
 					Err:         ctx.Err(),
 					IsRetryable: false,
 					ErrorType:   ErrorTypeNetwork,
 				}
+				recordError(span, err)
+				return nil, err
 			case <-time.After(backoff):
 			}
 		}
@@ -95,6 +154,7 @@ func (p *EC2StateProvider) GetInstanceState(ctx context.Context, instanceID stri
 
 		state, err := p.fetchInstanceState(ctx, instanceID)
 		if err == nil {
+			span.SetAttributes(attribute.Int("aws.attempts", attempt+1))
 			return state, nil
 		}
 
@@ -111,6 +171,8 @@ func (p *EC2StateProvider) GetInstanceState(ctx context.Context, instanceID stri
 				zap.String("error_type", string(ec2Err.ErrorType)),
 				zap.Error(ec2Err.Err),
 			)
+			span.SetAttributes(attribute.Int("aws.attempts", attempt+1))
+			recordError(span, ec2Err)
 			return nil, ec2Err
 		}
 
@@ -128,12 +190,15 @@ func (p *EC2StateProvider) GetInstanceState(ctx context.Context, instanceID stri
 		zap.Int("attempts", maxRetries+1),
 	)
 
-	return nil, &EC2Error{
+	err := &EC2Error{
 		InstanceID:  instanceID,
 		Err:         fmt.Errorf("max retries exceeded: %w", lastErr),
 		IsRetryable: false,
 		ErrorType:   ErrorTypeUnknown,
 	}
+	span.SetAttributes(attribute.Int("aws.attempts", maxRetries+1))
+	recordError(span, err)
+	return nil, err
 }
 
 func (p *EC2StateProvider) fetchInstanceState(ctx context.Context, instanceID string) (*models.InstanceState, error) {
@@ -169,7 +234,9 @@ func (p *EC2StateProvider) fetchInstanceState(ctx context.Context, instanceID st
 	return state, nil
 }
 
-func (p *EC2StateProvider) GetInstanceStatesBatch(ctx context.Context, instanceIDs []string) (map[string]*models.InstanceState, error) {
+func (p *EC2StateProvider) GetInstanceStatesBatch(ctx context.Context, instanceIDs []string, opts ...FetchOption) (map[string]*models.InstanceState, error) {
+	options := newFetchOptions(opts)
+
 	p.client.logger.Info("fetching instance states in batches",
 		zap.Int("total_instances", len(instanceIDs)),
 	)
@@ -198,6 +265,9 @@ func (p *EC2StateProvider) GetInstanceStatesBatch(ctx context.Context, instanceI
 			allErrors = append(allErrors, err)
 			continue
 		}
+		if options.rootVolumes {
+			p.enrichRootVolumes(ctx, slices.Collect(maps.Values(batchStates)))
+		}
 
 		for id, state := range batchStates {
 			states[id] = state
@@ -214,13 +284,19 @@ func (p *EC2StateProvider) GetInstanceStatesBatch(ctx context.Context, instanceI
 func (p *EC2StateProvider) fetchInstanceStatesBatch(ctx context.Context, instanceIDs []string) (map[string]*models.InstanceState, error) {
 	<-p.rateLimiter.C
 
+	ctx, span := startAPISpan(ctx, "EC2", "DescribeInstances",
+		attribute.Int("aws.ec2.batch_size", len(instanceIDs)))
+	defer span.End()
+
 	input := &ec2.DescribeInstancesInput{
 		InstanceIds: instanceIDs,
 	}
 
 	result, err := p.client.ec2Client.DescribeInstances(ctx, input)
 	if err != nil {
-		return nil, classifyError("batch", err)
+		ec2Err := classifyError("batch", err)
+		recordError(span, ec2Err)
+		return nil, ec2Err
 	}
 
 	states := make(map[string]*models.InstanceState)
@@ -234,6 +310,65 @@ func (p *EC2StateProvider) fetchInstanceStatesBatch(ctx context.Context, instanc
 	return states, nil
 }
 
+// enrichRootVolumes fills in the root volume details that DescribeInstances
+// does not return (size, type, IOPS, encryption). Failures are logged and the
+// affected fields are left empty rather than failing the instance fetch.
+func (p *EC2StateProvider) enrichRootVolumes(ctx context.Context, states []*models.InstanceState) {
+	byVolume := make(map[string]*models.InstanceState)
+	volumeIDs := make([]string, 0, len(states))
+	for _, state := range states {
+		if id := state.RootBlockDevice.VolumeID; id != "" {
+			byVolume[id] = state
+			volumeIDs = append(volumeIDs, id)
+		}
+	}
+
+	for i := 0; i < len(volumeIDs); i += maxBatchSize {
+		end := i + maxBatchSize
+		if end > len(volumeIDs) {
+			end = len(volumeIDs)
+		}
+
+		p.describeRootVolumes(ctx, volumeIDs[i:end], byVolume)
+	}
+}
+
+// describeRootVolumes describes one batch of root volumes, traced as its own
+// span, and fills in the instances they belong to.
+func (p *EC2StateProvider) describeRootVolumes(ctx context.Context, volumeIDs []string, byVolume map[string]*models.InstanceState) {
+	<-p.rateLimiter.C
+
+	ctx, span := startAPISpan(ctx, "EC2", "DescribeVolumes",
+		attribute.Int("aws.ec2.volume_count", len(volumeIDs)))
+	defer span.End()
+
+	result, err := p.client.ec2Client.DescribeVolumes(ctx, &ec2.DescribeVolumesInput{
+		VolumeIds: volumeIDs,
+	})
+	if err != nil {
+		recordError(span, err)
+		p.client.logger.Warn("failed to describe root volumes",
+			zap.Int("volume_count", len(volumeIDs)),
+			zap.Error(err),
+		)
+		return
+	}
+
+	for _, volume := range result.Volumes {
+		state, ok := byVolume[aws.ToString(volume.VolumeId)]
+		if !ok {
+			continue
+		}
+		device := &state.RootBlockDevice
+		device.VolumeSize = int(aws.ToInt32(volume.Size))
+		device.VolumeType = string(volume.VolumeType)
+		device.Iops = int(aws.ToInt32(volume.Iops))
+		device.Throughput = int(aws.ToInt32(volume.Throughput))
+		device.Encrypted = aws.ToBool(volume.Encrypted)
+		device.KmsKeyID = aws.ToString(volume.KmsKeyId)
+	}
+}
+
 func (p *EC2StateProvider) mapToInstanceState(instance types.Instance) *models.InstanceState {
 	if instance.Placement == nil {
 		instance.Placement = &types.Placement{}
@@ -253,9 +388,36 @@ func (p *EC2StateProvider) mapToInstanceState(instance types.Instance) *models.I
 		state.Monitoring = instance.Monitoring.State == types.MonitoringStateEnabled
 	}
 
+	if instance.MetadataOptions != nil {
+		state.MetadataOptions = models.MetadataOptions{
+			HttpEndpoint:            string(instance.MetadataOptions.HttpEndpoint),
+			HttpTokens:              string(instance.MetadataOptions.HttpTokens),
+			HttpPutResponseHopLimit: int(aws.ToInt32(instance.MetadataOptions.HttpPutResponseHopLimit)),
+			InstanceMetadataTags:    string(instance.MetadataOptions.InstanceMetadataTags),
+		}
+	}
+
+	state.RootBlockDevice = p.extractRootBlockDevice(instance)
+
 	return state
 }
 
+func (p *EC2StateProvider) extractRootBlockDevice(instance types.Instance) models.BlockDevice {
+	rootName := aws.ToString(instance.RootDeviceName)
+	device := models.BlockDevice{DeviceName: rootName}
+
+	for _, mapping := range instance.BlockDeviceMappings {
+		if aws.ToString(mapping.DeviceName) != rootName || mapping.Ebs == nil {
+			continue
+		}
+		device.VolumeID = aws.ToString(mapping.Ebs.VolumeId)
+		device.DeleteOnTermination = aws.ToBool(mapping.Ebs.DeleteOnTermination)
+		break
+	}
+
+	return device
+}
+
 func (p *EC2StateProvider) extractSecurityGroups(groups []types.GroupIdentifier) []string {
 	result := make([]string, 0, len(groups))
 	for _, g := range groups {
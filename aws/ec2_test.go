@@ -3,32 +3,74 @@ package aws
 import (
 	"context"
 	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
 	"testing"
+	"time"
 
 	flog "firefly-ec2-drift-detector/logger"
 	"firefly-ec2-drift-detector/models"
 	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 )
 
 // MockEC2Client implements the EC2Client interface for testing
 type MockEC2Client struct {
-	DescribeInstancesFunc func(ctx context.Context, params *ec2.DescribeInstancesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInstancesOutput, error)
+	DescribeInstancesFunc          func(ctx context.Context, params *ec2.DescribeInstancesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInstancesOutput, error)
+	DescribeVolumesFunc            func(ctx context.Context, params *ec2.DescribeVolumesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeVolumesOutput, error)
+	DescribeSecurityGroupsFunc     func(ctx context.Context, params *ec2.DescribeSecurityGroupsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSecurityGroupsOutput, error)
+	DescribeSecurityGroupRulesFunc func(ctx context.Context, params *ec2.DescribeSecurityGroupRulesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSecurityGroupRulesOutput, error)
+	DescribeLaunchTemplatesFunc    func(ctx context.Context, params *ec2.DescribeLaunchTemplatesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeLaunchTemplatesOutput, error)
 }
 
 func (m *MockEC2Client) DescribeInstances(ctx context.Context, params *ec2.DescribeInstancesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInstancesOutput, error) {
 	return m.DescribeInstancesFunc(ctx, params, optFns...)
 }
 
+func (m *MockEC2Client) DescribeVolumes(ctx context.Context, params *ec2.DescribeVolumesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeVolumesOutput, error) {
+	if m.DescribeVolumesFunc == nil {
+		return &ec2.DescribeVolumesOutput{}, nil
+	}
+	return m.DescribeVolumesFunc(ctx, params, optFns...)
+}
+
+func (m *MockEC2Client) DescribeSecurityGroups(ctx context.Context, params *ec2.DescribeSecurityGroupsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSecurityGroupsOutput, error) {
+	if m.DescribeSecurityGroupsFunc == nil {
+		return &ec2.DescribeSecurityGroupsOutput{}, nil
+	}
+	return m.DescribeSecurityGroupsFunc(ctx, params, optFns...)
+}
+
+func (m *MockEC2Client) DescribeSecurityGroupRules(ctx context.Context, params *ec2.DescribeSecurityGroupRulesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSecurityGroupRulesOutput, error) {
+	if m.DescribeSecurityGroupRulesFunc == nil {
+		return &ec2.DescribeSecurityGroupRulesOutput{}, nil
+	}
+	return m.DescribeSecurityGroupRulesFunc(ctx, params, optFns...)
+}
+
+func (m *MockEC2Client) DescribeLaunchTemplates(ctx context.Context, params *ec2.DescribeLaunchTemplatesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeLaunchTemplatesOutput, error) {
+	if m.DescribeLaunchTemplatesFunc == nil {
+		return &ec2.DescribeLaunchTemplatesOutput{}, nil
+	}
+	return m.DescribeLaunchTemplatesFunc(ctx, params, optFns...)
+}
+
 // Helper function to create AWSClient with mock EC2Client
-func newTestAWSClient(ec2Client EC2Client) *AWSClient {
+func newTestAWSClient(ec2Client EC2Client, opts ...ClientOption) *AWSClient {
 	logger, _ := flog.NewLogger(flog.Config{
 		LogLevel:    "error",
 		DevMode:     false,
 		ServiceName: "test",
 	})
-	client, _ := NewAWSClient(context.Background(), "us-east-1", ec2Client, logger)
+	client, _ := NewAWSClient(context.Background(), "us-east-1", ec2Client, logger, opts...)
 	return client
 }
 
@@ -632,3 +674,290 @@ func findSubstring(s, substr string) bool {
 	}
 	return false
 }
+
+func TestEC2StateProvider_GetInstanceState_RootVolumeAndMetadata(t *testing.T) {
+	volumeCalls := 0
+	mockClient := &MockEC2Client{
+		DescribeInstancesFunc: func(ctx context.Context, params *ec2.DescribeInstancesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInstancesOutput, error) {
+			return &ec2.DescribeInstancesOutput{
+				Reservations: []types.Reservation{
+					{
+						Instances: []types.Instance{
+							{
+								InstanceId:     aws.String("i-root"),
+								InstanceType:   types.InstanceTypeT3Micro,
+								RootDeviceName: aws.String("/dev/xvda"),
+								BlockDeviceMappings: []types.InstanceBlockDeviceMapping{
+									{
+										DeviceName: aws.String("/dev/sdf"),
+										Ebs:        &types.EbsInstanceBlockDevice{VolumeId: aws.String("vol-data")},
+									},
+									{
+										DeviceName: aws.String("/dev/xvda"),
+										Ebs: &types.EbsInstanceBlockDevice{
+											VolumeId:            aws.String("vol-root"),
+											DeleteOnTermination: aws.Bool(true),
+										},
+									},
+								},
+								MetadataOptions: &types.InstanceMetadataOptionsResponse{
+									HttpEndpoint:            types.InstanceMetadataEndpointStateEnabled,
+									HttpTokens:              types.HttpTokensStateRequired,
+									HttpPutResponseHopLimit: aws.Int32(2),
+								},
+							},
+						},
+					},
+				},
+			}, nil
+		},
+		DescribeVolumesFunc: func(ctx context.Context, params *ec2.DescribeVolumesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeVolumesOutput, error) {
+			volumeCalls++
+			if len(params.VolumeIds) != 1 || params.VolumeIds[0] != "vol-root" {
+				t.Errorf("expected only the root volume to be described, got %v", params.VolumeIds)
+			}
+			return &ec2.DescribeVolumesOutput{
+				Volumes: []types.Volume{
+					{
+						VolumeId:   aws.String("vol-root"),
+						Size:       aws.Int32(30),
+						VolumeType: types.VolumeTypeGp3,
+						Iops:       aws.Int32(3000),
+						Encrypted:  aws.Bool(true),
+					},
+				},
+			}, nil
+		},
+	}
+
+	provider := NewStateProvider(newTestAWSClient(mockClient))
+
+	state, err := provider.GetInstanceState(context.Background(), "i-root")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if volumeCalls != 0 {
+		t.Errorf("expected no DescribeVolumes call without WithRootVolumes, got %d", volumeCalls)
+	}
+	if state.RootBlockDevice.VolumeID != "vol-root" || state.RootBlockDevice.VolumeSize != 0 {
+		t.Errorf("expected only the fields DescribeInstances returns, got %+v", state.RootBlockDevice)
+	}
+
+	state, err = provider.GetInstanceState(context.Background(), "i-root", WithRootVolumes())
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if volumeCalls != 1 {
+		t.Errorf("expected one DescribeVolumes call with WithRootVolumes, got %d", volumeCalls)
+	}
+
+	expectedDevice := models.BlockDevice{
+		DeviceName:          "/dev/xvda",
+		VolumeID:            "vol-root",
+		VolumeSize:          30,
+		VolumeType:          "gp3",
+		Iops:                3000,
+		Encrypted:           true,
+		DeleteOnTermination: true,
+	}
+	if state.RootBlockDevice != expectedDevice {
+		t.Errorf("RootBlockDevice: expected %+v, got %+v", expectedDevice, state.RootBlockDevice)
+	}
+
+	if state.MetadataOptions.HttpTokens != "required" || state.MetadataOptions.HttpPutResponseHopLimit != 2 {
+		t.Errorf("unexpected metadata options: %+v", state.MetadataOptions)
+	}
+}
+
+type recordingObserver struct {
+	calls   []string
+	errs    []error
+	retries []int
+}
+
+func (o *recordingObserver) APICall(operation string, duration time.Duration, err error) {
+	o.calls = append(o.calls, operation)
+	o.errs = append(o.errs, err)
+}
+
+func (o *recordingObserver) APIRetry(operation string, attempt int, err error) {
+	o.retries = append(o.retries, attempt)
+}
+
+func TestEC2StateProvider_APIObserver(t *testing.T) {
+	calls := 0
+	mockClient := &MockEC2Client{
+		DescribeInstancesFunc: func(ctx context.Context, params *ec2.DescribeInstancesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInstancesOutput, error) {
+			if calls++; calls == 1 {
+				return nil, errors.New("api error Throttling: Rate exceeded")
+			}
+			return &ec2.DescribeInstancesOutput{
+				Reservations: []types.Reservation{{Instances: []types.Instance{{InstanceId: aws.String("i-1")}}}},
+			}, nil
+		},
+	}
+	observer := &recordingObserver{}
+	provider := NewStateProvider(newTestAWSClient(mockClient, WithAPIObserver(observer)))
+
+	if _, err := provider.GetInstanceState(context.Background(), "i-1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(observer.calls) != 2 || observer.calls[0] != "DescribeInstances" {
+		t.Fatalf("expected two DescribeInstances calls, got %v", observer.calls)
+	}
+	if !IsThrottlingError(observer.errs[0]) || observer.errs[1] != nil {
+		t.Errorf("expected the first call to be throttled, got %v", observer.errs)
+	}
+	if len(observer.retries) != 1 || observer.retries[0] != 1 {
+		t.Errorf("expected one retry, got %v", observer.retries)
+	}
+}
+
+func TestEC2StateProvider_APIObserverSeesSDKRetries(t *testing.T) {
+	requests := 0
+	endpoint := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/xml")
+		if requests++; requests == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`<Response><Errors><Error><Code>RequestLimitExceeded</Code><Message>Request limit exceeded.</Message></Error></Errors><RequestID>1</RequestID></Response>`))
+			return
+		}
+		w.Write([]byte(`<DescribeInstancesResponse><reservationSet><item><instancesSet><item><instanceId>i-1</instanceId></item></instancesSet></item></reservationSet></DescribeInstancesResponse>`))
+	}))
+	defer endpoint.Close()
+
+	ec2Client := ec2.New(ec2.Options{
+		Region:       "us-east-1",
+		BaseEndpoint: aws.String(endpoint.URL),
+		Credentials:  aws.AnonymousCredentials{},
+		Retryer: retry.NewStandard(func(o *retry.StandardOptions) {
+			o.Backoff = retry.BackoffDelayerFunc(func(int, error) (time.Duration, error) { return 0, nil })
+		}),
+	})
+	observer := &recordingObserver{}
+	provider := NewStateProvider(newTestAWSClient(ec2Client, WithAPIObserver(observer)))
+
+	states, err := provider.GetInstanceStatesBatch(context.Background(), []string{"i-1"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if states["i-1"] == nil {
+		t.Fatalf("expected i-1 to be fetched, got %v", states)
+	}
+
+	if len(observer.calls) != 2 || observer.calls[0] != "DescribeInstances" {
+		t.Fatalf("expected two DescribeInstances attempts, got %v", observer.calls)
+	}
+	if !IsThrottlingError(observer.errs[0]) || observer.errs[1] != nil {
+		t.Errorf("expected the first attempt to be throttled, got %v", observer.errs)
+	}
+	if len(observer.retries) != 1 || observer.retries[0] != 1 {
+		t.Errorf("expected one retry, got %v", observer.retries)
+	}
+}
+
+func TestEC2StateProvider_TracesDescribeInstances(t *testing.T) {
+	ended := recordSpans()
+
+	calls := 0
+	mockClient := &MockEC2Client{
+		DescribeInstancesFunc: func(ctx context.Context, params *ec2.DescribeInstancesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInstancesOutput, error) {
+			if calls++; calls == 1 {
+				return nil, errors.New("api error Throttling: Rate exceeded")
+			}
+			return &ec2.DescribeInstancesOutput{
+				Reservations: []types.Reservation{{Instances: []types.Instance{{InstanceId: aws.String("i-1")}}}},
+			}, nil
+		},
+	}
+	provider := NewStateProvider(newTestAWSClient(mockClient))
+
+	if _, err := provider.GetInstanceState(context.Background(), "i-1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	spans := ended()
+	if len(spans) != 1 || spans[0].Name() != "EC2.DescribeInstances" {
+		t.Fatalf("expected one EC2.DescribeInstances span, got %d", len(spans))
+	}
+	span := spans[0]
+
+	events := span.Events()
+	if len(events) != 1 || events[0].Name != "retry" {
+		t.Fatalf("expected one retry event, got %+v", events)
+	}
+	if !containsAttr(events[0].Attributes, attribute.Int("attempt", 1)) {
+		t.Errorf("expected the retry event to carry its attempt, got %v", events[0].Attributes)
+	}
+	if !containsAttr(span.Attributes(), attribute.String("aws.ec2.instance_id", "i-1")) ||
+		!containsAttr(span.Attributes(), attribute.Int("aws.attempts", 2)) {
+		t.Errorf("unexpected span attributes: %v", span.Attributes())
+	}
+}
+
+func TestEC2StateProvider_TracesDescribeVolumesApart(t *testing.T) {
+	ended := recordSpans()
+
+	mockClient := &MockEC2Client{
+		DescribeInstancesFunc: func(ctx context.Context, params *ec2.DescribeInstancesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInstancesOutput, error) {
+			return &ec2.DescribeInstancesOutput{
+				Reservations: []types.Reservation{{Instances: []types.Instance{{
+					InstanceId:     aws.String("i-1"),
+					RootDeviceName: aws.String("/dev/xvda"),
+					BlockDeviceMappings: []types.InstanceBlockDeviceMapping{{
+						DeviceName: aws.String("/dev/xvda"),
+						Ebs:        &types.EbsInstanceBlockDevice{VolumeId: aws.String("vol-1")},
+					}},
+				}}}},
+			}, nil
+		},
+		DescribeVolumesFunc: func(ctx context.Context, params *ec2.DescribeVolumesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeVolumesOutput, error) {
+			return nil, errors.New("api error UnauthorizedOperation: not allowed")
+		},
+	}
+	provider := NewStateProvider(newTestAWSClient(mockClient))
+
+	if _, err := provider.GetInstanceStatesBatch(context.Background(), []string{"i-1"}, WithRootVolumes()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	spans := ended()
+	if len(spans) != 2 || spans[0].Name() != "EC2.DescribeInstances" || spans[1].Name() != "EC2.DescribeVolumes" {
+		t.Fatalf("expected EC2.DescribeInstances then EC2.DescribeVolumes spans, got %d", len(spans))
+	}
+	if spans[1].Parent().SpanID() == spans[0].SpanContext().SpanID() {
+		t.Error("expected DescribeVolumes not to be traced inside DescribeInstances")
+	}
+	if spans[1].Status().Code != codes.Error || !containsAttr(spans[1].Attributes(), attribute.Int("aws.ec2.volume_count", 1)) {
+		t.Errorf("expected a failed DescribeVolumes span for one volume, got %v %v", spans[1].Status(), spans[1].Attributes())
+	}
+}
+
+var spanRecorder struct {
+	once     sync.Once
+	recorder *tracetest.SpanRecorder
+}
+
+// recordSpans returns a function listing the spans ended since the call. The
+// recording tracer provider is installed once, since the package tracer only
+// delegates to the first global provider set.
+func recordSpans() func() []sdktrace.ReadOnlySpan {
+	spanRecorder.once.Do(func() {
+		spanRecorder.recorder = tracetest.NewSpanRecorder()
+		otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spanRecorder.recorder)))
+	})
+	seen := len(spanRecorder.recorder.Ended())
+	return func() []sdktrace.ReadOnlySpan {
+		return spanRecorder.recorder.Ended()[seen:]
+	}
+}
+
+func containsAttr(attrs []attribute.KeyValue, want attribute.KeyValue) bool {
+	for _, attr := range attrs {
+		if attr == want {
+			return true
+		}
+	}
+	return false
+}
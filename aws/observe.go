@@ -0,0 +1,154 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/autoscaling"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/smithy-go/middleware"
+)
+
+// APIObserver is told about the AWS API calls a client makes, such as to
+// export metrics.
+type APIObserver interface {
+	// APICall is called after each call of an operation, such as
+	// DescribeInstances, with how long it took and its error, if any.
+	APICall(operation string, duration time.Duration, err error)
+	// APIRetry is called before an operation is tried again after err.
+	// attempt counts from 1 for the first retry.
+	APIRetry(operation string, attempt int, err error)
+}
+
+// WithAPIObserver reports every AWS API call to observer.
+func WithAPIObserver(observer APIObserver) ClientOption {
+	return func(c *AWSClient) {
+		c.observer = observer
+	}
+}
+
+// IsThrottlingError reports whether AWS rejected a request for exceeding
+// its rate limit.
+func IsThrottlingError(err error) bool {
+	if err == nil {
+		return false
+	}
+	var ec2Err *EC2Error
+	if !errors.As(err, &ec2Err) {
+		ec2Err = classifyError("", err)
+	}
+	return ec2Err.ErrorType == ErrorTypeThrottling
+}
+
+// observe reports the attempts call makes to observer. call must add
+// apiOption to the API options of the SDK call, so that the attempts the SDK
+// retries itself, such as throttled ones, are reported too: each as a call,
+// and each after the first as a retry. A client that runs no middleware,
+// such as a test double, is reported as one call.
+func observe[T any](observer APIObserver, operation string, call func(apiOption func(*middleware.Stack) error) (T, error)) (T, error) {
+	attempts := &observedAttempts{observer: observer, operation: operation}
+	start := time.Now()
+	out, err := call(attempts.addMiddleware)
+	if attempts.count == 0 {
+		observer.APICall(operation, time.Since(start), err)
+	}
+	return out, err
+}
+
+// observedAttempts reports the attempts of one SDK call.
+type observedAttempts struct {
+	observer  APIObserver
+	operation string
+	count     int
+	lastErr   error
+}
+
+// addMiddleware observes each attempt, after the SDK's retry middleware.
+func (a *observedAttempts) addMiddleware(stack *middleware.Stack) error {
+	return stack.Finalize.Insert(middleware.FinalizeMiddlewareFunc("ObserveAttempt", a.handleAttempt), "Retry", middleware.After)
+}
+
+func (a *observedAttempts) handleAttempt(ctx context.Context, in middleware.FinalizeInput, next middleware.FinalizeHandler) (middleware.FinalizeOutput, middleware.Metadata, error) {
+	if a.count > 0 {
+		a.observer.APIRetry(a.operation, a.count, a.lastErr)
+	}
+	a.count++
+
+	start := time.Now()
+	out, metadata, err := next.HandleFinalize(ctx, in)
+	a.observer.APICall(a.operation, time.Since(start), err)
+	a.lastErr = err
+	return out, metadata, err
+}
+
+// withEC2APIOption returns optFns with apiOption added to the EC2 API options.
+func withEC2APIOption(optFns []func(*ec2.Options), apiOption func(*middleware.Stack) error) []func(*ec2.Options) {
+	return append(optFns[:len(optFns):len(optFns)], func(o *ec2.Options) {
+		o.APIOptions = append(o.APIOptions, apiOption)
+	})
+}
+
+// withAutoScalingAPIOption returns optFns with apiOption added to the Auto
+// Scaling API options.
+func withAutoScalingAPIOption(optFns []func(*autoscaling.Options), apiOption func(*middleware.Stack) error) []func(*autoscaling.Options) {
+	return append(optFns[:len(optFns):len(optFns)], func(o *autoscaling.Options) {
+		o.APIOptions = append(o.APIOptions, apiOption)
+	})
+}
+
+// retrying reports a retry to the client's observer, if any.
+func (c *AWSClient) retrying(operation string, attempt int, err error) {
+	if c.observer != nil {
+		c.observer.APIRetry(operation, attempt, err)
+	}
+}
+
+// observedEC2Client reports the calls of an EC2Client to an observer.
+type observedEC2Client struct {
+	client   EC2Client
+	observer APIObserver
+}
+
+func (c observedEC2Client) DescribeInstances(ctx context.Context, params *ec2.DescribeInstancesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInstancesOutput, error) {
+	return observe(c.observer, "DescribeInstances", func(apiOption func(*middleware.Stack) error) (*ec2.DescribeInstancesOutput, error) {
+		return c.client.DescribeInstances(ctx, params, withEC2APIOption(optFns, apiOption)...)
+	})
+}
+
+func (c observedEC2Client) DescribeVolumes(ctx context.Context, params *ec2.DescribeVolumesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeVolumesOutput, error) {
+	return observe(c.observer, "DescribeVolumes", func(apiOption func(*middleware.Stack) error) (*ec2.DescribeVolumesOutput, error) {
+		return c.client.DescribeVolumes(ctx, params, withEC2APIOption(optFns, apiOption)...)
+	})
+}
+
+func (c observedEC2Client) DescribeSecurityGroups(ctx context.Context, params *ec2.DescribeSecurityGroupsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSecurityGroupsOutput, error) {
+	return observe(c.observer, "DescribeSecurityGroups", func(apiOption func(*middleware.Stack) error) (*ec2.DescribeSecurityGroupsOutput, error) {
+		return c.client.DescribeSecurityGroups(ctx, params, withEC2APIOption(optFns, apiOption)...)
+	})
+}
+
+func (c observedEC2Client) DescribeSecurityGroupRules(ctx context.Context, params *ec2.DescribeSecurityGroupRulesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSecurityGroupRulesOutput, error) {
+	return observe(c.observer, "DescribeSecurityGroupRules", func(apiOption func(*middleware.Stack) error) (*ec2.DescribeSecurityGroupRulesOutput, error) {
+		return c.client.DescribeSecurityGroupRules(ctx, params, withEC2APIOption(optFns, apiOption)...)
+	})
+}
+
+func (c observedEC2Client) DescribeLaunchTemplates(ctx context.Context, params *ec2.DescribeLaunchTemplatesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeLaunchTemplatesOutput, error) {
+	return observe(c.observer, "DescribeLaunchTemplates", func(apiOption func(*middleware.Stack) error) (*ec2.DescribeLaunchTemplatesOutput, error) {
+		return c.client.DescribeLaunchTemplates(ctx, params, withEC2APIOption(optFns, apiOption)...)
+	})
+}
+
+// observedAutoScalingClient reports the calls of an AutoScalingClient to an
+// observer.
+type observedAutoScalingClient struct {
+	client   AutoScalingClient
+	observer APIObserver
+}
+
+func (c observedAutoScalingClient) DescribeAutoScalingGroups(ctx context.Context, params *autoscaling.DescribeAutoScalingGroupsInput, optFns ...func(*autoscaling.Options)) (*autoscaling.DescribeAutoScalingGroupsOutput, error) {
+	return observe(c.observer, "DescribeAutoScalingGroups", func(apiOption func(*middleware.Stack) error) (*autoscaling.DescribeAutoScalingGroupsOutput, error) {
+		return c.client.DescribeAutoScalingGroups(ctx, params, withAutoScalingAPIOption(optFns, apiOption)...)
+	})
+}
@@ -0,0 +1,148 @@
+package aws
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"go.uber.org/zap"
+
+	"firefly-ec2-drift-detector/models"
+)
+
+// maxFilterValues is the number of values EC2 accepts in a single filter.
+const maxFilterValues = 200
+
+// GetSecurityGroupStates fetches security groups and their rules by ID and by
+// name. Names are used for groups declared in HCL, which have no ID until
+// applied. The result is keyed by group ID; groups that do not exist are
+// simply absent.
+func (p *EC2StateProvider) GetSecurityGroupStates(ctx context.Context, groupIDs, groupNames []string) (map[string]*models.SecurityGroupState, error) {
+	p.client.logger.Info("fetching security group states from AWS",
+		zap.Int("group_id_count", len(groupIDs)),
+		zap.Int("group_name_count", len(groupNames)),
+	)
+
+	states := make(map[string]*models.SecurityGroupState)
+
+	for filter, values := range map[string][]string{"group-id": groupIDs, "group-name": groupNames} {
+		if err := p.describeSecurityGroups(ctx, filter, values, states); err != nil {
+			return nil, err
+		}
+	}
+
+	ids := make([]string, 0, len(states))
+	for id := range states {
+		ids = append(ids, id)
+	}
+
+	if err := p.describeSecurityGroupRules(ctx, ids, states); err != nil {
+		return nil, err
+	}
+
+	return states, nil
+}
+
+func (p *EC2StateProvider) describeSecurityGroups(ctx context.Context, filter string, values []string, states map[string]*models.SecurityGroupState) error {
+	for i := 0; i < len(values); i += maxFilterValues {
+		end := min(i+maxFilterValues, len(values))
+
+		input := &ec2.DescribeSecurityGroupsInput{
+			Filters: []types.Filter{{Name: aws.String(filter), Values: values[i:end]}},
+		}
+
+		for {
+			<-p.rateLimiter.C
+
+			result, err := p.client.ec2Client.DescribeSecurityGroups(ctx, input)
+			if err != nil {
+				return classifyError("security-groups", err)
+			}
+
+			for _, group := range result.SecurityGroups {
+				id := aws.ToString(group.GroupId)
+				states[id] = &models.SecurityGroupState{
+					GroupID:     id,
+					Name:        aws.ToString(group.GroupName),
+					Description: aws.ToString(group.Description),
+					VpcID:       aws.ToString(group.VpcId),
+					Tags:        p.extractTags(group.Tags),
+				}
+			}
+
+			if aws.ToString(result.NextToken) == "" {
+				break
+			}
+			input.NextToken = result.NextToken
+		}
+	}
+
+	return nil
+}
+
+// describeSecurityGroupRules uses DescribeSecurityGroupRules rather than the
+// permissions embedded in DescribeSecurityGroups because it reports one rule
+// per source, each with its own description.
+func (p *EC2StateProvider) describeSecurityGroupRules(ctx context.Context, groupIDs []string, states map[string]*models.SecurityGroupState) error {
+	for i := 0; i < len(groupIDs); i += maxFilterValues {
+		end := min(i+maxFilterValues, len(groupIDs))
+
+		input := &ec2.DescribeSecurityGroupRulesInput{
+			Filters: []types.Filter{{Name: aws.String("group-id"), Values: groupIDs[i:end]}},
+		}
+
+		for {
+			<-p.rateLimiter.C
+
+			result, err := p.client.ec2Client.DescribeSecurityGroupRules(ctx, input)
+			if err != nil {
+				return classifyError("security-group-rules", err)
+			}
+
+			for _, rule := range result.SecurityGroupRules {
+				state, ok := states[aws.ToString(rule.GroupId)]
+				if !ok {
+					continue
+				}
+
+				mapped := p.mapSecurityGroupRule(rule, state.GroupID)
+				if aws.ToBool(rule.IsEgress) {
+					state.Egress = append(state.Egress, mapped)
+				} else {
+					state.Ingress = append(state.Ingress, mapped)
+				}
+			}
+
+			if aws.ToString(result.NextToken) == "" {
+				break
+			}
+			input.NextToken = result.NextToken
+		}
+	}
+
+	return nil
+}
+
+func (p *EC2StateProvider) mapSecurityGroupRule(rule types.SecurityGroupRule, groupID string) models.SecurityGroupRule {
+	source := aws.ToString(rule.CidrIpv4)
+	switch {
+	case rule.CidrIpv6 != nil:
+		source = aws.ToString(rule.CidrIpv6)
+	case rule.PrefixListId != nil:
+		source = aws.ToString(rule.PrefixListId)
+	case rule.ReferencedGroupInfo != nil:
+		source = aws.ToString(rule.ReferencedGroupInfo.GroupId)
+		if source == groupID {
+			source = models.SelfSource
+		}
+	}
+
+	return models.NewSecurityGroupRule(
+		aws.ToString(rule.IpProtocol),
+		int(aws.ToInt32(rule.FromPort)),
+		int(aws.ToInt32(rule.ToPort)),
+		source,
+		aws.ToString(rule.Description),
+	)
+}
@@ -0,0 +1,130 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+
+	"firefly-ec2-drift-detector/models"
+)
+
+func TestEC2StateProvider_GetSecurityGroupStates(t *testing.T) {
+	mockClient := &MockEC2Client{
+		DescribeSecurityGroupsFunc: func(ctx context.Context, params *ec2.DescribeSecurityGroupsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSecurityGroupsOutput, error) {
+			switch aws.ToString(params.Filters[0].Name) {
+			case "group-id":
+				return &ec2.DescribeSecurityGroupsOutput{
+					SecurityGroups: []types.SecurityGroup{
+						{GroupId: aws.String("sg-web"), GroupName: aws.String("web"), VpcId: aws.String("vpc-1")},
+					},
+				}, nil
+			case "group-name":
+				if len(params.Filters[0].Values) != 1 || params.Filters[0].Values[0] != "db" {
+					t.Errorf("unexpected group-name filter: %v", params.Filters[0].Values)
+				}
+				return &ec2.DescribeSecurityGroupsOutput{
+					SecurityGroups: []types.SecurityGroup{
+						{GroupId: aws.String("sg-db"), GroupName: aws.String("db")},
+					},
+				}, nil
+			}
+			return nil, errors.New("unexpected filter")
+		},
+		DescribeSecurityGroupRulesFunc: func(ctx context.Context, params *ec2.DescribeSecurityGroupRulesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSecurityGroupRulesOutput, error) {
+			return &ec2.DescribeSecurityGroupRulesOutput{
+				SecurityGroupRules: []types.SecurityGroupRule{
+					{
+						GroupId:     aws.String("sg-web"),
+						IpProtocol:  aws.String("tcp"),
+						FromPort:    aws.Int32(443),
+						ToPort:      aws.Int32(443),
+						CidrIpv4:    aws.String("0.0.0.0/0"),
+						Description: aws.String("https"),
+						IsEgress:    aws.Bool(false),
+					},
+					{
+						GroupId:             aws.String("sg-web"),
+						IpProtocol:          aws.String("tcp"),
+						FromPort:            aws.Int32(8080),
+						ToPort:              aws.Int32(8080),
+						ReferencedGroupInfo: &types.ReferencedSecurityGroup{GroupId: aws.String("sg-web")},
+						IsEgress:            aws.Bool(false),
+					},
+					{
+						GroupId:    aws.String("sg-web"),
+						IpProtocol: aws.String("-1"),
+						FromPort:   aws.Int32(-1),
+						ToPort:     aws.Int32(-1),
+						CidrIpv4:   aws.String("0.0.0.0/0"),
+						IsEgress:   aws.Bool(true),
+					},
+					{
+						GroupId:      aws.String("sg-db"),
+						IpProtocol:   aws.String("tcp"),
+						FromPort:     aws.Int32(5432),
+						ToPort:       aws.Int32(5432),
+						PrefixListId: aws.String("pl-123"),
+						IsEgress:     aws.Bool(false),
+					},
+				},
+			}, nil
+		},
+	}
+
+	provider := NewStateProvider(newTestAWSClient(mockClient))
+
+	states, err := provider.GetSecurityGroupStates(context.Background(), []string{"sg-web"}, []string{"db"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(states) != 2 {
+		t.Fatalf("expected 2 groups, got %d", len(states))
+	}
+
+	web := states["sg-web"]
+	if web == nil || web.VpcID != "vpc-1" {
+		t.Fatalf("unexpected web group: %+v", web)
+	}
+
+	wantIngress := []models.SecurityGroupRule{
+		{Protocol: "tcp", FromPort: 443, ToPort: 443, Source: "0.0.0.0/0", Description: "https"},
+		{Protocol: "tcp", FromPort: 8080, ToPort: 8080, Source: models.SelfSource},
+	}
+	if len(web.Ingress) != len(wantIngress) {
+		t.Fatalf("expected %d ingress rules, got %+v", len(wantIngress), web.Ingress)
+	}
+	for i, rule := range wantIngress {
+		if web.Ingress[i] != rule {
+			t.Errorf("ingress[%d] = %+v, want %+v", i, web.Ingress[i], rule)
+		}
+	}
+
+	if len(web.Egress) != 1 || web.Egress[0].Key() != "-1 all 0.0.0.0/0" {
+		t.Errorf("expected normalised all-traffic egress rule, got %+v", web.Egress)
+	}
+
+	db := states["sg-db"]
+	if db == nil || len(db.Ingress) != 1 || db.Ingress[0].Source != "pl-123" {
+		t.Errorf("unexpected db group: %+v", db)
+	}
+}
+
+func TestEC2StateProvider_GetSecurityGroupStates_Error(t *testing.T) {
+	mockClient := &MockEC2Client{
+		DescribeSecurityGroupsFunc: func(ctx context.Context, params *ec2.DescribeSecurityGroupsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSecurityGroupsOutput, error) {
+			return nil, errors.New("UnauthorizedOperation: not allowed")
+		},
+	}
+
+	provider := NewStateProvider(newTestAWSClient(mockClient))
+
+	_, err := provider.GetSecurityGroupStates(context.Background(), []string{"sg-web"}, nil)
+	if !IsAuthError(err) {
+		t.Errorf("expected auth error, got %v", err)
+	}
+}
@@ -0,0 +1,81 @@
+package aws
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"go.uber.org/zap"
+
+	"firefly-ec2-drift-detector/models"
+)
+
+// GetVolumeStates fetches EBS volumes and their current attachment. Volumes
+// that do not exist are absent from the result.
+func (p *EC2StateProvider) GetVolumeStates(ctx context.Context, volumeIDs []string) (map[string]*models.VolumeState, error) {
+	p.client.logger.Info("fetching volume states from AWS",
+		zap.Int("volume_count", len(volumeIDs)),
+	)
+
+	states := make(map[string]*models.VolumeState, len(volumeIDs))
+
+	for i := 0; i < len(volumeIDs); i += maxFilterValues {
+		end := min(i+maxFilterValues, len(volumeIDs))
+
+		// A volume-id filter, unlike VolumeIds, does not fail the whole call
+		// when one of the volumes has been deleted.
+		input := &ec2.DescribeVolumesInput{
+			Filters: []types.Filter{{Name: aws.String("volume-id"), Values: volumeIDs[i:end]}},
+		}
+
+		for {
+			<-p.rateLimiter.C
+
+			result, err := p.client.ec2Client.DescribeVolumes(ctx, input)
+			if err != nil {
+				return nil, classifyError("volumes", err)
+			}
+
+			for _, volume := range result.Volumes {
+				state := p.mapToVolumeState(volume)
+				states[state.VolumeID] = state
+			}
+
+			if aws.ToString(result.NextToken) == "" {
+				break
+			}
+			input.NextToken = result.NextToken
+		}
+	}
+
+	return states, nil
+}
+
+func (p *EC2StateProvider) mapToVolumeState(volume types.Volume) *models.VolumeState {
+	state := &models.VolumeState{
+		VolumeID:         aws.ToString(volume.VolumeId),
+		AvailabilityZone: aws.ToString(volume.AvailabilityZone),
+		Size:             int(aws.ToInt32(volume.Size)),
+		VolumeType:       string(volume.VolumeType),
+		Iops:             int(aws.ToInt32(volume.Iops)),
+		Throughput:       int(aws.ToInt32(volume.Throughput)),
+		Encrypted:        aws.ToBool(volume.Encrypted),
+		KmsKeyID:         aws.ToString(volume.KmsKeyId),
+		SnapshotID:       aws.ToString(volume.SnapshotId),
+		Tags:             p.extractTags(volume.Tags),
+	}
+
+	for _, attachment := range volume.Attachments {
+		switch attachment.State {
+		case types.VolumeAttachmentStateAttached, types.VolumeAttachmentStateAttaching:
+			state.Attachment = models.VolumeAttachment{
+				InstanceID: aws.ToString(attachment.InstanceId),
+				DeviceName: aws.ToString(attachment.Device),
+			}
+			return state
+		}
+	}
+
+	return state
+}
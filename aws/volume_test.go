@@ -0,0 +1,58 @@
+package aws
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+func TestEC2StateProvider_GetVolumeStates(t *testing.T) {
+	mockClient := &MockEC2Client{
+		DescribeVolumesFunc: func(ctx context.Context, params *ec2.DescribeVolumesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeVolumesOutput, error) {
+			if len(params.VolumeIds) != 0 || aws.ToString(params.Filters[0].Name) != "volume-id" {
+				t.Errorf("expected a volume-id filter, got %+v", params)
+			}
+			return &ec2.DescribeVolumesOutput{
+				Volumes: []types.Volume{
+					{
+						VolumeId:   aws.String("vol-1"),
+						Size:       aws.Int32(100),
+						VolumeType: types.VolumeTypeGp3,
+						Encrypted:  aws.Bool(true),
+						Attachments: []types.VolumeAttachment{
+							{InstanceId: aws.String("i-old"), Device: aws.String("/dev/sdf"), State: types.VolumeAttachmentStateDetached},
+							{InstanceId: aws.String("i-new"), Device: aws.String("/dev/sdg"), State: types.VolumeAttachmentStateAttached},
+						},
+					},
+					{
+						VolumeId:   aws.String("vol-2"),
+						Size:       aws.Int32(10),
+						VolumeType: types.VolumeTypeGp2,
+					},
+				},
+			}, nil
+		},
+	}
+
+	provider := NewStateProvider(newTestAWSClient(mockClient))
+
+	states, err := provider.GetVolumeStates(context.Background(), []string{"vol-1", "vol-2", "vol-gone"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(states) != 2 {
+		t.Fatalf("expected 2 volumes, got %d", len(states))
+	}
+
+	if got := states["vol-1"].Attachment; got.InstanceID != "i-new" || got.DeviceName != "/dev/sdg" {
+		t.Errorf("expected the attached instance, got %+v", got)
+	}
+
+	if got := states["vol-2"].Attachment.InstanceID; got != "" {
+		t.Errorf("expected vol-2 to be detached, got %s", got)
+	}
+}
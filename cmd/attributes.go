@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"firefly-ec2-drift-detector/models"
+)
+
+var attributesResourceType string
+
+var attributesCmd = &cobra.Command{
+	Use:   "attributes",
+	Short: "List the attributes that can be checked for drift",
+	Long: `List every attribute the detector can compare, with its canonical name,
+its Terraform name and its type. Either name can be passed to the -a flag of
+the detector command, and nested fields can be addressed with dotted paths
+such as RootBlockDevice.VolumeSize or root_block_device.volume_size.
+
+Use --type to list the attributes of another resource type, e.g.
+aws_ebs_volume.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		registry := models.AttributeRegistryFor(attributesResourceType)
+		if registry == nil {
+			return fmt.Errorf("resource type %q has no configurable attributes", attributesResourceType)
+		}
+		printAttributes(registry)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(attributesCmd)
+
+	attributesCmd.Flags().StringVarP(&attributesResourceType, "type", "t", models.ResourceTypeInstance, "Terraform resource type")
+}
+
+func printAttributes(registry *models.AttributeRegistry) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tTERRAFORM NAME\tTYPE\tDESCRIPTION")
+
+	for _, attr := range registry.Attributes() {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", attr.Name, attr.TerraformName, attr.Kind, attr.Description)
+		for _, child := range attr.Children {
+			fmt.Fprintf(w, "%s.%s\t%s.%s\t%s\t%s\n",
+				attr.Name, child.Name, attr.TerraformName, child.TerraformName, child.Kind, child.Description)
+		}
+	}
+
+	w.Flush()
+
+	fmt.Printf("\nUse %q to check every top-level attribute.\n", models.AllAttributes)
+}
@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"firefly-ec2-drift-detector/models"
+	"firefly-ec2-drift-detector/service"
+)
+
+var (
+	baselineFile    string
+	baselineExpires string
+	baselineReason  string
+)
+
+var baselineCmd = &cobra.Command{
+	Use:   "baseline",
+	Short: "Manage accepted drift",
+	Long: `A baseline file lists drift that is known and accepted, such as an instance
+resized by hand while the Terraform change is in review. Pass it to the
+detector command with --baseline to report matching drift as suppressed.`,
+}
+
+var baselineCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Write the current drift to a baseline file",
+	Long: `Scan like the detector command and write every drift found to a baseline
+file. Each entry records the resource, the attribute and the expected and
+actual values, so the drift is reported again if its values change.
+
+--expires and --reason are recorded on every entry. Once an entry expires, its
+drift is reported again. Entries can also be edited by hand (see README).
+
+Examples:
+  firefly baseline create -s terraform.tfstate -a all
+  firefly baseline create -s terraform.tfstate -a InstanceType -i i-0abc \
+    --expires 2024-06-30 --reason "resize pending in PR #42" -o drift-baseline.json`,
+	SilenceUsage: true,
+	RunE:         runBaselineCreate,
+}
+
+func init() {
+	rootCmd.AddCommand(baselineCmd)
+	baselineCmd.AddCommand(baselineCreateCmd)
+
+	addScanFlags(baselineCreateCmd)
+
+	baselineCreateCmd.Flags().StringVarP(&baselineFile, "output-file", "o", "drift-baseline.json", "Baseline file to write")
+	baselineCreateCmd.Flags().StringVar(&baselineExpires, "expires", "", "Date (2024-06-30) or RFC 3339 time after which the drift is reported again")
+	baselineCreateCmd.Flags().StringVar(&baselineReason, "reason", "", "Why the drift is accepted")
+}
+
+func runBaselineCreate(cmd *cobra.Command, args []string) error {
+	if baselineExpires != "" {
+		if _, err := models.ParseBaselineExpiry(baselineExpires); err != nil {
+			return fmt.Errorf("invalid --expires value: %w", err)
+		}
+	}
+
+	ctx := context.Background()
+
+	driftService, err := newDriftService(ctx)
+	if err != nil {
+		return err
+	}
+
+	reports, detectErr := driftService.DetectResources(ctx, terraformStatePath, buildDetectRequest())
+	if detectErr != nil {
+		if len(reports) == 0 {
+			return fmt.Errorf("drift detection failed: %w", detectErr)
+		}
+		fmt.Fprintf(os.Stderr, "⚠️  Warning: %d resource(s) could not be checked and are not in the baseline: %v\n",
+			len(service.Failures(detectErr)), detectErr)
+	}
+
+	baseline, err := models.NewBaseline(reports, baselineExpires, baselineReason)
+	if err != nil {
+		return err
+	}
+	if err := baseline.Save(baselineFile); err != nil {
+		return err
+	}
+
+	fmt.Printf("Wrote %d accepted drift(s) to %s\n", len(baseline.Suppressions), baselineFile)
+	return nil
+}
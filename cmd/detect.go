@@ -2,29 +2,44 @@ package cmd
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	"os"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 	"go.uber.org/zap"
 
-	"firefly-ec2-drift-detector/aws"
 	flog "firefly-ec2-drift-detector/logger"
+	"firefly-ec2-drift-detector/metrics"
 	"firefly-ec2-drift-detector/models"
+	"firefly-ec2-drift-detector/output"
 	"firefly-ec2-drift-detector/service"
-	"firefly-ec2-drift-detector/terraform"
 )
 
 var (
-	terraformStatePath string
-	instanceIDs        []string
-	attributes         []string
-	outputFormat       string
-	awsRegion          string
+	terraformStatePath  string
+	instanceIDs         []string
+	attributes          []string
+	outputFormats       []string
+	awsRegion           string
+	ignoreTags          []string
+	ignoreTagPrefixes   []string
+	ignoreTagPatterns   []string
+	noDefaultTagIgnores bool
+	resourceTypes       []string
+	securityGroups      bool
+	securityGroupIDs    []string
+	amiAliases          map[string]string
+	severityPolicyPath  string
+	failOn              string
+	detailedExitCode    bool
+	outputFile          string
+	templatePath        string
+	noHistory           bool
+	baselinePath        string
+	metricsFile         string
 )
 
 var detectorCmd = &cobra.Command{
@@ -49,7 +64,68 @@ Examples:
 
   # Check all instances in state file
   firefly detector -s terraform.tfstate -a InstanceType,Monitoring
+
+  # Check nested attributes, map keys and list elements
+  firefly detector -s terraform.tfstate \
+    -a Tags.Owner,SecurityGroups[0],MetadataOptions.HttpTokens,RootBlockDevice.VolumeSize
+
+  # Ignore tags written by automation in addition to AWS-managed aws:* tags
+  firefly detector -s terraform.tfstate -a Tags \
+    --ignore-tags PatchGroup --ignore-tag-prefixes patching: --ignore-tag-patterns '^backup-.*$'
+
+  # Also check ingress/egress rules of security groups in the state
+  firefly detector -s terraform.tfstate -a all --security-groups
+  firefly detector -s terraform.tfstate --security-group-ids sg-0123456789abcdef0
+
+  # Choose resource types explicitly
+  firefly detector -s terraform.tfstate -t aws_instance,aws_security_group,aws_ebs_volume
+
+  # Check Auto Scaling group members against their launch template
+  firefly detector -s terraform.tfstate -t aws_autoscaling_group
+
+  # Exit 2 when drift is found and 3 when some resources could not be checked
+  firefly detector -s terraform.tfstate -a all --detailed-exitcode
+
+  # Fail the run when any drift is rated high or critical
+  firefly detector -s terraform.tfstate -a all --fail-on high --severity-policy severity.json
+
+  # Write SARIF for GitHub code scanning, pointing at the .tf declarations
+  firefly detector -s ./infra -a all -f sarif > drift.sarif
+
+  # Report drift as failed tests in Jenkins or GitLab
+  firefly detector -s terraform.tfstate -a all -f junit > drift-junit.xml
+
+  # Markdown for a pull request comment, HTML as a build artifact
+  firefly detector -s terraform.tfstate -a all -f markdown -o drift.md
+  firefly detector -s terraform.tfstate -a all -f html -o drift.html
+
+  # One row per drifted attribute for spreadsheets; one JSON event per line for log pipelines
+  firefly detector -s terraform.tfstate -a all -f csv -o drift.csv
+  firefly detector -s terraform.tfstate -a all -f ndjson | jq -c 'select(.has_drift)'
+
+  # Several outputs at once, and a custom text/template report
+  firefly detector -s terraform.tfstate -a all -f text,json=report.json,sarif=drift.sarif
+  firefly detector -s terraform.tfstate -a all -f template --template slack.tmpl
+
+  # Treat an SSM AMI alias in Terraform as the AMI it currently resolves to
+  firefly detector -s terraform.tfstate -a ami \
+    --ami-alias resolve:ssm:/aws/service/ami-amazon-linux-latest/al2023-ami-kernel-default-x86_64=ami-0abc
+
+  # Terraform attribute names work too; "all" checks every attribute
+  firefly detector -s terraform.tfstate -a ami,vpc_security_group_ids
+  firefly detector -s terraform.tfstate -a all
   
+  # Report accepted drift listed in a baseline file as suppressed
+  firefly baseline create -s terraform.tfstate -a all --expires 2024-06-30
+  firefly detector -s terraform.tfstate -a all --baseline drift-baseline.json
+
+  # Runs are saved to ~/.firefly/history.db; compare them with diff-runs
+  firefly detector -s terraform.tfstate -a all --history-db drift-history.db
+  firefly detector -s terraform.tfstate -a all --no-history
+
+  # Write Prometheus metrics for the node exporter's textfile collector
+  firefly detector -s terraform.tfstate -a all --metrics-file /var/lib/node_exporter/firefly.prom
+
   # Enable verbose logging
   firefly detector -v -s terraform.tfstate -a InstanceType`,
 	SilenceUsage: true,
@@ -59,155 +135,206 @@ Examples:
 func init() {
 	rootCmd.AddCommand(detectorCmd)
 
-	detectorCmd.Flags().StringVarP(&terraformStatePath, "state", "s", "", "Path to Terraform state file (required)")
-	detectorCmd.Flags().StringSliceVarP(&instanceIDs, "instances", "i", []string{}, "Comma-separated list of instance IDs (empty = all instances in state)")
-	detectorCmd.Flags().StringSliceVarP(&attributes, "attributes", "a", []string{"InstanceType"}, "Comma-separated list of attributes to check")
-	detectorCmd.Flags().StringVarP(&outputFormat, "format", "f", "text", "Output format: text or json")
-	detectorCmd.Flags().StringVarP(&awsRegion, "region", "r", "us-east-1", "AWS region")
+	addScanFlags(detectorCmd)
+
+	detectorCmd.Flags().StringSliceVarP(&outputFormats, "format", "f", []string{"text"}, "Output formats, each optionally written to a file: "+strings.Join(output.NewRegistry().Names(), ", ")+" (e.g. text,json=report.json)")
+	detectorCmd.Flags().StringVarP(&outputFile, "output-file", "o", "", "Write the output given without a file to this file instead of stdout")
+	detectorCmd.Flags().StringVar(&templatePath, "template", "", "text/template file for -f template")
 
-	detectorCmd.MarkFlagRequired("state")
+	detectorCmd.Flags().StringVar(&failOn, "fail-on", "", "Fail the run with exit code 2 when any drift is at or above this severity: critical, high, medium, low or info")
+	detectorCmd.Flags().BoolVar(&detailedExitCode, "detailed-exitcode", false, "Exit 0 with no drift, 2 with any drift, and 3 when some resources could not be checked and none had drift")
+	detectorCmd.Flags().StringVar(&baselinePath, "baseline", "", "JSON file of accepted drifts to report as suppressed (see 'firefly baseline create')")
+
+	detectorCmd.Flags().BoolVar(&noHistory, "no-history", false, "Do not save this run to the run history")
+	detectorCmd.Flags().StringVar(&metricsFile, "metrics-file", "", "Write Prometheus metrics of the run to this file, for the node exporter's textfile collector")
 }
 
 func runDetector(cmd *cobra.Command, args []string) error {
 	logger.Info("firefly drift detection started",
-		zap.String("version", "1.0.0"),
+		zap.String("version", version),
 		zap.String("terraform_state", terraformStatePath),
 		zap.Strings("instance_ids", instanceIDs),
 		zap.Strings("attributes", attributes),
-		zap.String("output_format", outputFormat),
+		zap.Strings("output_formats", outputFormats),
 		zap.String("aws_region", awsRegion),
+		zap.Strings("resource_types", resourceTypes),
 	)
 
-	// Check if state file exists before proceeding
-	if _, err := os.Stat(terraformStatePath); os.IsNotExist(err) {
-		return fmt.Errorf("terraform state file not found: %s\n\nPlease ensure the file exists or provide the correct path using -s flag", terraformStatePath)
+	specs, err := outputSpecs()
+	if err != nil {
+		return err
+	}
+
+	var failOnSeverity models.Severity
+	if failOn != "" {
+		failOnSeverity, err = models.ParseSeverity(failOn)
+		if err != nil {
+			return fmt.Errorf("invalid --fail-on value: %w", err)
+		}
 	}
 
 	ctx := context.Background()
 
-	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(awsRegion))
-	if err != nil {
-		logger.Error("failed to load AWS config",
-			zap.String("region", awsRegion),
-			zap.Error(err),
-		)
-		return fmt.Errorf("failed to load AWS config: %w", err)
+	if metricsFile != "" {
+		scanMetrics = metrics.New()
 	}
 
-	awsClient, err := aws.NewAWSClient(ctx, awsRegion, ec2.NewFromConfig(cfg), logger)
+	driftService, err := newDriftService(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to initialize AWS client: %w", err)
+		return err
 	}
 
-	awsProvider := aws.NewStateProvider(awsClient)
-	tfClient := terraform.NewTerraformClient(logger)
-	comparator := models.NewAttributeComparator(logger)
-	driftService := service.NewDriftService(awsProvider, tfClient, comparator, logger)
-
-	reports, err := driftService.DetectDrift(ctx, terraformStatePath, instanceIDs, attributes)
+	outputs, err := output.Open(output.NewRegistry(), specs, os.Stdout, output.Options{
+		Logger:       logger,
+		TemplatePath: templatePath,
+	})
 	if err != nil {
-		return handleDriftError(err, reports, outputFormat, logger)
+		return err
 	}
 
-	return outputReports(reports, outputFormat, logger)
-}
+	// Streaming formats such as NDJSON write each report as it completes.
+	req := buildDetectRequest()
+	if outputs.Streaming() {
+		req.OnReport = outputs.WriteReport
+	}
 
-func handleDriftError(err error, reports []*models.DriftReport, format string, logger *flog.Logger) error {
-	if len(reports) > 0 {
-		fmt.Fprintf(os.Stderr, "\n⚠️  Warning: Drift detection completed with partial failures\n")
-		fmt.Fprintf(os.Stderr, "Successfully checked: %d instance(s)\n", len(reports))
-		fmt.Fprintf(os.Stderr, "Error details: %v\n\n", err)
+	started := time.Now()
+	reports, detectErr := driftService.DetectResources(ctx, terraformStatePath, req)
 
-		logger.Warn("partial failure during drift detection", zap.Error(err))
+	run := newRun(reports, started)
+	if detectErr != nil {
+		err = handleDriftError(outputs, run, detectErr, logger)
+	} else {
+		err = outputs.Write(run)
+	}
+	if closeErr := outputs.Close(); err == nil {
+		err = closeErr
+	}
+	if metricsFile != "" {
+		// Written for failed runs too, so they show up in monitoring.
+		if metricsErr := writeMetricsFile(run, detectErr); err == nil {
+			err = metricsErr
+		}
+	}
+	if err != nil {
+		return err
+	}
 
-		return outputReports(reports, format, logger)
+	if !noHistory {
+		saveHistory(run)
 	}
 
-	return fmt.Errorf("drift detection failed: %w", err)
+	return exitStatus(reports, detectErr != nil, failOnSeverity)
 }
 
-func outputReports(reports []*models.DriftReport, format string, logger *flog.Logger) error {
-	switch format {
-	case "json":
-		return outputJSON(reports, logger)
-	case "text":
-		return outputText(reports, logger)
-	default:
-		return fmt.Errorf("unsupported output format: %s (use 'text' or 'json')", format)
+// newRun describes a scan that started at started and has just finished.
+func newRun(reports []*models.DriftReport, started time.Time) *output.Run {
+	return &output.Run{
+		ID:          output.NewRunID(),
+		ToolVersion: version,
+		Reports:     reports,
+		StatePath:   terraformStatePath,
+		Region:      awsRegion,
+		Attributes:  attributes,
+		Started:     started,
+		Finished:    time.Now(),
 	}
 }
 
-func outputJSON(reports []*models.DriftReport, logger *flog.Logger) error {
-	logger.Debug("formatting output as JSON")
-
-	encoder := json.NewEncoder(os.Stdout)
-	encoder.SetIndent("", "  ")
-
-	if err := encoder.Encode(reports); err != nil {
-		return fmt.Errorf("failed to encode JSON output: %w", err)
+// writeMetricsFile records the run in --metrics-file. A run that checked
+// nothing counts as a failed scan.
+func writeMetricsFile(run *output.Run, detectErr error) error {
+	if detectErr != nil && len(run.Reports) == 0 {
+		scanMetrics.ScanFailed()
+	} else {
+		scanMetrics.ObserveScan(run)
 	}
-
-	return nil
+	return scanMetrics.WriteTextfile(metricsFile)
 }
 
-func outputText(reports []*models.DriftReport, logger *flog.Logger) error {
-	logger.Debug("formatting output as text")
+// exitStatus applies the exit code contract once reports have been written.
+// With --detailed-exitcode it is 2 whenever there is drift, 3 when there is
+// none but some resource could not be checked, and 0 otherwise; drift takes
+// precedence so that it is never hidden by resources that could not be
+// checked. --fail-on only decides whether the run fails: drift at or above it
+// is reported as an error and, without --detailed-exitcode, exits 2. Fatal
+// errors never reach this point and exit 1.
+func exitStatus(reports []*models.DriftReport, partialFailure bool, threshold models.Severity) error {
+	drifted, failing := 0, 0
+	for _, report := range reports {
+		if !report.HasDrift {
+			continue
+		}
+		drifted++
+		if threshold != "" && report.Severity.AtLeast(threshold) {
+			failing++
+		}
+	}
 
-	fmt.Printf("\n")
-	fmt.Printf("╔═══════════════════════════════════════════════════════════╗\n")
-	fmt.Printf("║           FIREFLY DRIFT DETECTION REPORT                  ║\n")
-	fmt.Printf("╚═══════════════════════════════════════════════════════════╝\n")
-	fmt.Printf("\n")
+	var err error
+	if failing > 0 {
+		err = fmt.Errorf("%d resource(s) have drift at or above %s severity", failing, threshold)
+	}
 
-	if len(reports) == 0 {
-		fmt.Printf("No instances were checked.\n\n")
+	switch {
+	case detailedExitCode && drifted > 0:
+		return &ExitCodeError{Code: ExitDrift, Err: err}
+	case detailedExitCode && partialFailure:
+		return &ExitCodeError{Code: ExitPartialFailure}
+	case failing > 0:
+		return &ExitCodeError{Code: ExitDrift, Err: err}
+	default:
 		return nil
 	}
+}
 
-	totalDrifts := 0
-	for _, report := range reports {
-		fmt.Printf("Instance: %s\n", report.InstanceID)
-		fmt.Printf("Status: %s\n", getDriftStatus(report.HasDrift))
-
-		if report.HasDrift {
-			totalDrifts++
-			fmt.Printf("Drifted Attributes (%d):\n", len(report.Drifts))
-			for _, drift := range report.Drifts {
-				fmt.Printf("  • %s:\n", drift.AttributeName)
-				fmt.Printf("    Expected: %v\n", formatValue(drift.ExpectedValue))
-				fmt.Printf("    Actual:   %v\n", formatValue(drift.ActualValue))
-				fmt.Printf("    Type:     %s\n", drift.DriftType)
-			}
-		}
-		fmt.Println()
-	}
+// handleDriftError writes the reports of a run in which some resources could
+// not be checked. With --detailed-exitcode, a run in which every resource
+// failed on its own is written too, so that it exits 3 like any other partial
+// failure; anything else that checked nothing, such as unreadable state, is
+// fatal.
+func handleDriftError(outputs *output.Set, run *output.Run, err error, logger *flog.Logger) error {
+	if len(run.Reports) > 0 || (detailedExitCode && onlyResourcesFailed(err)) {
+		fmt.Fprintf(os.Stderr, "\n⚠️  Warning: Drift detection completed with partial failures\n")
+		fmt.Fprintf(os.Stderr, "Successfully checked: %d resource(s)\n", len(run.Reports))
+		fmt.Fprintf(os.Stderr, "Error details: %v\n\n", err)
 
-	fmt.Printf("───────────────────────────────────────────────────────────\n")
-	fmt.Printf("Summary: %d/%d instances have drift\n", totalDrifts, len(reports))
-	fmt.Printf("\n")
+		logger.Warn("partial failure during drift detection", zap.Error(err))
 
-	logger.Info("drift report generated",
-		zap.Int("total_instances", len(reports)),
-		zap.Int("instances_with_drift", totalDrifts),
-	)
+		run.Failures = service.Failures(err)
+		return outputs.Write(run)
+	}
 
-	return nil
+	return fmt.Errorf("drift detection failed: %w", err)
 }
 
-func getDriftStatus(hasDrift bool) string {
-	if hasDrift {
-		return "⚠  DRIFT DETECTED"
+// onlyResourcesFailed reports whether err lists resources that could not be
+// checked and nothing else, such as a resource type whose state could not be
+// read.
+func onlyResourcesFailed(err error) bool {
+	failures := service.Failures(err)
+	for _, failure := range failures {
+		if failure.ResourceID == "" {
+			return false
+		}
 	}
-	return "✓ NO DRIFT"
+	return len(failures) > 0
 }
 
-func formatValue(v interface{}) string {
-	switch val := v.(type) {
-	case []string:
-		return fmt.Sprintf("[%s]", strings.Join(val, ", "))
-	case map[string]string:
-		return fmt.Sprintf("%v", val)
-	default:
-		return fmt.Sprintf("%v", val)
+// outputSpecs parses -f, sending the output given without a file to
+// --output-file when it is set.
+func outputSpecs() ([]output.Spec, error) {
+	specs, err := output.ParseSpecs(outputFormats, outputFile)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --format value: %w", err)
 	}
+
+	formats := output.NewRegistry().Names()
+	for i := range specs {
+		if !slices.Contains(formats, specs[i].Format) {
+			return nil, fmt.Errorf("unsupported output format: %s (use %s)", specs[i].Format, strings.Join(formats, ", "))
+		}
+	}
+
+	return specs, nil
 }
@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"firefly-ec2-drift-detector/models"
+	"firefly-ec2-drift-detector/service"
+)
+
+func TestExitStatus(t *testing.T) {
+	drifted := &models.DriftReport{InstanceID: "i-1", HasDrift: true, Severity: models.SeverityLow}
+	clean := &models.DriftReport{InstanceID: "i-2"}
+
+	tests := []struct {
+		name           string
+		detailed       bool
+		reports        []*models.DriftReport
+		partialFailure bool
+		threshold      models.Severity
+		want           int
+		failing        bool // the run fails with an error naming the threshold
+	}{
+		{name: "no drift", detailed: true, reports: []*models.DriftReport{clean}, want: ExitOK},
+		{name: "drift", detailed: true, reports: []*models.DriftReport{drifted}, want: ExitDrift},
+		{name: "partial failure", detailed: true, reports: []*models.DriftReport{clean}, partialFailure: true, want: ExitPartialFailure},
+		{name: "every resource failed", detailed: true, partialFailure: true, want: ExitPartialFailure},
+		{name: "drift and partial failure", detailed: true, reports: []*models.DriftReport{drifted, clean}, partialFailure: true, want: ExitDrift},
+		{name: "drift below threshold", detailed: true, reports: []*models.DriftReport{drifted}, threshold: models.SeverityHigh, want: ExitDrift},
+		{name: "drift below threshold and partial failure", detailed: true, reports: []*models.DriftReport{drifted}, partialFailure: true, threshold: models.SeverityHigh, want: ExitDrift},
+		{name: "no drift with threshold and partial failure", detailed: true, reports: []*models.DriftReport{clean}, partialFailure: true, threshold: models.SeverityHigh, want: ExitPartialFailure},
+		{name: "drift at threshold", detailed: true, reports: []*models.DriftReport{drifted}, threshold: models.SeverityLow, want: ExitDrift, failing: true},
+		{name: "drift without detailed exit code", reports: []*models.DriftReport{drifted}, partialFailure: true, want: ExitOK},
+		{name: "partial failure without detailed exit code", reports: []*models.DriftReport{clean}, partialFailure: true, want: ExitOK},
+		{name: "drift below threshold without detailed exit code", reports: []*models.DriftReport{drifted}, threshold: models.SeverityHigh, want: ExitOK},
+		{name: "drift at threshold without detailed exit code", reports: []*models.DriftReport{drifted}, partialFailure: true, threshold: models.SeverityLow, want: ExitDrift, failing: true},
+	}
+
+	t.Cleanup(func() { detailedExitCode = false })
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			detailedExitCode = tt.detailed
+
+			err := exitStatus(tt.reports, tt.partialFailure, tt.threshold)
+
+			got := ExitOK
+			var exitErr *ExitCodeError
+			if errors.As(err, &exitErr) {
+				got = exitErr.Code
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("exit code = %d, want %d", got, tt.want)
+			}
+			if failing := exitErr != nil && exitErr.Err != nil; failing != tt.failing {
+				t.Errorf("run failing = %v, want %v", failing, tt.failing)
+			}
+		})
+	}
+}
+
+func TestOnlyResourcesFailed(t *testing.T) {
+	resourceFailure := &service.ResourceError{ResourceType: models.ResourceTypeInstance, ResourceID: "i-1", Err: errors.New("throttled")}
+	typeFailure := &service.ResourceError{ResourceType: models.ResourceTypeInstance, Err: errors.New("unreadable state")}
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "resource failures", err: &service.PartialFailureError{Failures: []*service.ResourceError{resourceFailure}}, want: true},
+		{name: "type failure", err: &service.PartialFailureError{Failures: []*service.ResourceError{resourceFailure, typeFailure}}, want: false},
+		{name: "other error", err: errors.New("no credentials"), want: false},
+	}
+
+	for _, tt := range tests {
+		if got := onlyResourcesFailed(tt.err); got != tt.want {
+			t.Errorf("%s: onlyResourcesFailed = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestOutputSpecs_OutputFileSharedByFormats(t *testing.T) {
+	t.Cleanup(func() { outputFormats, outputFile = nil, "" })
+
+	outputFormats, outputFile = []string{"json,sarif"}, "out"
+	if _, err := outputSpecs(); err == nil || !strings.Contains(err.Error(), "both write to out") {
+		t.Errorf("expected json and sarif sharing -o to fail, got %v", err)
+	}
+
+	outputFormats = []string{"json,sarif=drift.sarif"}
+	specs, err := outputSpecs()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if specs[0].Path != "out" || specs[1].Path != "drift.sarif" {
+		t.Errorf("unexpected outputs: %+v", specs)
+	}
+}
@@ -0,0 +1,187 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"firefly-ec2-drift-detector/history"
+	"firefly-ec2-drift-detector/output"
+)
+
+var (
+	historyLimit   int
+	diffRunsFormat string
+)
+
+const historyTimeLayout = "2006-01-02 15:04:05 MST"
+
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "List previous drift detection runs",
+	Long: `List the runs saved to the run history by the detector command, oldest
+first, with their drift counts. Pass two run IDs, or unique prefixes of them,
+to diff-runs to compare runs.`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := openHistory()
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+
+		runs, err := store.Runs(historyLimit)
+		if err != nil {
+			return err
+		}
+		if len(runs) == 0 {
+			fmt.Println("No runs recorded yet.")
+			return nil
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "RUN ID\tSTARTED\tRESOURCES\tDRIFTED\tNOT CHECKED\tSEVERITY\tSTATE")
+		for _, run := range runs {
+			fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%d\t%s\t%s\n",
+				run.ID, run.StartedAt.Local().Format(historyTimeLayout),
+				run.Summary.ResourcesChecked, run.Summary.ResourcesDrifted, run.Summary.ResourcesNotChecked,
+				valueOr(run.Summary.HighestSeverity, "-"), run.StateSource)
+		}
+		return w.Flush()
+	},
+}
+
+var diffRunsCmd = &cobra.Command{
+	Use:   "diff-runs <a> <b>",
+	Short: "Compare the drift of two runs",
+	Long: `Compare the drift reported by two runs from the run history: drift new in
+<b>, drift resolved since <a>, and drift present in both. New and persistent
+drift show when they were first seen. Drift is first seen in the earliest run
+of the unbroken streak of runs that reported it.
+
+Runs are given by ID or by a unique prefix, as listed by the history command.
+
+Examples:
+  firefly diff-runs 0b6f2c1e 7a1d9e44
+  firefly diff-runs 0b6f2c1e 7a1d9e44 -f json`,
+	Args:         cobra.ExactArgs(2),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if diffRunsFormat != "text" && diffRunsFormat != "json" {
+			return fmt.Errorf("unsupported output format: %s (use text or json)", diffRunsFormat)
+		}
+
+		store, err := openHistory()
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+
+		diff, err := store.Diff(args[0], args[1])
+		if err != nil {
+			return err
+		}
+
+		if diffRunsFormat == "json" {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(diff)
+		}
+		printRunDiff(diff)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(historyCmd)
+	rootCmd.AddCommand(diffRunsCmd)
+
+	historyCmd.Flags().IntVarP(&historyLimit, "limit", "n", 20, "Show only the latest n runs (0 for all)")
+	diffRunsCmd.Flags().StringVarP(&diffRunsFormat, "format", "f", "text", "Output format: text or json")
+}
+
+// openHistory opens --history-db, or the default history file.
+func openHistory() (*history.Store, error) {
+	path := historyPath
+	if path == "" {
+		var err error
+		if path, err = history.DefaultPath(); err != nil {
+			return nil, err
+		}
+	}
+	return history.Open(path)
+}
+
+// saveHistory records a finished run. A history that cannot be written does
+// not fail the run, whose reports have already been written.
+func saveHistory(run *output.Run) {
+	store, err := openHistory()
+	if err != nil {
+		logger.Warn("failed to open run history", zap.Error(err))
+		return
+	}
+	defer store.Close()
+
+	if err := store.Save(output.NewDocument(run)); err != nil {
+		logger.Warn("failed to save run to history", zap.String("run_id", run.ID), zap.Error(err))
+		return
+	}
+	logger.Info("run saved to history", zap.String("run_id", run.ID))
+}
+
+func printRunDiff(diff *history.RunDiff) {
+	fmt.Printf("Comparing run %s (%s) with run %s (%s)\n",
+		diff.From, diff.FromStarted.Local().Format(historyTimeLayout),
+		diff.To, diff.ToStarted.Local().Format(historyTimeLayout))
+
+	sections := []struct {
+		title    string
+		marker   string
+		findings []history.Finding
+	}{
+		{"New drift", "+", diff.New},
+		{"Resolved drift", "-", diff.Resolved},
+		{"Persistent drift", "~", diff.Persistent},
+		{"No longer checked", "?", diff.Unchecked},
+	}
+
+	for _, section := range sections {
+		if len(section.findings) == 0 {
+			continue
+		}
+		fmt.Printf("\n%s (%d):\n", section.title, len(section.findings))
+		for _, f := range section.findings {
+			fmt.Printf("  %s %s %s %s: %s → %s", section.marker, f.ResourceType, f.ResourceID,
+				f.Attribute, describeValue(f.Expected), describeValue(f.Actual))
+			if !f.FirstSeen.IsZero() {
+				fmt.Printf(" (first seen %s)", f.FirstSeen.Local().Format(historyTimeLayout))
+			}
+			fmt.Println()
+		}
+	}
+
+	fmt.Printf("\nSummary: %d new, %d resolved, %d persistent\n", len(diff.New), len(diff.Resolved), len(diff.Persistent))
+}
+
+// describeValue prints strings as they are and other values as JSON.
+func describeValue(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
+func valueOr(s, fallback string) string {
+	if s == "" {
+		return fallback
+	}
+	return s
+}
@@ -1,19 +1,58 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
+	"time"
 
 	flog "firefly-ec2-drift-detector/logger"
+	"firefly-ec2-drift-detector/tracing"
 	"github.com/spf13/cobra"
 )
 
+// Exit codes. ExitDrift and ExitPartialFailure are only used with
+// --detailed-exitcode or --fail-on.
+const (
+	ExitOK             = 0
+	ExitError          = 1
+	ExitDrift          = 2
+	ExitPartialFailure = 3
+)
+
+// traceShutdownTimeout bounds how long exiting waits for spans to be exported.
+const traceShutdownTimeout = 5 * time.Second
+
 var (
-	logger  *flog.Logger
-	verbose bool
-	err     error
+	logger      *flog.Logger
+	verbose     bool
+	historyPath string
+	err         error
+
+	traceExporter string
+	traceEndpoint string
+	traceFile     string
+	stopTracing   = func(context.Context) error { return nil }
 )
 
+// ExitCodeError ends the process with Code. Err, when set, is printed first.
+type ExitCodeError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExitCodeError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("exit status %d", e.Code)
+	}
+	return e.Err.Error()
+}
+
+func (e *ExitCodeError) Unwrap() error {
+	return e.Err
+}
+
 var rootCmd = &cobra.Command{
 	Use:   "firefly",
 	Short: "EC2 drift detection tool",
@@ -22,6 +61,31 @@ AWS EC2 instances and their Terraform state definitions.
 
 It compares live infrastructure state with expected state defined in 
 Terraform and reports any discrepancies found.`,
+	SilenceErrors:     true,
+	PersistentPreRunE: setupTracing,
+}
+
+func setupTracing(cmd *cobra.Command, args []string) error {
+	shutdown, err := tracing.Setup(cmd.Context(), tracing.Config{
+		Exporter:       traceExporter,
+		Endpoint:       traceEndpoint,
+		FilePath:       traceFile,
+		ServiceVersion: version,
+	})
+	if err != nil {
+		return err
+	}
+	stopTracing = shutdown
+	return nil
+}
+
+// flushTraces exports the spans not yet exported before the process exits.
+func flushTraces() {
+	ctx, cancel := context.WithTimeout(context.Background(), traceShutdownTimeout)
+	defer cancel()
+	if err := stopTracing(ctx); err != nil {
+		fmt.Fprintln(os.Stderr, "Warning: failed to export traces:", err)
+	}
 }
 
 func buildLogger() {
@@ -42,12 +106,29 @@ func buildLogger() {
 }
 
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
-		os.Exit(1)
+	err := rootCmd.Execute()
+	flushTraces()
+	if err == nil {
+		os.Exit(ExitOK)
 	}
+
+	var exitErr *ExitCodeError
+	if errors.As(err, &exitErr) {
+		if exitErr.Err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", exitErr.Err)
+		}
+		os.Exit(exitErr.Code)
+	}
+
+	fmt.Fprintln(os.Stderr, "Error:", err)
+	os.Exit(ExitError)
 }
 
 func init() {
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Enable verbose logging")
+	rootCmd.PersistentFlags().StringVar(&historyPath, "history-db", "", "Run history file (default ~/.firefly/history.db)")
+	rootCmd.PersistentFlags().StringVar(&traceExporter, "trace-exporter", tracing.ExporterNone, "Export OpenTelemetry traces: none, otlp or file")
+	rootCmd.PersistentFlags().StringVar(&traceEndpoint, "trace-endpoint", "", "OTLP/HTTP endpoint URL for --trace-exporter otlp (default from OTEL_EXPORTER_OTLP_* or http://localhost:4318)")
+	rootCmd.PersistentFlags().StringVar(&traceFile, "trace-file", "firefly-traces.jsonl", "File to append spans to as JSON for --trace-exporter file")
 	cobra.OnInitialize(buildLogger)
 }
@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/autoscaling"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"firefly-ec2-drift-detector/aws"
+	"firefly-ec2-drift-detector/metrics"
+	"firefly-ec2-drift-detector/models"
+	"firefly-ec2-drift-detector/service"
+	"firefly-ec2-drift-detector/terraform"
+)
+
+// scanMetrics, when set before the drift service is built, records the AWS
+// API calls of its scans.
+var scanMetrics *metrics.Metrics
+
+// addScanFlags registers the flags that choose what a scan checks, and the
+// comparison flags, for every command that scans a state given on the
+// command line.
+func addScanFlags(cmd *cobra.Command) {
+	flags := cmd.Flags()
+
+	flags.StringVarP(&terraformStatePath, "state", "s", "", "Path to Terraform state file (required)")
+	flags.StringSliceVarP(&instanceIDs, "instances", "i", []string{}, "Comma-separated list of instance IDs (empty = all instances in state)")
+	flags.StringSliceVarP(&attributes, "attributes", "a", []string{"InstanceType"}, "Comma-separated list of attributes or attribute paths to check (e.g. Tags.Owner, ami, all)")
+
+	flags.StringSliceVarP(&resourceTypes, "resource-types", "t", []string{models.ResourceTypeInstance}, "Comma-separated list of Terraform resource types to check")
+	flags.BoolVar(&securityGroups, "security-groups", false, "Also check ingress/egress rule drift for security groups in the state")
+	flags.StringSliceVar(&securityGroupIDs, "security-group-ids", []string{}, "Comma-separated list of security group IDs to check (implies --security-groups)")
+
+	cmd.MarkFlagRequired("state")
+
+	addComparisonFlags(cmd)
+}
+
+// addComparisonFlags registers the flags that configure how the drift
+// service compares, for every command that builds one.
+func addComparisonFlags(cmd *cobra.Command) {
+	flags := cmd.Flags()
+
+	flags.StringVarP(&awsRegion, "region", "r", "us-east-1", "AWS region")
+
+	flags.StringSliceVar(&ignoreTags, "ignore-tags", []string{}, "Tag keys to leave out of Tags comparisons")
+	flags.StringSliceVar(&ignoreTagPrefixes, "ignore-tag-prefixes", []string{}, "Tag key prefixes to leave out of Tags comparisons")
+	flags.StringArrayVar(&ignoreTagPatterns, "ignore-tag-patterns", []string{}, "Regular expressions matching tag keys to leave out of Tags comparisons (repeatable)")
+	flags.BoolVar(&noDefaultTagIgnores, "no-default-tag-ignores", false, "Also compare AWS-managed tags (aws:*), which are ignored by default")
+	flags.StringToStringVar(&amiAliases, "ami-alias", map[string]string{}, "AMI aliases to resolve before comparing, as alias=ami-id (e.g. resolve:ssm:/aws/service/...=ami-0abc)")
+
+	flags.StringVar(&severityPolicyPath, "severity-policy", "", "JSON file of rules assigning a severity to drifts (see README)")
+}
+
+// newDriftService checks the scan flags and builds the service that runs the
+// scan, with its AWS clients. --attributes is replaced by its resolved form.
+func newDriftService(ctx context.Context) (*service.DriftService, error) {
+	if _, err := os.Stat(terraformStatePath); os.IsNotExist(err) {
+		return nil, fmt.Errorf("terraform state file not found: %s\n\nPlease ensure the file exists or provide the correct path using -s flag", terraformStatePath)
+	}
+
+	resolvedAttrs, err := models.ResolveAttributes(attributes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --attributes value: %w\n\nRun 'firefly attributes' to list supported attributes", err)
+	}
+	attributes = resolvedAttrs
+
+	return newComparisonService(ctx)
+}
+
+// newComparisonService builds the drift service from the comparison flags,
+// for commands that choose the state and attributes per scan.
+func newComparisonService(ctx context.Context) (*service.DriftService, error) {
+	var err error
+	severityPolicy := models.DefaultSeverityPolicy()
+	if severityPolicyPath != "" {
+		severityPolicy, err = models.LoadSeverityPolicy(severityPolicyPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var baseline *models.Baseline
+	if baselinePath != "" {
+		baseline, err = models.LoadBaseline(baselinePath)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	tagIgnores, err := models.NewTagIgnoreRules(ignoreTags, ignoreTagPrefixes, ignoreTagPatterns, !noDefaultTagIgnores)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(awsRegion))
+	if err != nil {
+		logger.Error("failed to load AWS config",
+			zap.String("region", awsRegion),
+			zap.Error(err),
+		)
+		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+
+	clientOpts := []aws.ClientOption{aws.WithAutoScalingClient(autoscaling.NewFromConfig(cfg))}
+	if scanMetrics != nil {
+		clientOpts = append(clientOpts, aws.WithAPIObserver(scanMetrics))
+	}
+	awsClient, err := aws.NewAWSClient(ctx, awsRegion, ec2.NewFromConfig(cfg), logger, clientOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize AWS client: %w", err)
+	}
+
+	awsProvider := aws.NewStateProvider(awsClient)
+	tfClient := terraform.NewTerraformClient(logger)
+	comparator := models.NewAttributeComparator(logger,
+		models.WithTagIgnoreRules(tagIgnores),
+		models.WithNormalizers(models.DefaultNormalizers().WithAMIAliases(amiAliases)),
+	)
+	driftService := service.NewDriftService(awsProvider, tfClient, comparator, logger)
+	driftService.SetSeverityPolicy(severityPolicy)
+	driftService.SetBaseline(baseline)
+
+	return driftService, nil
+}
+
+// buildDetectRequest maps the per-type flags onto a service request. -i and -a
+// apply to aws_instance; other types compare all of their attributes.
+func buildDetectRequest() service.DetectRequest {
+	types := resourceTypes
+	if (securityGroups || len(securityGroupIDs) > 0) && !slices.Contains(types, models.ResourceTypeSecurityGroup) {
+		types = append(types, models.ResourceTypeSecurityGroup)
+	}
+
+	return service.DetectRequest{
+		Types: types,
+		IDs: map[string][]string{
+			models.ResourceTypeInstance:      instanceIDs,
+			models.ResourceTypeSecurityGroup: securityGroupIDs,
+		},
+		Attributes: map[string][]string{
+			models.ResourceTypeInstance: attributes,
+		},
+	}
+}
@@ -0,0 +1,24 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"firefly-ec2-drift-detector/output"
+)
+
+var schemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Print the JSON Schema of the JSON report",
+	Long: `Print the JSON Schema (draft 2020-12) describing the document written by
+the detector command with -f json. Its schema_version is ` + output.SchemaVersion + `.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		_, err := os.Stdout.Write(output.Schema)
+		return err
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(schemaCmd)
+}
@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"firefly-ec2-drift-detector/metrics"
+	"firefly-ec2-drift-detector/server"
+)
+
+var (
+	serveAddr           string
+	serveStateDir       string
+	serveScanTimeout    time.Duration
+	serveRequestTimeout time.Duration
+	serveWorkers        int
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve drift detection over an HTTP API",
+	Long: `Serve a JSON API that runs drift detection in the background and returns the
+results. Scans are submitted as jobs, run by --workers workers, and their
+reports follow the JSON report schema printed by 'firefly schema'.
+
+  POST /v1/scans               Queue a scan: {"state", "instances", "attributes", "resource_types"}
+  GET  /v1/scans/{id}          Scan status and summary
+  GET  /v1/scans/{id}/report   Report of a finished scan
+  GET  /v1/instances/{id}      Latest result for an instance
+  GET  /healthz                Liveness
+  GET  /metrics                Prometheus metrics
+
+State paths in requests are relative to --state-dir. The comparison flags,
+such as --region and --severity-policy, apply to every scan. Scans are saved
+to the run history unless --no-history is given. SIGINT or SIGTERM stops the
+server.
+
+Examples:
+  firefly serve --addr :8080 --state-dir /srv/terraform
+  curl -X POST localhost:8080/v1/scans -d '{"state": "prod/terraform.tfstate", "attributes": ["all"]}'
+  curl localhost:8080/v1/scans/0b6f2c1e-3f4a-4d5b-9c6d-7e8f9a0b1c2d/report`,
+	SilenceUsage: true,
+	RunE:         runServe,
+}
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+
+	addComparisonFlags(serveCmd)
+
+	serveCmd.Flags().StringVar(&serveAddr, "addr", ":8080", "Address to listen on")
+	serveCmd.Flags().StringVar(&serveStateDir, "state-dir", ".", "Directory that state paths in scan requests are relative to")
+	serveCmd.Flags().DurationVar(&serveScanTimeout, "scan-timeout", 10*time.Minute, "Time a scan may run before it fails")
+	serveCmd.Flags().DurationVar(&serveRequestTimeout, "request-timeout", 30*time.Second, "Time an HTTP request may take")
+	serveCmd.Flags().IntVar(&serveWorkers, "workers", 2, "Number of scans run at once")
+	serveCmd.Flags().StringVar(&baselinePath, "baseline", "", "JSON file of accepted drifts to report as suppressed (see 'firefly baseline create')")
+	serveCmd.Flags().BoolVar(&noHistory, "no-history", false, "Do not save scans to the run history")
+}
+
+func runServe(cmd *cobra.Command, args []string) error {
+	if serveWorkers < 1 {
+		return fmt.Errorf("invalid --workers value %d: must be at least 1", serveWorkers)
+	}
+	if serveScanTimeout <= 0 || serveRequestTimeout <= 0 {
+		return fmt.Errorf("--scan-timeout and --request-timeout must be positive")
+	}
+	if info, err := os.Stat(serveStateDir); err != nil || !info.IsDir() {
+		return fmt.Errorf("state directory not found: %s", serveStateDir)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	scanMetrics = metrics.New()
+	driftService, err := newComparisonService(ctx)
+	if err != nil {
+		return err
+	}
+
+	opts := []server.Option{
+		server.WithStateDir(serveStateDir),
+		server.WithRunInfo(version, awsRegion),
+		server.WithScanTimeout(serveScanTimeout),
+		server.WithRequestTimeout(serveRequestTimeout),
+		server.WithWorkers(serveWorkers),
+		server.WithMetrics(scanMetrics),
+	}
+	if !noHistory {
+		opts = append(opts, server.WithRunHook(saveHistory))
+	}
+
+	logger.Info("firefly serve started",
+		zap.String("version", version),
+		zap.String("addr", serveAddr),
+		zap.String("state_dir", serveStateDir),
+		zap.String("aws_region", awsRegion),
+	)
+
+	return server.New(driftService, logger, opts...).Run(ctx, serveAddr)
+}
@@ -6,13 +6,16 @@ import (
 	"github.com/spf13/cobra"
 )
 
+// version is reported by the version command and recorded in reports.
+const version = "1.0.0"
+
 var versionCmd = &cobra.Command{
 	Use:   "version",
 	Short: "Print the version number",
 	Long:  `Display version information for Firefly EC2 Drift Detector.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		fmt.Println("Firefly EC2 Drift Detector")
-		fmt.Println("Version: 1.0.0")
+		fmt.Println("Version: " + version)
 		fmt.Println("Built with Go")
 	},
 }
@@ -0,0 +1,173 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"firefly-ec2-drift-detector/metrics"
+	"firefly-ec2-drift-detector/output"
+	"firefly-ec2-drift-detector/service"
+	"firefly-ec2-drift-detector/watch"
+)
+
+var (
+	watchInterval time.Duration
+	watchFormat   string
+	metricsAddr   string
+)
+
+var watchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Detect drift continuously on a schedule",
+	Long: `Run drift detection now and then every --interval until interrupted. The
+first scan reports all drift; later scans report only drift that is new,
+resolved or whose values changed since the previous scan.
+
+The Terraform state is parsed again only when it changes, judged by the
+modification time and size of the state file, or of the .tf files in a state
+directory. AWS is queried on every scan.
+
+Every scan is saved to the run history unless --no-history is given.
+--metrics-addr serves Prometheus metrics of the scans at /metrics. SIGINT
+or SIGTERM stops the watch, abandoning a scan in progress.
+
+Examples:
+  # Scan every 15 minutes
+  firefly watch -s terraform.tfstate -a all
+
+  # Scan every 5 minutes, writing one JSON event per change for a log pipeline
+  firefly watch -s terraform.tfstate -a all --interval 5m -f ndjson
+
+  # Serve Prometheus metrics at http://localhost:9090/metrics
+  firefly watch -s terraform.tfstate -a all --metrics-addr :9090
+
+  # Leave out drift accepted in a baseline file
+  firefly watch -s terraform.tfstate -a all --baseline drift-baseline.json`,
+	SilenceUsage: true,
+	RunE:         runWatch,
+}
+
+func init() {
+	rootCmd.AddCommand(watchCmd)
+
+	addScanFlags(watchCmd)
+
+	watchCmd.Flags().DurationVar(&watchInterval, "interval", 15*time.Minute, "Time between the start of scans (e.g. 30s, 15m, 1h)")
+	watchCmd.Flags().StringVarP(&watchFormat, "format", "f", "text", "Output format: text, or ndjson for one JSON event per change")
+	watchCmd.Flags().StringVar(&baselinePath, "baseline", "", "JSON file of accepted drifts to leave out (see 'firefly baseline create')")
+	watchCmd.Flags().BoolVar(&noHistory, "no-history", false, "Do not save scans to the run history")
+	watchCmd.Flags().StringVar(&metricsAddr, "metrics-addr", "", "Address to serve Prometheus metrics on at /metrics (e.g. :9090)")
+}
+
+func runWatch(cmd *cobra.Command, args []string) error {
+	if watchFormat != "text" && watchFormat != "ndjson" {
+		return fmt.Errorf("unsupported output format: %s (use text or ndjson)", watchFormat)
+	}
+	if watchInterval <= 0 {
+		return fmt.Errorf("invalid --interval value %s: must be positive", watchInterval)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if metricsAddr != "" {
+		scanMetrics = metrics.New()
+		if err := scanMetrics.Serve(ctx, metricsAddr, logger); err != nil {
+			return err
+		}
+	}
+
+	driftService, err := newDriftService(ctx)
+	if err != nil {
+		return err
+	}
+
+	logger.Info("firefly watch started",
+		zap.String("version", version),
+		zap.String("terraform_state", terraformStatePath),
+		zap.Duration("interval", watchInterval),
+		zap.Strings("attributes", attributes),
+		zap.String("aws_region", awsRegion),
+	)
+
+	scan := func(ctx context.Context, stateVersion string) (*output.Run, error) {
+		req := buildDetectRequest()
+		req.StateVersion = stateVersion
+
+		started := time.Now()
+		reports, err := driftService.DetectResources(ctx, terraformStatePath, req)
+		if err != nil && len(reports) == 0 {
+			if scanMetrics != nil && ctx.Err() == nil {
+				scanMetrics.ScanFailed()
+			}
+			return nil, fmt.Errorf("drift detection failed: %w", err)
+		}
+		if err != nil && ctx.Err() == nil {
+			logger.Warn("partial failure during drift detection", zap.Error(err))
+		}
+
+		run := newRun(reports, started)
+		run.Failures = service.Failures(err)
+		return run, nil
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	onScan := func(s *watch.Scan) {
+		if scanMetrics != nil {
+			scanMetrics.ObserveScan(s.Run)
+		}
+		if !noHistory {
+			saveHistory(s.Run)
+		}
+
+		if watchFormat == "ndjson" {
+			for _, event := range s.Events() {
+				if err := encoder.Encode(event); err != nil {
+					logger.Error("failed to write watch event", zap.Error(err))
+				}
+			}
+			return
+		}
+		printWatchScan(s)
+	}
+
+	return watch.New(terraformStatePath, watchInterval, scan, onScan, logger).Run(ctx)
+}
+
+// printWatchScan prints the first scan, and later scans that changed
+// anything, with their changes.
+func printWatchScan(s *watch.Scan) {
+	events := s.Events()
+	if s.Number > 1 && len(events) == 0 {
+		return
+	}
+
+	summary := s.Run.Summary()
+	fmt.Printf("[%s] Scan %d: %d resource(s) checked, %d with drift, %d not checked",
+		s.Run.Finished.Local().Format(historyTimeLayout), s.Number, summary.Resources, summary.Drifted, summary.NotChecked)
+	if s.StateChanged {
+		fmt.Print(" (state reloaded)")
+	}
+	fmt.Println()
+
+	markers := map[string]string{watch.EventNew: "+", watch.EventResolved: "-", watch.EventChanged: "~"}
+	for _, event := range events {
+		fmt.Printf("  %s %s %s %s: %s → %s", markers[event.Type], event.ResourceType, event.ResourceID,
+			event.Attribute, describeValue(event.Expected), describeValue(event.Actual))
+		if event.Severity != "" {
+			fmt.Printf(" (%s)", event.Severity)
+		}
+		if event.Type == watch.EventResolved {
+			fmt.Print(" resolved")
+		}
+		fmt.Println()
+	}
+}
@@ -0,0 +1,193 @@
+package history
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"firefly-ec2-drift-detector/output"
+)
+
+type (
+	// Finding is one drifted attribute of one resource.
+	Finding struct {
+		ResourceType string `json:"resource_type"`
+		ResourceID   string `json:"resource_id"`
+		Address      string `json:"address,omitempty"`
+		output.DocumentDrift
+
+		// FirstSeen is when the run that first reported this drift, without
+		// it being resolved since, started. Zero for resolved drift.
+		FirstSeen    time.Time `json:"first_seen,omitzero"`
+		FirstSeenRun string    `json:"first_seen_run,omitempty"`
+	}
+
+	// RunDiff compares the drift of two runs.
+	RunDiff struct {
+		From        string    `json:"from"`
+		FromStarted time.Time `json:"from_started_at"`
+		To          string    `json:"to"`
+		ToStarted   time.Time `json:"to_started_at"`
+
+		New        []Finding `json:"new"`        // in To only
+		Resolved   []Finding `json:"resolved"`   // in From, and checked without drift in To
+		Persistent []Finding `json:"persistent"` // in both, with To's values
+		Changed    []Finding `json:"changed"`    // persistent, with other values than in From
+		Unchecked  []Finding `json:"unchecked"`  // in From, but not checked in To
+	}
+)
+
+// Diff compares the drift reported by two runs. A drift is identified by
+// its resource and attribute, so a drift whose values changed between the
+// runs is persistent. FirstSeen is left unset; see Store.Diff.
+func Diff(from, to *output.Document) *RunDiff {
+	diff := &RunDiff{
+		From:        from.RunID,
+		FromStarted: from.StartedAt,
+		To:          to.RunID,
+		ToStarted:   to.StartedAt,
+		New:         []Finding{},
+		Resolved:    []Finding{},
+		Persistent:  []Finding{},
+		Changed:     []Finding{},
+		Unchecked:   []Finding{},
+	}
+
+	before := findings(from)
+	after := findings(to)
+
+	for key, finding := range after {
+		if previous, ok := before[key]; ok {
+			diff.Persistent = append(diff.Persistent, finding)
+			if !reflect.DeepEqual(previous.Expected, finding.Expected) || !reflect.DeepEqual(previous.Actual, finding.Actual) {
+				diff.Changed = append(diff.Changed, finding)
+			}
+		} else {
+			diff.New = append(diff.New, finding)
+		}
+	}
+
+	checked := resources(to)
+	for key, finding := range before {
+		if _, ok := after[key]; ok {
+			continue
+		}
+		if resource, ok := checked[resourceKey(finding.ResourceType, finding.ResourceID)]; ok && checks(resource, finding.Attribute) {
+			diff.Resolved = append(diff.Resolved, finding)
+		} else {
+			diff.Unchecked = append(diff.Unchecked, finding)
+		}
+	}
+
+	for _, list := range [][]Finding{diff.New, diff.Resolved, diff.Persistent, diff.Changed, diff.Unchecked} {
+		sortFindings(list)
+	}
+	return diff
+}
+
+// Diff compares two stored runs, given by ID or unique ID prefix, and sets
+// FirstSeen on their new and persistent drift. A drift is first seen in the
+// earliest run of the streak of runs reporting it that ends with to; runs
+// that did not check the resource do not break a streak.
+func (s *Store) Diff(fromID, toID string) (*RunDiff, error) {
+	from, err := s.Load(fromID)
+	if err != nil {
+		return nil, err
+	}
+	to, err := s.Load(toID)
+	if err != nil {
+		return nil, err
+	}
+
+	firstSeen := make(map[string]*output.Document)
+	err = s.walk(func(doc *output.Document) bool {
+		current := findings(doc)
+		for key := range current {
+			if _, ok := firstSeen[key]; !ok {
+				firstSeen[key] = doc
+			}
+		}
+
+		checked := resources(doc)
+		for key := range firstSeen {
+			if _, ok := current[key]; ok {
+				continue
+			}
+			resourceType, resourceID, attribute := splitKey(key)
+			if resource, ok := checked[resourceKey(resourceType, resourceID)]; ok && checks(resource, attribute) {
+				delete(firstSeen, key)
+			}
+		}
+
+		return doc.RunID != to.RunID
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	diff := Diff(from, to)
+	for _, list := range [][]Finding{diff.New, diff.Persistent, diff.Changed} {
+		for i := range list {
+			if run, ok := firstSeen[findingKey(list[i])]; ok {
+				list[i].FirstSeen = run.StartedAt
+				list[i].FirstSeenRun = run.RunID
+			}
+		}
+	}
+	return diff, nil
+}
+
+func findings(doc *output.Document) map[string]Finding {
+	result := make(map[string]Finding)
+	for _, resource := range doc.Resources {
+		for _, drift := range resource.Drifts {
+			finding := Finding{
+				ResourceType:  resource.ResourceType,
+				ResourceID:    resource.ResourceID,
+				Address:       resource.Address,
+				DocumentDrift: drift,
+			}
+			result[findingKey(finding)] = finding
+		}
+	}
+	return result
+}
+
+func resources(doc *output.Document) map[string]output.DocumentResource {
+	result := make(map[string]output.DocumentResource, len(doc.Resources))
+	for _, resource := range doc.Resources {
+		result[resourceKey(resource.ResourceType, resource.ResourceID)] = resource
+	}
+	return result
+}
+
+// checks reports whether the resource's run compared attribute, directly or
+// as part of a checked parent such as Tags for Tags.Owner.
+func checks(resource output.DocumentResource, attribute string) bool {
+	for _, checked := range resource.CheckedAttributes {
+		if attribute == checked || strings.HasPrefix(attribute, checked+".") || strings.HasPrefix(attribute, checked+"[") {
+			return true
+		}
+	}
+	return false
+}
+
+func resourceKey(resourceType, resourceID string) string {
+	return resourceType + "\x00" + resourceID
+}
+
+func findingKey(f Finding) string {
+	return resourceKey(f.ResourceType, f.ResourceID) + "\x00" + f.Attribute
+}
+
+func splitKey(key string) (resourceType, resourceID, attribute string) {
+	parts := strings.SplitN(key, "\x00", 3)
+	return parts[0], parts[1], parts[2]
+}
+
+func sortFindings(list []Finding) {
+	sort.Slice(list, func(i, j int) bool {
+		return findingKey(list[i]) < findingKey(list[j])
+	})
+}
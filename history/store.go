@@ -0,0 +1,216 @@
+// Package history persists the report of every drift detection run so that
+// runs can be compared, and drift told apart as new or long-standing.
+package history
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"firefly-ec2-drift-detector/output"
+)
+
+var (
+	// runsBucket maps run IDs to their output.Document as JSON.
+	runsBucket = []byte("runs")
+	// orderBucket maps "<started_at>/<run ID>" keys to run IDs, so a
+	// cursor walks runs in the order they started.
+	orderBucket = []byte("order")
+)
+
+// orderKeyLayout is fixed-width so keys sort in time order.
+const orderKeyLayout = "2006-01-02T15:04:05.000000000Z"
+
+var (
+	// ErrRunNotFound is returned for a run ID that matches no stored run.
+	ErrRunNotFound = errors.New("run not found")
+	// ErrAmbiguousRunID is returned for a run ID prefix that matches
+	// several stored runs.
+	ErrAmbiguousRunID = errors.New("run ID prefix matches several runs")
+)
+
+// Store is a BoltDB file of run reports, keyed by run ID.
+type Store struct {
+	db *bolt.DB
+}
+
+// RunInfo describes a stored run without its resources.
+type RunInfo struct {
+	ID          string
+	StartedAt   time.Time
+	FinishedAt  time.Time
+	StateSource string
+	Region      string
+	Summary     output.DocumentSummary
+}
+
+// DefaultPath returns ~/.firefly/history.db.
+func DefaultPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find home directory: %w", err)
+	}
+	return filepath.Join(home, ".firefly", "history.db"), nil
+}
+
+// Open opens the store at path, creating it and its directory if needed.
+// It waits up to a second for another process holding the file.
+func Open(path string) (*Store, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create history directory: %w", err)
+	}
+
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open history %s: %w", path, err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{runsBucket, orderBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize history %s: %w", path, err)
+	}
+
+	return &Store{db: db}, nil
+}
+
+// Close closes the store file.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Save stores a run's report. Saving a run ID again replaces it.
+func (s *Store) Save(doc *output.Document) error {
+	if doc.RunID == "" {
+		return errors.New("cannot save a run without an ID")
+	}
+
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return fmt.Errorf("failed to encode run %s: %w", doc.RunID, err)
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		order := tx.Bucket(orderBucket)
+		if previous, err := load(tx, doc.RunID); err == nil {
+			if err := order.Delete(orderKey(previous)); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Bucket(runsBucket).Put([]byte(doc.RunID), data); err != nil {
+			return err
+		}
+		return order.Put(orderKey(doc), []byte(doc.RunID))
+	})
+}
+
+// Load returns the run whose ID is id or, failing that, the only run whose
+// ID starts with id.
+func (s *Store) Load(id string) (*output.Document, error) {
+	var doc *output.Document
+	err := s.db.View(func(tx *bolt.Tx) error {
+		runID, err := resolve(tx, id)
+		if err != nil {
+			return err
+		}
+		doc, err = load(tx, runID)
+		return err
+	})
+	return doc, err
+}
+
+// Runs lists the stored runs, oldest first. With limit > 0 only the latest
+// limit runs are returned.
+func (s *Store) Runs(limit int) ([]RunInfo, error) {
+	var runs []RunInfo
+	err := s.walk(func(doc *output.Document) bool {
+		runs = append(runs, RunInfo{
+			ID:          doc.RunID,
+			StartedAt:   doc.StartedAt,
+			FinishedAt:  doc.FinishedAt,
+			StateSource: doc.StateSource,
+			Region:      doc.Region,
+			Summary:     doc.Summary,
+		})
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if limit > 0 && len(runs) > limit {
+		runs = runs[len(runs)-limit:]
+	}
+	return runs, nil
+}
+
+// walk calls fn with each run, oldest first, until fn returns false.
+func (s *Store) walk(fn func(doc *output.Document) bool) error {
+	return s.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(orderBucket).Cursor()
+		for _, runID := c.First(); runID != nil; _, runID = c.Next() {
+			doc, err := load(tx, string(runID))
+			if err != nil {
+				return err
+			}
+			if !fn(doc) {
+				return nil
+			}
+		}
+		return nil
+	})
+}
+
+func load(tx *bolt.Tx, runID string) (*output.Document, error) {
+	data := tx.Bucket(runsBucket).Get([]byte(runID))
+	if data == nil {
+		return nil, fmt.Errorf("%w: %s", ErrRunNotFound, runID)
+	}
+
+	var doc output.Document
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to decode run %s: %w", runID, err)
+	}
+	return &doc, nil
+}
+
+func resolve(tx *bolt.Tx, id string) (string, error) {
+	runs := tx.Bucket(runsBucket)
+	if id == "" {
+		return "", fmt.Errorf("%w: empty run ID", ErrRunNotFound)
+	}
+	if runs.Get([]byte(id)) != nil {
+		return id, nil
+	}
+
+	var match string
+	c := runs.Cursor()
+	for k, _ := c.Seek([]byte(id)); k != nil && strings.HasPrefix(string(k), id); k, _ = c.Next() {
+		if match != "" {
+			return "", fmt.Errorf("%w: %s", ErrAmbiguousRunID, id)
+		}
+		match = string(k)
+	}
+	if match == "" {
+		return "", fmt.Errorf("%w: %s", ErrRunNotFound, id)
+	}
+	return match, nil
+}
+
+func orderKey(doc *output.Document) []byte {
+	return []byte(doc.StartedAt.UTC().Format(orderKeyLayout) + "/" + doc.RunID)
+}
@@ -0,0 +1,229 @@
+package history
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"firefly-ec2-drift-detector/models"
+	"firefly-ec2-drift-detector/output"
+)
+
+func openTestStore(t *testing.T) *Store {
+	t.Helper()
+
+	store, err := Open(filepath.Join(t.TempDir(), "history", "history.db"))
+	if err != nil {
+		t.Fatalf("failed to open store: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+// newDoc builds a run in which every resource checked InstanceType and Tags,
+// with drifts given as resource ID to drifted attributes.
+func newDoc(id string, day int, drifts map[string][]string) *output.Document {
+	doc := &output.Document{
+		SchemaVersion: output.SchemaVersion,
+		RunID:         id,
+		StartedAt:     time.Date(2024, 1, day, 0, 0, 0, 0, time.UTC),
+		FinishedAt:    time.Date(2024, 1, day, 0, 1, 0, 0, time.UTC),
+		StateSource:   "terraform.tfstate",
+	}
+
+	for _, resourceID := range []string{"i-1", "i-2", "i-3"} {
+		attrs, ok := drifts[resourceID]
+		if !ok {
+			continue
+		}
+		resource := output.DocumentResource{
+			ResourceType:      "aws_instance",
+			ResourceID:        resourceID,
+			HasDrift:          len(attrs) > 0,
+			CheckedAttributes: []string{"InstanceType", "Tags"},
+			Drifts:            []output.DocumentDrift{},
+		}
+		for _, attr := range attrs {
+			resource.Drifts = append(resource.Drifts, output.DocumentDrift{
+				Attribute: attr,
+				DriftType: "VALUE_MISMATCH",
+				Expected:  "t3.micro",
+				Actual:    "t3.large",
+			})
+		}
+		doc.Resources = append(doc.Resources, resource)
+		doc.Summary.ResourcesChecked++
+	}
+	return doc
+}
+
+func TestStore_SaveLoadAndRuns(t *testing.T) {
+	store := openTestStore(t)
+
+	// Saved out of order; Runs lists them by start time.
+	for _, doc := range []*output.Document{
+		newDoc("bbbb-2", 2, map[string][]string{"i-1": nil}),
+		newDoc("aaaa-1", 1, map[string][]string{"i-1": {"InstanceType"}}),
+		newDoc("cccc-3", 3, map[string][]string{"i-1": nil}),
+	} {
+		if err := store.Save(doc); err != nil {
+			t.Fatalf("failed to save %s: %v", doc.RunID, err)
+		}
+	}
+
+	runs, err := store.Runs(0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(runs) != 3 || runs[0].ID != "aaaa-1" || runs[2].ID != "cccc-3" {
+		t.Fatalf("expected runs oldest first, got %+v", runs)
+	}
+
+	runs, _ = store.Runs(2)
+	if len(runs) != 2 || runs[0].ID != "bbbb-2" {
+		t.Errorf("expected the latest 2 runs, got %+v", runs)
+	}
+
+	doc, err := store.Load("aaaa")
+	if err != nil {
+		t.Fatalf("failed to load by prefix: %v", err)
+	}
+	if doc.Resources[0].Drifts[0].Attribute != "InstanceType" {
+		t.Errorf("unexpected run: %+v", doc)
+	}
+
+	// Saving a run again replaces it rather than listing it twice.
+	if err := store.Save(newDoc("aaaa-1", 4, map[string][]string{"i-1": nil})); err != nil {
+		t.Fatalf("failed to save: %v", err)
+	}
+	runs, _ = store.Runs(0)
+	if len(runs) != 3 || runs[2].ID != "aaaa-1" {
+		t.Errorf("expected the replaced run to move, got %+v", runs)
+	}
+}
+
+func TestStore_LoadErrors(t *testing.T) {
+	store := openTestStore(t)
+	store.Save(newDoc("abc-1", 1, nil))
+	store.Save(newDoc("abc-2", 2, nil))
+
+	if _, err := store.Load("abc"); !errors.Is(err, ErrAmbiguousRunID) {
+		t.Errorf("expected ErrAmbiguousRunID, got %v", err)
+	}
+	if _, err := store.Load("xyz"); !errors.Is(err, ErrRunNotFound) {
+		t.Errorf("expected ErrRunNotFound, got %v", err)
+	}
+	if err := store.Save(&output.Document{}); err == nil {
+		t.Error("expected an error for a run without an ID")
+	}
+}
+
+func TestStore_Diff(t *testing.T) {
+	store := openTestStore(t)
+
+	runs := []*output.Document{
+		newDoc("run-1", 1, map[string][]string{"i-1": {"InstanceType"}, "i-2": {"Tags.Owner"}, "i-3": nil}),
+		// i-1 not checked: its drift is still considered first seen in run-1.
+		newDoc("run-2", 2, map[string][]string{"i-2": {"Tags.Owner"}, "i-3": nil}),
+		// i-2 resolved, then drifts again in run-4.
+		newDoc("run-3", 3, map[string][]string{"i-1": {"InstanceType"}, "i-2": nil, "i-3": nil}),
+		newDoc("run-4", 4, map[string][]string{"i-1": {"InstanceType"}, "i-2": {"Tags.Owner"}, "i-3": {"InstanceType"}}),
+		newDoc("run-5", 5, map[string][]string{"i-1": {"InstanceType"}, "i-2": {"Tags.Owner"}}),
+	}
+	for _, doc := range runs {
+		if err := store.Save(doc); err != nil {
+			t.Fatalf("failed to save %s: %v", doc.RunID, err)
+		}
+	}
+
+	diff, err := store.Diff("run-3", "run-5")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(diff.Persistent) != 1 || diff.Persistent[0].ResourceID != "i-1" {
+		t.Fatalf("expected i-1 to be persistent, got %+v", diff.Persistent)
+	}
+	if got := diff.Persistent[0]; got.FirstSeenRun != "run-1" || !got.FirstSeen.Equal(runs[0].StartedAt) {
+		t.Errorf("expected i-1 first seen in run-1, got %s at %s", got.FirstSeenRun, got.FirstSeen)
+	}
+
+	if len(diff.New) != 1 || diff.New[0].ResourceID != "i-2" || diff.New[0].FirstSeenRun != "run-4" {
+		t.Errorf("expected i-2 to be new, first seen in run-4, got %+v", diff.New)
+	}
+	if len(diff.Resolved) != 0 {
+		t.Errorf("expected nothing resolved, got %+v", diff.Resolved)
+	}
+
+	diff, err = store.Diff("run-4", "run-5")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(diff.Unchecked) != 1 || diff.Unchecked[0].ResourceID != "i-3" {
+		t.Errorf("expected i-3 not to be checked in run-5, got %+v", diff.Unchecked)
+	}
+}
+
+func TestDiff_Resolved(t *testing.T) {
+	from := newDoc("a", 1, map[string][]string{"i-1": {"InstanceType", "Tags.Owner"}})
+	to := newDoc("b", 2, map[string][]string{"i-1": {"InstanceType"}})
+	to.Resources[0].CheckedAttributes = []string{"InstanceType", "Tags"}
+
+	diff := Diff(from, to)
+	if len(diff.Resolved) != 1 || diff.Resolved[0].Attribute != "Tags.Owner" {
+		t.Errorf("expected Tags.Owner to be resolved, got %+v", diff.Resolved)
+	}
+
+	// Tags not compared in the later run: the drift may still be there.
+	to.Resources[0].CheckedAttributes = []string{"InstanceType"}
+	diff = Diff(from, to)
+	if len(diff.Resolved) != 0 || len(diff.Unchecked) != 1 {
+		t.Errorf("expected Tags.Owner to be unchecked, got %+v", diff)
+	}
+}
+
+func TestDiff_Changed(t *testing.T) {
+	from := newDoc("a", 1, map[string][]string{"i-1": {"InstanceType", "Tags.Owner"}})
+	to := newDoc("b", 2, map[string][]string{"i-1": {"InstanceType", "Tags.Owner"}})
+	to.Resources[0].Drifts[0].Actual = "t3.xlarge"
+
+	diff := Diff(from, to)
+	if len(diff.Persistent) != 2 {
+		t.Errorf("expected both drifts to be persistent, got %+v", diff.Persistent)
+	}
+	if len(diff.Changed) != 1 || diff.Changed[0].Attribute != "InstanceType" || diff.Changed[0].Actual != "t3.xlarge" {
+		t.Errorf("expected InstanceType to have changed, got %+v", diff.Changed)
+	}
+}
+
+func TestDiff_AutoScalingGroupResolved(t *testing.T) {
+	group := func(maxSize int, memberVersion string) *models.AutoScalingGroupState {
+		return &models.AutoScalingGroupState{
+			Name:           "web-asg",
+			MaxSize:        maxSize,
+			LaunchTemplate: models.LaunchTemplateSpec{ID: "lt-1", Version: "$Latest"},
+			Template:       &models.LaunchTemplateState{ID: "lt-1", LatestVersion: 2},
+			Instances: []models.AutoScalingInstance{
+				{InstanceID: "i-1", LaunchTemplate: models.LaunchTemplateSpec{ID: "lt-1", Version: memberVersion}},
+			},
+		}
+	}
+	document := func(id string, day int, actual *models.AutoScalingGroupState) *output.Document {
+		started := time.Date(2024, 1, day, 0, 0, 0, 0, time.UTC)
+		return output.NewDocument(&output.Run{
+			ID:       id,
+			Reports:  []*models.DriftReport{models.CompareAutoScalingGroup(group(3, "2"), actual)},
+			Started:  started,
+			Finished: started.Add(time.Minute),
+		})
+	}
+
+	from := document("a", 1, group(5, "1"))
+	to := document("b", 2, group(3, "2"))
+
+	diff := Diff(from, to)
+	if len(diff.Resolved) != 2 || len(diff.Unchecked) != 0 {
+		t.Errorf("expected MaxSize and the member version to be resolved, got %+v", diff)
+	}
+}
@@ -0,0 +1,229 @@
+// Package metrics exports Prometheus metrics about drift found by scans and
+// the AWS API calls they make.
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.uber.org/zap"
+
+	"firefly-ec2-drift-detector/aws"
+	flog "firefly-ec2-drift-detector/logger"
+	"firefly-ec2-drift-detector/models"
+	"firefly-ec2-drift-detector/output"
+)
+
+const namespace = "firefly"
+
+// Metrics holds the metrics of one process. It implements aws.APIObserver.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	apiCalls     *prometheus.CounterVec
+	apiRetries   *prometheus.CounterVec
+	apiThrottles *prometheus.CounterVec
+	apiDuration  *prometheus.HistogramVec
+
+	scans         prometheus.Counter
+	scanFailures  prometheus.Counter
+	scanDuration  prometheus.Histogram
+	lastScan      prometheus.Gauge
+	resources     *prometheus.GaugeVec
+	driftedByAttr *prometheus.GaugeVec
+}
+
+var _ aws.APIObserver = (*Metrics)(nil)
+
+// New returns metrics registered with a registry of their own.
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+
+		apiCalls: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "aws_api_calls_total",
+			Help:      "AWS API calls, by operation.",
+		}, []string{"operation"}),
+		apiRetries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "aws_api_retries_total",
+			Help:      "AWS API calls retried after an error, by operation.",
+		}, []string{"operation"}),
+		apiThrottles: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "aws_api_throttles_total",
+			Help:      "AWS API calls rejected for exceeding the rate limit, by operation.",
+		}, []string{"operation"}),
+		apiDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "aws_api_call_duration_seconds",
+			Help:      "Latency of AWS API calls, by operation.",
+			Buckets:   prometheus.ExponentialBuckets(0.05, 2, 10),
+		}, []string{"operation"}),
+
+		scans: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "scans_total",
+			Help:      "Drift scans completed, including those with resources not checked.",
+		}),
+		scanFailures: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "scan_failures_total",
+			Help:      "Drift scans that failed without checking any resource.",
+		}),
+		scanDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "scan_duration_seconds",
+			Help:      "Duration of completed drift scans.",
+			Buckets:   prometheus.ExponentialBuckets(1, 2, 12),
+		}),
+		lastScan: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "last_scan_timestamp_seconds",
+			Help:      "Time the latest completed scan finished, as a Unix timestamp.",
+		}),
+		resources: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "resources",
+			Help:      "Resources in the latest completed scan, by type and status: checked, drifted or not_checked.",
+		}, []string{"resource_type", "status"}),
+		driftedByAttr: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "drifted_resources",
+			Help:      "Resources with drift in the latest completed scan, by type, drifted top-level attribute and drift severity.",
+		}, []string{"resource_type", "attribute", "severity"}),
+	}
+
+	m.registry.MustRegister(
+		m.apiCalls, m.apiRetries, m.apiThrottles, m.apiDuration,
+		m.scans, m.scanFailures, m.scanDuration, m.lastScan, m.resources, m.driftedByAttr,
+	)
+	return m
+}
+
+// APICall counts a call, its latency and, when AWS throttled it, the
+// throttle.
+func (m *Metrics) APICall(operation string, duration time.Duration, err error) {
+	m.apiCalls.WithLabelValues(operation).Inc()
+	m.apiDuration.WithLabelValues(operation).Observe(duration.Seconds())
+	if aws.IsThrottlingError(err) {
+		m.apiThrottles.WithLabelValues(operation).Inc()
+	}
+}
+
+// APIRetry counts a retry.
+func (m *Metrics) APIRetry(operation string, attempt int, err error) {
+	m.apiRetries.WithLabelValues(operation).Inc()
+}
+
+// ObserveScan records a completed scan. The resource and drift gauges are
+// replaced by its results.
+func (m *Metrics) ObserveScan(run *output.Run) {
+	m.scans.Inc()
+	m.scanDuration.Observe(run.Finished.Sub(run.Started).Seconds())
+	m.lastScan.Set(float64(run.Finished.Unix()))
+
+	m.resources.Reset()
+	m.driftedByAttr.Reset()
+
+	for _, report := range run.Reports {
+		resourceType := report.ResourceType
+		if resourceType == "" {
+			resourceType = models.ResourceTypeInstance
+		}
+		m.resources.WithLabelValues(resourceType, "checked").Inc()
+		// Created for every type checked, so no drift reads as 0.
+		drifted := m.resources.WithLabelValues(resourceType, "drifted")
+		if !report.HasDrift {
+			continue
+		}
+		drifted.Inc()
+
+		// A resource counts once per attribute and severity, however many
+		// of its drifts share them.
+		seen := make(map[[2]string]bool)
+		for _, drift := range report.Drifts {
+			severity := string(drift.Severity)
+			if severity == "" {
+				severity = "none"
+			}
+			attribute := rootAttribute(drift.AttributeName)
+			key := [2]string{attribute, severity}
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			m.driftedByAttr.WithLabelValues(resourceType, attribute, severity).Inc()
+		}
+	}
+
+	for _, failure := range run.Failures {
+		m.resources.WithLabelValues(failure.ResourceType, "not_checked").Inc()
+	}
+}
+
+// rootAttribute returns the top-level attribute of a drift, such as Tags for
+// Tags.Owner or Instances for Instances["i-1"].LaunchTemplate, so that tag
+// keys and Auto Scaling group members do not each add series.
+func rootAttribute(name string) string {
+	if path, err := models.ParseAttributePath(name); err == nil {
+		return path.Root()
+	}
+	if i := strings.IndexAny(name, ".["); i > 0 {
+		return name[:i]
+	}
+	return name
+}
+
+// ScanFailed counts a scan that checked nothing.
+func (m *Metrics) ScanFailed() {
+	m.scanFailures.Inc()
+}
+
+// Handler serves the metrics in the Prometheus exposition format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// WriteTextfile writes the metrics for the node exporter's textfile
+// collector. The file is replaced atomically.
+func (m *Metrics) WriteTextfile(path string) error {
+	if err := prometheus.WriteToTextfile(path, m.registry); err != nil {
+		return fmt.Errorf("failed to write metrics file: %w", err)
+	}
+	return nil
+}
+
+// Serve listens on addr and serves the metrics at /metrics in the
+// background until ctx is cancelled. An error serving them is logged to
+// logger.
+func (m *Metrics) Serve(ctx context.Context, addr string, logger *flog.Logger) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen for metrics: %w", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", m.Handler())
+	srv := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	go func() {
+		<-ctx.Done()
+		srv.Close()
+	}()
+	go func() {
+		if err := srv.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Error("metrics server failed", zap.String("addr", addr), zap.Error(err))
+		}
+	}()
+
+	return nil
+}
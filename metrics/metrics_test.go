@@ -0,0 +1,149 @@
+package metrics
+
+import (
+	"errors"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"firefly-ec2-drift-detector/models"
+	"firefly-ec2-drift-detector/output"
+	"firefly-ec2-drift-detector/service"
+)
+
+func newTestRun() *output.Run {
+	drifted := &models.DriftReport{InstanceID: "i-1", ResourceType: models.ResourceTypeInstance}
+	drifted.AddAttributeDrift(models.AttributeDrift{AttributeName: "InstanceType", Severity: models.SeverityHigh})
+	drifted.AddAttributeDrift(models.AttributeDrift{AttributeName: "Tags.Owner", Severity: models.SeverityLow})
+
+	drifted.AddAttributeDrift(models.AttributeDrift{AttributeName: "Tags.Team", Severity: models.SeverityLow})
+
+	other := &models.DriftReport{InstanceID: "i-2", ResourceType: models.ResourceTypeInstance}
+	other.AddAttributeDrift(models.AttributeDrift{AttributeName: "InstanceType", Severity: models.SeverityHigh})
+
+	started := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	return &output.Run{
+		Reports: []*models.DriftReport{
+			drifted,
+			other,
+			{InstanceID: "i-3", ResourceType: models.ResourceTypeInstance},
+		},
+		Failures: []*service.ResourceError{
+			{ResourceType: models.ResourceTypeInstance, ResourceID: "i-4", Err: errors.New("boom")},
+		},
+		Started:  started,
+		Finished: started.Add(90 * time.Second),
+	}
+}
+
+func TestMetrics_ObserveScan(t *testing.T) {
+	m := New()
+	m.ObserveScan(newTestRun())
+
+	for _, tc := range []struct {
+		labels []string
+		want   float64
+	}{
+		{[]string{"aws_instance", "InstanceType", "high"}, 2},
+		{[]string{"aws_instance", "Tags", "low"}, 1},
+	} {
+		if got := testutil.ToFloat64(m.driftedByAttr.WithLabelValues(tc.labels...)); got != tc.want {
+			t.Errorf("drifted_resources%v = %v, want %v", tc.labels, got, tc.want)
+		}
+	}
+	for status, want := range map[string]float64{"checked": 3, "drifted": 2, "not_checked": 1} {
+		if got := testutil.ToFloat64(m.resources.WithLabelValues("aws_instance", status)); got != want {
+			t.Errorf("resources{status=%q} = %v, want %v", status, got, want)
+		}
+	}
+
+	// A later scan replaces the drift gauges.
+	run := newTestRun()
+	run.Reports = run.Reports[2:]
+	m.ObserveScan(run)
+	if n := testutil.CollectAndCount(m.driftedByAttr); n != 0 {
+		t.Errorf("expected no drifted_resources series, got %d", n)
+	}
+	if got := testutil.ToFloat64(m.resources.WithLabelValues("aws_instance", "drifted")); got != 0 {
+		t.Errorf("expected no drifted resources, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.scans); got != 2 {
+		t.Errorf("expected 2 scans, got %v", got)
+	}
+}
+
+func TestMetrics_ObserveScanLabelsRootAttributes(t *testing.T) {
+	group := &models.DriftReport{InstanceID: "web-asg", ResourceType: models.ResourceTypeAutoScalingGroup}
+	for _, member := range []string{"i-1", "i-2", "i-3"} {
+		group.AddAttributeDrift(models.AttributeDrift{AttributeName: models.MemberAttribute(member, "LaunchTemplate.Version"), Severity: models.SeverityMedium})
+	}
+	group.AddAttributeDrift(models.AttributeDrift{AttributeName: "LaunchTemplate.Version", Severity: models.SeverityMedium})
+
+	m := New()
+	m.ObserveScan(&output.Run{Reports: []*models.DriftReport{group}})
+
+	if n := testutil.CollectAndCount(m.driftedByAttr); n != 2 {
+		t.Errorf("expected 2 drifted_resources series, got %d", n)
+	}
+	for _, attribute := range []string{"Instances", "LaunchTemplate"} {
+		if got := testutil.ToFloat64(m.driftedByAttr.WithLabelValues(models.ResourceTypeAutoScalingGroup, attribute, "medium")); got != 1 {
+			t.Errorf("drifted_resources{attribute=%q} = %v, want 1", attribute, got)
+		}
+	}
+}
+
+func TestMetrics_APICalls(t *testing.T) {
+	m := New()
+	m.APICall("DescribeInstances", 200*time.Millisecond, errors.New("api error RequestLimitExceeded: Request limit exceeded"))
+	m.APIRetry("DescribeInstances", 1, errors.New("api error RequestLimitExceeded"))
+	m.APICall("DescribeInstances", 100*time.Millisecond, nil)
+
+	if got := testutil.ToFloat64(m.apiCalls.WithLabelValues("DescribeInstances")); got != 2 {
+		t.Errorf("expected 2 calls, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.apiThrottles.WithLabelValues("DescribeInstances")); got != 1 {
+		t.Errorf("expected 1 throttle, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.apiRetries.WithLabelValues("DescribeInstances")); got != 1 {
+		t.Errorf("expected 1 retry, got %v", got)
+	}
+
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if body := rec.Body.String(); !strings.Contains(body, `firefly_aws_api_call_duration_seconds_count{operation="DescribeInstances"} 2`) {
+		t.Errorf("expected the latency histogram to be served, got:\n%s", body)
+	}
+}
+
+func TestMetrics_WriteTextfile(t *testing.T) {
+	m := New()
+	m.ObserveScan(newTestRun())
+
+	path := filepath.Join(t.TempDir(), "firefly.prom")
+	if err := m.WriteTextfile(path); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`firefly_drifted_resources{attribute="InstanceType",resource_type="aws_instance",severity="high"} 2`,
+		`firefly_scan_duration_seconds_sum 90`,
+		`firefly_last_scan_timestamp_seconds 1.704164735e+09`,
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("expected %q in the textfile, got:\n%s", want, data)
+		}
+	}
+
+	if err := m.WriteTextfile(filepath.Join(t.TempDir(), "missing", "firefly.prom")); err == nil {
+		t.Error("expected an error for a missing directory")
+	}
+}
@@ -0,0 +1,287 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	AttributeKindString    AttributeKind = "string"
+	AttributeKindInt       AttributeKind = "int"
+	AttributeKindBool      AttributeKind = "bool"
+	AttributeKindStringSet AttributeKind = "set(string)"
+	AttributeKindStringMap AttributeKind = "map(string)"
+	AttributeKindObject    AttributeKind = "object"
+
+	// AllAttributes expands to every top-level attribute in a registry.
+	AllAttributes = "all"
+)
+
+type (
+	AttributeKind string
+
+	// Attribute describes a single comparable attribute: its canonical
+	// (Go-style) name, its Terraform name, its type and how to read it.
+	Attribute struct {
+		Name          string
+		TerraformName string
+		Kind          AttributeKind
+		Description   string
+		Children      []*Attribute
+
+		get func(state interface{}) interface{}
+	}
+
+	// AttributeRegistry indexes attributes by both naming schemes.
+	AttributeRegistry struct {
+		attrs  []*Attribute
+		byName map[string]*Attribute
+	}
+
+	// AttributeRef is a validated attribute path bound to a registry entry.
+	AttributeRef struct {
+		Path      string
+		Field     string // Path without map keys or list indexes: Tags for Tags.Owner
+		Attribute *Attribute
+		Kind      AttributeKind
+		selectors []pathSegment
+	}
+)
+
+// InstanceAttributes is the registry of attributes that can be compared on an
+// aws_instance.
+var InstanceAttributes = NewAttributeRegistry(
+	instanceAttr("InstanceType", "instance_type", AttributeKindString, "Instance size (t3.micro, t3.medium, ...)",
+		func(s *InstanceState) interface{} { return s.InstanceType }),
+	instanceAttr("AvailabilityZone", "availability_zone", AttributeKindString, "Availability zone placement",
+		func(s *InstanceState) interface{} { return s.AvailabilityZone }),
+	instanceAttr("SecurityGroups", "vpc_security_group_ids", AttributeKindStringSet, "Attached security group IDs (order independent)",
+		func(s *InstanceState) interface{} { return s.SecurityGroups }),
+	instanceAttr("Tags", "tags", AttributeKindStringMap, "Instance tags, including provider default_tags",
+		func(s *InstanceState) interface{} { return s.EffectiveTags() }),
+	instanceAttr("SubnetID", "subnet_id", AttributeKindString, "Subnet ID",
+		func(s *InstanceState) interface{} { return s.SubnetID }),
+	instanceAttr("ImageID", "ami", AttributeKindString, "AMI ID",
+		func(s *InstanceState) interface{} { return s.ImageID }),
+	instanceAttr("KeyName", "key_name", AttributeKindString, "SSH key pair name",
+		func(s *InstanceState) interface{} { return s.KeyName }),
+	instanceAttr("Monitoring", "monitoring", AttributeKindBool, "Detailed monitoring enabled",
+		func(s *InstanceState) interface{} { return s.Monitoring }),
+	instanceAttr("RootBlockDevice", "root_block_device", AttributeKindObject, "Root EBS volume",
+		func(s *InstanceState) interface{} { return s.RootBlockDevice },
+		instanceAttr("DeviceName", "device_name", AttributeKindString, "Root device name",
+			func(s *InstanceState) interface{} { return s.RootBlockDevice.DeviceName }),
+		instanceAttr("VolumeSize", "volume_size", AttributeKindInt, "Root volume size in GiB",
+			func(s *InstanceState) interface{} { return s.RootBlockDevice.VolumeSize }),
+		instanceAttr("VolumeType", "volume_type", AttributeKindString, "Root volume type",
+			func(s *InstanceState) interface{} { return s.RootBlockDevice.VolumeType }),
+		instanceAttr("Iops", "iops", AttributeKindInt, "Provisioned IOPS",
+			func(s *InstanceState) interface{} { return s.RootBlockDevice.Iops }),
+		instanceAttr("Throughput", "throughput", AttributeKindInt, "Provisioned throughput in MiB/s",
+			func(s *InstanceState) interface{} { return s.RootBlockDevice.Throughput }),
+		instanceAttr("Encrypted", "encrypted", AttributeKindBool, "Root volume encryption",
+			func(s *InstanceState) interface{} { return s.RootBlockDevice.Encrypted }),
+		instanceAttr("KmsKeyID", "kms_key_id", AttributeKindString, "KMS key used for encryption",
+			func(s *InstanceState) interface{} { return s.RootBlockDevice.KmsKeyID }),
+		instanceAttr("DeleteOnTermination", "delete_on_termination", AttributeKindBool, "Delete volume on instance termination",
+			func(s *InstanceState) interface{} { return s.RootBlockDevice.DeleteOnTermination }),
+	),
+	instanceAttr("MetadataOptions", "metadata_options", AttributeKindObject, "Instance metadata service options",
+		func(s *InstanceState) interface{} { return s.MetadataOptions },
+		instanceAttr("HttpEndpoint", "http_endpoint", AttributeKindString, "IMDS endpoint state",
+			func(s *InstanceState) interface{} { return s.MetadataOptions.HttpEndpoint }),
+		instanceAttr("HttpTokens", "http_tokens", AttributeKindString, "IMDSv2 token requirement",
+			func(s *InstanceState) interface{} { return s.MetadataOptions.HttpTokens }),
+		instanceAttr("HttpPutResponseHopLimit", "http_put_response_hop_limit", AttributeKindInt, "IMDS PUT response hop limit",
+			func(s *InstanceState) interface{} { return s.MetadataOptions.HttpPutResponseHopLimit }),
+		instanceAttr("InstanceMetadataTags", "instance_metadata_tags", AttributeKindString, "Tags exposed through IMDS",
+			func(s *InstanceState) interface{} { return s.MetadataOptions.InstanceMetadataTags }),
+	),
+)
+
+func instanceAttr(name, tfName string, kind AttributeKind, description string, get func(*InstanceState) interface{}, children ...*Attribute) *Attribute {
+	return stateAttr(name, tfName, kind, description, get, children...)
+}
+
+// stateAttr builds an attribute read from a state of type S.
+func stateAttr[S any](name, tfName string, kind AttributeKind, description string, get func(*S) interface{}, children ...*Attribute) *Attribute {
+	return &Attribute{
+		Name:          name,
+		TerraformName: tfName,
+		Kind:          kind,
+		Description:   description,
+		Children:      children,
+		get: func(state interface{}) interface{} {
+			return get(state.(*S))
+		},
+	}
+}
+
+func NewAttributeRegistry(attrs ...*Attribute) *AttributeRegistry {
+	r := &AttributeRegistry{
+		attrs:  attrs,
+		byName: make(map[string]*Attribute, len(attrs)*2),
+	}
+
+	for _, attr := range attrs {
+		r.byName[attr.Name] = attr
+		r.byName[attr.TerraformName] = attr
+	}
+
+	return r
+}
+
+// Attributes returns the top-level attributes in registration order.
+func (r *AttributeRegistry) Attributes() []*Attribute {
+	return r.attrs
+}
+
+// Names returns the canonical names of all top-level attributes.
+func (r *AttributeRegistry) Names() []string {
+	names := make([]string, 0, len(r.attrs))
+	for _, attr := range r.attrs {
+		names = append(names, attr.Name)
+	}
+	return names
+}
+
+// Lookup validates an attribute path against the registry. Either naming
+// scheme may be used for each field segment ("ami", "ImageID",
+// "root_block_device.volume_size", "RootBlockDevice.VolumeSize").
+func (r *AttributeRegistry) Lookup(raw string) (*AttributeRef, error) {
+	path, err := ParseAttributePath(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	attr, ok := r.byName[path.Root()]
+	if !ok {
+		return nil, fmt.Errorf("unknown attribute %q", path.Root())
+	}
+
+	canonical := attr.Name
+	field := attr.Name
+	kind := attr.Kind
+	var selectors []pathSegment
+
+	for _, seg := range path.segments[1:] {
+		switch {
+		case kind == AttributeKindObject && !seg.isIndex:
+			child := attr.child(seg.name)
+			if child == nil {
+				return nil, fmt.Errorf("unknown attribute %q in path %q", canonical+"."+seg.name, raw)
+			}
+			attr = child
+			kind = child.Kind
+			canonical += "." + child.Name
+			field += "." + child.Name
+
+		case kind == AttributeKindStringMap && !seg.isIndex:
+			selectors = append(selectors, seg)
+			kind = AttributeKindString
+			if strings.ContainsAny(seg.name, ".[]") {
+				canonical += fmt.Sprintf("[%q]", seg.name)
+			} else {
+				canonical += "." + seg.name
+			}
+
+		case kind == AttributeKindStringSet && seg.isIndex:
+			selectors = append(selectors, seg)
+			kind = AttributeKindString
+			canonical += fmt.Sprintf("[%d]", seg.index)
+
+		default:
+			return nil, fmt.Errorf("cannot select %s of %s (%s) in path %q", seg.describe(), canonical, kind, raw)
+		}
+	}
+
+	return &AttributeRef{
+		Path:      canonical,
+		Field:     field,
+		Attribute: attr,
+		Kind:      kind,
+		selectors: selectors,
+	}, nil
+}
+
+// Resolve expands "all", validates every path and returns the canonical
+// attribute paths with duplicates removed.
+func (r *AttributeRegistry) Resolve(attrs []string) ([]string, error) {
+	var (
+		resolved []string
+		errs     []error
+		seen     = make(map[string]bool)
+	)
+
+	add := func(path string) {
+		if !seen[path] {
+			seen[path] = true
+			resolved = append(resolved, path)
+		}
+	}
+
+	for _, attr := range attrs {
+		if strings.EqualFold(attr, AllAttributes) {
+			for _, name := range r.Names() {
+				add(name)
+			}
+			continue
+		}
+
+		ref, err := r.Lookup(attr)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		add(ref.Path)
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	return resolved, nil
+}
+
+// Value reads the referenced value from a state. Missing map keys and out of
+// range indices resolve to nil so they surface as missing values in a report.
+func (ref *AttributeRef) Value(state interface{}) interface{} {
+	value := ref.Attribute.get(state)
+
+	for _, seg := range ref.selectors {
+		switch v := value.(type) {
+		case map[string]string:
+			val, ok := v[seg.name]
+			if !ok {
+				return nil
+			}
+			value = val
+
+		case []string:
+			if seg.index >= len(v) {
+				return nil
+			}
+			value = v[seg.index]
+
+		default:
+			return nil
+		}
+	}
+
+	return value
+}
+
+func (a *Attribute) child(name string) *Attribute {
+	for _, child := range a.Children {
+		if child.Name == name || child.TerraformName == name {
+			return child
+		}
+	}
+	return nil
+}
+
+// ResolveAttributes resolves attribute paths against InstanceAttributes.
+func ResolveAttributes(attrs []string) ([]string, error) {
+	return InstanceAttributes.Resolve(attrs)
+}
@@ -0,0 +1,133 @@
+package models
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAttributeRegistry_Lookup_BothNamingSchemes(t *testing.T) {
+	tests := []struct {
+		raw       string
+		canonical string
+		kind      AttributeKind
+	}{
+		{raw: "InstanceType", canonical: "InstanceType", kind: AttributeKindString},
+		{raw: "instance_type", canonical: "InstanceType", kind: AttributeKindString},
+		{raw: "ami", canonical: "ImageID", kind: AttributeKindString},
+		{raw: "vpc_security_group_ids", canonical: "SecurityGroups", kind: AttributeKindStringSet},
+		{raw: "vpc_security_group_ids[0]", canonical: "SecurityGroups[0]", kind: AttributeKindString},
+		{raw: "tags.Owner", canonical: "Tags.Owner", kind: AttributeKindString},
+		{raw: `Tags["app.kubernetes.io/name"]`, canonical: `Tags["app.kubernetes.io/name"]`, kind: AttributeKindString},
+		{raw: "root_block_device.volume_size", canonical: "RootBlockDevice.VolumeSize", kind: AttributeKindInt},
+		{raw: "MetadataOptions.http_tokens", canonical: "MetadataOptions.HttpTokens", kind: AttributeKindString},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			ref, err := InstanceAttributes.Lookup(tt.raw)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if ref.Path != tt.canonical {
+				t.Errorf("expected canonical path %q, got %q", tt.canonical, ref.Path)
+			}
+
+			if ref.Kind != tt.kind {
+				t.Errorf("expected kind %s, got %s", tt.kind, ref.Kind)
+			}
+		})
+	}
+}
+
+func TestAttributeRegistry_Lookup_Unknown(t *testing.T) {
+	tests := []string{
+		"DoesNotExist",
+		"InstanceID",
+		"MetadataOptions.Nope",
+		"InstanceType.Foo",
+		"Tags[0]",
+		"SecurityGroups.Name",
+		"Tags.Owner.Extra",
+		"Tags[",
+	}
+
+	for _, raw := range tests {
+		t.Run(raw, func(t *testing.T) {
+			if _, err := InstanceAttributes.Lookup(raw); err == nil {
+				t.Fatalf("expected error for %q", raw)
+			}
+		})
+	}
+}
+
+func TestAttributeRef_Value(t *testing.T) {
+	state := &InstanceState{
+		InstanceType:   "t3.micro",
+		SecurityGroups: []string{"sg-1", "sg-2"},
+		Tags:           map[string]string{"Owner": "team-a", "a.b": "dotted"},
+		RootBlockDevice: BlockDevice{
+			VolumeSize: 20,
+		},
+		MetadataOptions: MetadataOptions{
+			HttpTokens: "required",
+		},
+	}
+
+	tests := []struct {
+		path     string
+		expected interface{}
+	}{
+		{path: "InstanceType", expected: "t3.micro"},
+		{path: "Tags.Owner", expected: "team-a"},
+		{path: `Tags["a.b"]`, expected: "dotted"},
+		{path: "Tags.Missing", expected: nil},
+		{path: "SecurityGroups[1]", expected: "sg-2"},
+		{path: "SecurityGroups[5]", expected: nil},
+		{path: "RootBlockDevice.VolumeSize", expected: 20},
+		{path: "MetadataOptions.HttpTokens", expected: "required"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			ref, err := InstanceAttributes.Lookup(tt.path)
+			if err != nil {
+				t.Fatalf("unexpected lookup error: %v", err)
+			}
+
+			if got := ref.Value(state); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestAttributeRegistry_Resolve(t *testing.T) {
+	resolved, err := InstanceAttributes.Resolve([]string{"ami", "ImageID", "tags.Owner"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !reflect.DeepEqual(resolved, []string{"ImageID", "Tags.Owner"}) {
+		t.Errorf("unexpected resolved attributes: %v", resolved)
+	}
+
+	all, err := InstanceAttributes.Resolve([]string{"all"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !reflect.DeepEqual(all, InstanceAttributes.Names()) {
+		t.Errorf("expected all to expand to %v, got %v", InstanceAttributes.Names(), all)
+	}
+
+	_, err = InstanceAttributes.Resolve([]string{"InstanceType", "Bogus", "Tags["})
+	if err == nil {
+		t.Fatalf("expected error for invalid paths")
+	}
+
+	if !strings.Contains(err.Error(), "Bogus") || !strings.Contains(err.Error(), "Tags[") {
+		t.Errorf("expected error to mention every invalid path, got: %v", err)
+	}
+}
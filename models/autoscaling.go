@@ -0,0 +1,244 @@
+package models
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	// LatestVersion and DefaultVersion are the launch template version
+	// aliases accepted by aws_autoscaling_group.
+	LatestVersion  = "$Latest"
+	DefaultVersion = "$Default"
+)
+
+type (
+	// AutoScalingGroupState is an aws_autoscaling_group. Instances is only
+	// populated for live groups, since Terraform does not track members.
+	AutoScalingGroupState struct {
+		Name            string
+		MinSize         int
+		MaxSize         int
+		DesiredCapacity int
+		LaunchTemplate  LaunchTemplateSpec
+		Template        *LaunchTemplateState // nil when the template is not known
+		Instances       []AutoScalingInstance
+		Source          *SourceLocation // nil unless parsed from HCL
+		Address         string          // Terraform address
+	}
+
+	// LaunchTemplateSpec references a launch template version. Version may be
+	// a number, $Latest or $Default.
+	LaunchTemplateSpec struct {
+		ID      string
+		Name    string
+		Version string
+	}
+
+	// AutoScalingInstance is a member of a live group. State is nil when the
+	// instance could not be described.
+	AutoScalingInstance struct {
+		InstanceID     string
+		LifecycleState string
+		LaunchTemplate LaunchTemplateSpec
+		State          *InstanceState
+	}
+
+	// LaunchTemplateState is an aws_launch_template. Live templates only
+	// carry the identifiers and version numbers.
+	LaunchTemplateState struct {
+		ID              string
+		Name            string
+		LatestVersion   int
+		DefaultVersion  int
+		ImageID         string
+		InstanceType    string
+		KeyName         string
+		SecurityGroups  []string
+		Monitoring      *bool // nil when the template does not set monitoring
+		MetadataOptions MetadataOptions
+	}
+)
+
+// ResourceID implements Resource.
+func (g *AutoScalingGroupState) ResourceID() string {
+	return g.Name
+}
+
+// SourceLocation implements Declared.
+func (g *AutoScalingGroupState) SourceLocation() *SourceLocation {
+	return g.Source
+}
+
+// ResourceAddress implements Addressed.
+func (g *AutoScalingGroupState) ResourceAddress() string {
+	return g.Address
+}
+
+// ResolveVersion turns a version reference into a version number, or 0 when
+// it cannot be resolved. An empty version means $Default, as in AWS.
+// References to the template's latest_version or default_version attributes,
+// as written in configuration, resolve like the matching alias.
+func (t *LaunchTemplateState) ResolveVersion(version string) int {
+	if t == nil {
+		return 0
+	}
+
+	switch {
+	case version == LatestVersion, strings.HasSuffix(version, ".latest_version"):
+		return t.LatestVersion
+	case version == "", version == DefaultVersion, strings.HasSuffix(version, ".default_version"):
+		return t.DefaultVersion
+	}
+
+	n, err := strconv.Atoi(version)
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+// InstanceState returns the configuration the template gives an instance.
+func (t *LaunchTemplateState) InstanceState(instanceID string) *InstanceState {
+	state := &InstanceState{
+		InstanceID:      instanceID,
+		ImageID:         t.ImageID,
+		InstanceType:    t.InstanceType,
+		KeyName:         t.KeyName,
+		SecurityGroups:  t.SecurityGroups,
+		MetadataOptions: t.MetadataOptions,
+	}
+	if t.Monitoring != nil {
+		state.Monitoring = *t.Monitoring
+	}
+	return state
+}
+
+// InstanceAttributes lists the aws_instance attributes the template sets.
+// Anything it leaves unset is up to the group or AWS and is not compared.
+func (t *LaunchTemplateState) InstanceAttributes() []string {
+	var attrs []string
+
+	add := func(name string, set bool) {
+		if set {
+			attrs = append(attrs, name)
+		}
+	}
+
+	add("InstanceType", t.InstanceType != "")
+	add("ImageID", t.ImageID != "")
+	add("KeyName", t.KeyName != "")
+	add("SecurityGroups", len(t.SecurityGroups) > 0)
+	add("Monitoring", t.Monitoring != nil)
+	add("MetadataOptions.HttpEndpoint", t.MetadataOptions.HttpEndpoint != "")
+	add("MetadataOptions.HttpTokens", t.MetadataOptions.HttpTokens != "")
+	add("MetadataOptions.HttpPutResponseHopLimit", t.MetadataOptions.HttpPutResponseHopLimit != 0)
+	add("MetadataOptions.InstanceMetadataTags", t.MetadataOptions.InstanceMetadataTags != "")
+
+	return attrs
+}
+
+// MembersAttribute is the attribute that member drifts, named by
+// MemberAttribute, belong to.
+const MembersAttribute = "Instances"
+
+// MemberAttribute is the drift name used for an attribute of a group member.
+func MemberAttribute(instanceID, attr string) string {
+	return fmt.Sprintf("%s[%q].%s", MembersAttribute, instanceID, attr)
+}
+
+// CompareAutoScalingGroup compares the group's own settings and reports
+// members launched from a different template or an outdated version of it.
+// Members are listed in CheckedAttrs as Instances only when their versions
+// could be compared.
+// Member configuration is compared separately against the template, see
+// LaunchTemplateState.InstanceState.
+func CompareAutoScalingGroup(expected, actual *AutoScalingGroupState) *DriftReport {
+	report := &DriftReport{
+		InstanceID:   actual.ResourceID(),
+		ResourceType: ResourceTypeAutoScalingGroup,
+		Drifts:       []AttributeDrift{},
+		CheckedAttrs: []string{"MinSize", "MaxSize", "LaunchTemplate"},
+	}
+
+	if expected.MinSize != actual.MinSize {
+		report.AddDrift("MinSize", expected.MinSize, actual.MinSize, DriftTypeValueMismatch)
+	}
+	if expected.MaxSize != actual.MaxSize {
+		report.AddDrift("MaxSize", expected.MaxSize, actual.MaxSize, DriftTypeValueMismatch)
+	}
+
+	if !sameTemplate(expected.LaunchTemplate, actual.LaunchTemplate) {
+		report.AddDrift("LaunchTemplate", templateLabel(expected.LaunchTemplate), templateLabel(actual.LaunchTemplate), DriftTypeValueMismatch)
+		return report
+	}
+
+	// Configuration references such as aws_launch_template.web.latest_version
+	// cannot be compared as written, only once resolved.
+	if !strings.HasPrefix(expected.LaunchTemplate.Version, "hcl:") &&
+		normalizeVersion(expected.LaunchTemplate.Version) != normalizeVersion(actual.LaunchTemplate.Version) {
+		report.AddDrift("LaunchTemplate.Version", expected.LaunchTemplate.Version, actual.LaunchTemplate.Version, DriftTypeValueMismatch)
+	}
+
+	// Prefer the version numbers recorded in state: a version created outside
+	// Terraform is not the one the group is supposed to run.
+	source := expected.Template
+	if source == nil || source.LatestVersion == 0 {
+		source = actual.Template
+	}
+	want := source.ResolveVersion(expected.LaunchTemplate.Version)
+
+	if expected.Template != nil && actual.Template != nil &&
+		expected.Template.LatestVersion != 0 && expected.Template.LatestVersion != actual.Template.LatestVersion {
+		report.AddDriftWithDetails("LaunchTemplate.LatestVersion", expected.Template.LatestVersion, actual.Template.LatestVersion,
+			DriftTypeValueMismatch, "launch template has versions that are not in terraform state")
+	}
+
+	if want == 0 {
+		return report
+	}
+
+	report.CheckedAttrs = append(report.CheckedAttrs, MembersAttribute)
+	for _, member := range actual.Instances {
+		if !sameTemplate(actual.LaunchTemplate, member.LaunchTemplate) {
+			report.AddDrift(MemberAttribute(member.InstanceID, "LaunchTemplate"),
+				templateLabel(actual.LaunchTemplate), templateLabel(member.LaunchTemplate), DriftTypeValueMismatch)
+			continue
+		}
+
+		got := actual.Template.ResolveVersion(member.LaunchTemplate.Version)
+		if got != want {
+			report.AddDriftWithDetails(MemberAttribute(member.InstanceID, "LaunchTemplate.Version"), want, got,
+				DriftTypeValueMismatch, fmt.Sprintf("instance runs launch template version %d, group uses version %d", got, want))
+		}
+	}
+
+	return report
+}
+
+// sameTemplate matches on ID when both sides have a real one and falls back
+// to the name, which is all configuration may know.
+func sameTemplate(a, b LaunchTemplateSpec) bool {
+	if a.ID != "" && b.ID != "" && !strings.HasPrefix(a.ID, "hcl:") && !strings.HasPrefix(b.ID, "hcl:") {
+		return a.ID == b.ID
+	}
+	if a.Name != "" && b.Name != "" {
+		return a.Name == b.Name
+	}
+	return true
+}
+
+func templateLabel(spec LaunchTemplateSpec) string {
+	if spec.Name != "" {
+		return spec.Name
+	}
+	return spec.ID
+}
+
+func normalizeVersion(version string) string {
+	if version == "" {
+		return DefaultVersion
+	}
+	return version
+}
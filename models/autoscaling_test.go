@@ -0,0 +1,143 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLaunchTemplateState_ResolveVersion(t *testing.T) {
+	template := &LaunchTemplateState{LatestVersion: 5, DefaultVersion: 2}
+
+	tests := map[string]int{
+		"$Latest":                 5,
+		"$Default":                2,
+		"":                        2,
+		"3":                       3,
+		"hcl:web.latest_version":  5,
+		"hcl:web.default_version": 2,
+		"hcl:web":                 0,
+	}
+
+	for version, want := range tests {
+		if got := template.ResolveVersion(version); got != want {
+			t.Errorf("ResolveVersion(%q) = %d, want %d", version, got, want)
+		}
+	}
+
+	var missing *LaunchTemplateState
+	if got := missing.ResolveVersion("$Latest"); got != 0 {
+		t.Errorf("expected 0 for an unknown template, got %d", got)
+	}
+}
+
+func TestLaunchTemplateState_InstanceAttributes(t *testing.T) {
+	enabled := true
+	template := &LaunchTemplateState{
+		InstanceType:    "t3.micro",
+		ImageID:         "ami-1",
+		Monitoring:      &enabled,
+		MetadataOptions: MetadataOptions{HttpTokens: "required"},
+	}
+
+	got := template.InstanceAttributes()
+	want := []string{"InstanceType", "ImageID", "Monitoring", "MetadataOptions.HttpTokens"}
+	if len(got) != len(want) {
+		t.Fatalf("InstanceAttributes() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("InstanceAttributes()[%d] = %s, want %s", i, got[i], want[i])
+		}
+	}
+
+	if state := template.InstanceState("i-1"); !state.Monitoring || state.InstanceType != "t3.micro" {
+		t.Errorf("unexpected instance state: %+v", state)
+	}
+}
+
+func TestCompareAutoScalingGroup(t *testing.T) {
+	spec := func(version string) LaunchTemplateSpec {
+		return LaunchTemplateSpec{ID: "lt-1", Name: "web", Version: version}
+	}
+
+	expected := &AutoScalingGroupState{
+		Name:           "web-asg",
+		MinSize:        1,
+		MaxSize:        3,
+		LaunchTemplate: spec("$Latest"),
+		Template:       &LaunchTemplateState{ID: "lt-1", Name: "web", LatestVersion: 4, DefaultVersion: 1},
+	}
+	actual := &AutoScalingGroupState{
+		Name:           "web-asg",
+		MinSize:        1,
+		MaxSize:        5,
+		LaunchTemplate: spec("$Latest"),
+		Template:       &LaunchTemplateState{ID: "lt-1", Name: "web", LatestVersion: 4, DefaultVersion: 1},
+		Instances: []AutoScalingInstance{
+			{InstanceID: "i-current", LaunchTemplate: spec("4")},
+			{InstanceID: "i-stale", LaunchTemplate: spec("3")},
+			{InstanceID: "i-other", LaunchTemplate: LaunchTemplateSpec{ID: "lt-2", Name: "batch", Version: "1"}},
+		},
+	}
+
+	report := CompareAutoScalingGroup(expected, actual)
+
+	if report.ResourceType != ResourceTypeAutoScalingGroup || report.InstanceID != "web-asg" {
+		t.Errorf("unexpected report identity: %s %s", report.ResourceType, report.InstanceID)
+	}
+	if want := []string{"MinSize", "MaxSize", "LaunchTemplate", MembersAttribute}; !reflect.DeepEqual(report.CheckedAttrs, want) {
+		t.Errorf("expected checked attributes %v, got %v", want, report.CheckedAttrs)
+	}
+
+	drifts := make(map[string]AttributeDrift)
+	for _, drift := range report.Drifts {
+		drifts[drift.AttributeName] = drift
+	}
+
+	if len(drifts) != 3 {
+		t.Fatalf("expected 3 drifts, got %+v", report.Drifts)
+	}
+
+	if drifts["MaxSize"].ActualValue != 5 {
+		t.Errorf("expected MaxSize drift, got %+v", drifts["MaxSize"])
+	}
+
+	stale := drifts[`Instances["i-stale"].LaunchTemplate.Version`]
+	if stale.ExpectedValue != 4 || stale.ActualValue != 3 {
+		t.Errorf("expected i-stale to be reported on version 3, got %+v", stale)
+	}
+	if stale.Details != "instance runs launch template version 3, group uses version 4" {
+		t.Errorf("unexpected details: %q", stale.Details)
+	}
+
+	if other := drifts[`Instances["i-other"].LaunchTemplate`]; other.ActualValue != "batch" {
+		t.Errorf("expected i-other to be reported on another template, got %+v", other)
+	}
+}
+
+func TestCompareAutoScalingGroup_UnmanagedTemplateVersion(t *testing.T) {
+	expected := &AutoScalingGroupState{
+		Name:           "web-asg",
+		LaunchTemplate: LaunchTemplateSpec{ID: "lt-1", Version: "$Latest"},
+		Template:       &LaunchTemplateState{ID: "lt-1", LatestVersion: 2},
+	}
+	actual := &AutoScalingGroupState{
+		Name:           "web-asg",
+		LaunchTemplate: LaunchTemplateSpec{ID: "lt-1", Version: "$Latest"},
+		Template:       &LaunchTemplateState{ID: "lt-1", LatestVersion: 3},
+		Instances: []AutoScalingInstance{
+			{InstanceID: "i-1", LaunchTemplate: LaunchTemplateSpec{ID: "lt-1", Version: "3"}},
+		},
+	}
+
+	report := CompareAutoScalingGroup(expected, actual)
+
+	// The version created outside Terraform is reported, and so is the member
+	// running it, since state says the group should be on version 2.
+	if len(report.Drifts) != 2 {
+		t.Fatalf("expected 2 drifts, got %+v", report.Drifts)
+	}
+	if report.Drifts[0].AttributeName != "LaunchTemplate.LatestVersion" {
+		t.Errorf("expected LatestVersion drift first, got %s", report.Drifts[0].AttributeName)
+	}
+}
@@ -0,0 +1,258 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"time"
+)
+
+// baselineDateLayout is the layout of an expiry given as a date. The
+// suppression then lasts until the end of that day, UTC.
+const baselineDateLayout = "2006-01-02"
+
+type (
+	// BaselineEntry accepts one known drift. It matches a drift of the given
+	// resource and attribute and, when Expected or Actual are given, only
+	// while the drift still has those values. They are kept as written, so
+	// that a value left out matches anything while an explicit null only
+	// matches a missing value.
+	BaselineEntry struct {
+		ResourceType string          `json:"resource_type,omitempty"` // defaults to aws_instance
+		ResourceID   string          `json:"resource_id"`
+		Attribute    string          `json:"attribute"`
+		Expected     json.RawMessage `json:"expected,omitempty"`
+		Actual       json.RawMessage `json:"actual,omitempty"`
+		// Expires is a date such as "2024-06-30" or an RFC 3339 time. Once
+		// past, the drift is reported again.
+		Expires string `json:"expires,omitempty"`
+		Reason  string `json:"reason,omitempty"`
+
+		expiresAt time.Time
+	}
+
+	// Baseline is a list of accepted drifts, read from a baseline file.
+	Baseline struct {
+		Suppressions []BaselineEntry `json:"suppressions"`
+	}
+
+	// SuppressedDrift is a drift accepted by a Baseline. It is reported
+	// apart from the drifts of its report.
+	SuppressedDrift struct {
+		AttributeDrift
+		Reason  string     `json:",omitempty"`
+		Expires *time.Time `json:",omitempty"`
+	}
+)
+
+// LoadBaseline reads a JSON baseline file.
+func LoadBaseline(filename string) (*Baseline, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read baseline: %w", err)
+	}
+
+	var baseline Baseline
+	if err := json.Unmarshal(data, &baseline); err != nil {
+		return nil, fmt.Errorf("failed to parse baseline %s: %w", filename, err)
+	}
+
+	if err := baseline.validate(); err != nil {
+		return nil, fmt.Errorf("invalid baseline %s: %w", filename, err)
+	}
+
+	return &baseline, nil
+}
+
+// NewBaseline accepts every drift in reports. expires and reason, when set,
+// are recorded on each entry.
+func NewBaseline(reports []*DriftReport, expires, reason string) (*Baseline, error) {
+	baseline := &Baseline{Suppressions: []BaselineEntry{}}
+
+	for _, report := range reports {
+		resourceType := report.ResourceType
+		if resourceType == "" {
+			resourceType = ResourceTypeInstance
+		}
+
+		for _, drift := range report.Drifts {
+			// A nil value is written as null rather than left out, so the
+			// entry keeps matching only while the value is missing.
+			expected, err := json.Marshal(drift.ExpectedValue)
+			if err != nil {
+				return nil, fmt.Errorf("failed to encode %s of %s: %w", drift.AttributeName, report.InstanceID, err)
+			}
+			actual, err := json.Marshal(drift.ActualValue)
+			if err != nil {
+				return nil, fmt.Errorf("failed to encode %s of %s: %w", drift.AttributeName, report.InstanceID, err)
+			}
+
+			baseline.Suppressions = append(baseline.Suppressions, BaselineEntry{
+				ResourceType: resourceType,
+				ResourceID:   report.InstanceID,
+				Attribute:    drift.AttributeName,
+				Expected:     expected,
+				Actual:       actual,
+				Expires:      expires,
+				Reason:       reason,
+			})
+		}
+	}
+
+	if err := baseline.validate(); err != nil {
+		return nil, err
+	}
+	return baseline, nil
+}
+
+// Save writes the baseline as indented JSON.
+func (b *Baseline) Save(filename string) error {
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode baseline: %w", err)
+	}
+
+	if err := os.WriteFile(filename, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write baseline: %w", err)
+	}
+	return nil
+}
+
+func (b *Baseline) validate() error {
+	for i := range b.Suppressions {
+		entry := &b.Suppressions[i]
+		if entry.ResourceID == "" || entry.Attribute == "" {
+			return fmt.Errorf("suppression %d: resource_id and attribute are required", i+1)
+		}
+		if entry.ResourceType == "" {
+			entry.ResourceType = ResourceTypeInstance
+		}
+
+		if entry.Expected != nil && !json.Valid(entry.Expected) {
+			return fmt.Errorf("suppression %d: invalid expected value", i+1)
+		}
+		if entry.Actual != nil && !json.Valid(entry.Actual) {
+			return fmt.Errorf("suppression %d: invalid actual value", i+1)
+		}
+
+		if entry.Expires == "" {
+			continue
+		}
+		expiresAt, err := ParseBaselineExpiry(entry.Expires)
+		if err != nil {
+			return fmt.Errorf("suppression %d: %w", i+1, err)
+		}
+		entry.expiresAt = expiresAt
+	}
+	return nil
+}
+
+// ParseBaselineExpiry parses an expiry as a date, lasting until the end of
+// that day UTC, or as an RFC 3339 time.
+func ParseBaselineExpiry(s string) (time.Time, error) {
+	if date, err := time.Parse(baselineDateLayout, s); err == nil {
+		return date.AddDate(0, 0, 1), nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid expiry %q: use a date such as 2024-06-30 or an RFC 3339 time", s)
+	}
+	return t, nil
+}
+
+// Apply moves the drifts the baseline accepts at now from report.Drifts to
+// report.Suppressed, then updates HasDrift and Severity for the drifts left.
+// Drifts whose suppression has expired stay, with a note in Details.
+func (b *Baseline) Apply(report *DriftReport, now time.Time) {
+	if b == nil || len(report.Drifts) == 0 {
+		return
+	}
+
+	resourceType := report.ResourceType
+	if resourceType == "" {
+		resourceType = ResourceTypeInstance
+	}
+
+	kept := report.Drifts[:0]
+	for _, drift := range report.Drifts {
+		entry := b.match(resourceType, report.InstanceID, &drift)
+		switch {
+		case entry == nil:
+			kept = append(kept, drift)
+		case !entry.expiresAt.IsZero() && !now.Before(entry.expiresAt):
+			note := fmt.Sprintf("baseline suppression expired %s", entry.Expires)
+			if drift.Details != "" {
+				note = drift.Details + "; " + note
+			}
+			drift.Details = note
+			kept = append(kept, drift)
+		default:
+			suppressed := SuppressedDrift{AttributeDrift: drift, Reason: entry.Reason}
+			if !entry.expiresAt.IsZero() {
+				expiresAt := entry.expiresAt
+				suppressed.Expires = &expiresAt
+			}
+			report.Suppressed = append(report.Suppressed, suppressed)
+		}
+	}
+	report.Drifts = kept
+
+	report.HasDrift = len(report.Drifts) > 0
+	report.Severity = ""
+	for _, drift := range report.Drifts {
+		if drift.Severity != "" && (report.Severity == "" || !report.Severity.AtLeast(drift.Severity)) {
+			report.Severity = drift.Severity
+		}
+	}
+}
+
+// match returns the first entry accepting the drift. Expired entries are
+// returned too, so their drift can say so.
+func (b *Baseline) match(resourceType, resourceID string, drift *AttributeDrift) *BaselineEntry {
+	for i := range b.Suppressions {
+		entry := &b.Suppressions[i]
+		entryType := entry.ResourceType
+		if entryType == "" {
+			entryType = ResourceTypeInstance
+		}
+		if entryType != resourceType || entry.ResourceID != resourceID || entry.Attribute != drift.AttributeName {
+			continue
+		}
+		if !baselineValueMatches(entry.Expected, drift.ExpectedValue) ||
+			!baselineValueMatches(entry.Actual, drift.ActualValue) {
+			continue
+		}
+		return entry
+	}
+	return nil
+}
+
+// baselineValueMatches reports whether a value given in a baseline entry
+// matches v. A value left out matches anything; an explicit null matches
+// only a missing value.
+func baselineValueMatches(raw json.RawMessage, v interface{}) bool {
+	if raw == nil {
+		return true
+	}
+	var value interface{}
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return false
+	}
+	return reflect.DeepEqual(value, jsonValue(v))
+}
+
+// jsonValue returns v as it reads back from JSON, so values from a baseline
+// file compare equal to the live values they were written from.
+func jsonValue(v interface{}) interface{} {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil
+	}
+	return value
+}
@@ -0,0 +1,145 @@
+package models
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newBaselineTestReport() *DriftReport {
+	report := &DriftReport{InstanceID: "i-1", ResourceType: ResourceTypeInstance, CheckedAttrs: []string{"InstanceType", "MetadataOptions", "Tags"}}
+	report.AddAttributeDrift(AttributeDrift{AttributeName: "InstanceType", ExpectedValue: "t3.micro", ActualValue: "t3.large", DriftType: DriftTypeValueMismatch, Severity: SeverityMedium})
+	report.AddAttributeDrift(AttributeDrift{AttributeName: "MetadataOptions", ExpectedValue: MetadataOptions{HttpTokens: "required", HttpPutResponseHopLimit: 1}, ActualValue: MetadataOptions{HttpTokens: "optional", HttpPutResponseHopLimit: 1}, DriftType: DriftTypeValueMismatch, Severity: SeverityHigh})
+	report.AddAttributeDrift(AttributeDrift{AttributeName: "Tags.Owner", ExpectedValue: "alice", ActualValue: "bob", DriftType: DriftTypeValueMismatch, Severity: SeverityLow})
+	report.Severity = SeverityHigh
+	return report
+}
+
+func TestBaseline_RoundTripSuppressesEverything(t *testing.T) {
+	baseline, err := NewBaseline([]*DriftReport{newBaselineTestReport()}, "2024-06-30", "pending PR #42")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "drift-baseline.json")
+	if err := baseline.Save(path); err != nil {
+		t.Fatalf("failed to save: %v", err)
+	}
+	loaded, err := LoadBaseline(path)
+	if err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+
+	report := newBaselineTestReport()
+	loaded.Apply(report, time.Date(2024, 6, 30, 23, 0, 0, 0, time.UTC))
+
+	if report.HasDrift || len(report.Drifts) != 0 || report.Severity != "" {
+		t.Fatalf("expected every drift to be suppressed, got %+v", report)
+	}
+	if len(report.Suppressed) != 3 || report.Suppressed[0].Reason != "pending PR #42" {
+		t.Errorf("unexpected suppressed drifts: %+v", report.Suppressed)
+	}
+	if want := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC); report.Suppressed[0].Expires == nil || !report.Suppressed[0].Expires.Equal(want) {
+		t.Errorf("expected the suppression to last through 2024-06-30, got %v", report.Suppressed[0].Expires)
+	}
+}
+
+func TestBaseline_Apply(t *testing.T) {
+	baseline := &Baseline{Suppressions: []BaselineEntry{
+		// Matches any values.
+		{ResourceID: "i-1", Attribute: "Tags.Owner"},
+		// Values changed since the drift was accepted.
+		{ResourceID: "i-1", Attribute: "InstanceType", Expected: json.RawMessage(`"t3.micro"`), Actual: json.RawMessage(`"t3.medium"`)},
+		// Expired.
+		{ResourceID: "i-1", Attribute: "MetadataOptions", Expires: "2024-01-01T00:00:00Z"},
+	}}
+	if err := baseline.validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	report := newBaselineTestReport()
+	baseline.Apply(report, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC))
+
+	if len(report.Suppressed) != 1 || report.Suppressed[0].AttributeName != "Tags.Owner" {
+		t.Errorf("expected only Tags.Owner to be suppressed, got %+v", report.Suppressed)
+	}
+	if len(report.Drifts) != 2 || !report.HasDrift || report.Severity != SeverityHigh {
+		t.Fatalf("expected InstanceType and MetadataOptions to remain, got %+v", report)
+	}
+	if got := report.Drifts[1].Details; got != "baseline suppression expired 2024-01-01T00:00:00Z" {
+		t.Errorf("expected the expired suppression to be noted, got %q", got)
+	}
+}
+
+func TestBaseline_KeepsExplicitNull(t *testing.T) {
+	missing := func(expected interface{}) *DriftReport {
+		report := &DriftReport{InstanceID: "i-1", ResourceType: ResourceTypeInstance}
+		report.AddAttributeDrift(AttributeDrift{AttributeName: "Tags.Owner", ExpectedValue: expected, ActualValue: "bob", DriftType: DriftTypeMissingInTerraform})
+		return report
+	}
+
+	baseline, err := NewBaseline([]*DriftReport{missing(nil)}, "", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "drift-baseline.json")
+	if err := baseline.Save(path); err != nil {
+		t.Fatalf("failed to save: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"expected": null`) {
+		t.Errorf("expected the missing value to be saved as null:\n%s", data)
+	}
+
+	loaded, err := LoadBaseline(path)
+	if err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+
+	report := missing(nil)
+	loaded.Apply(report, time.Now())
+	if report.HasDrift {
+		t.Errorf("expected the drift to stay suppressed while the value is missing, got %+v", report.Drifts)
+	}
+
+	report = missing("alice")
+	loaded.Apply(report, time.Now())
+	if !report.HasDrift {
+		t.Error("expected an explicit null not to match a value that is now set")
+	}
+
+	wildcard := &Baseline{Suppressions: []BaselineEntry{{ResourceID: "i-1", Attribute: "Tags.Owner"}}}
+	if err := wildcard.validate(); err != nil {
+		t.Fatal(err)
+	}
+	report = missing("alice")
+	wildcard.Apply(report, time.Now())
+	if report.HasDrift {
+		t.Error("expected an entry without expected or actual to match any values")
+	}
+}
+
+func TestLoadBaseline_Invalid(t *testing.T) {
+	dir := t.TempDir()
+
+	for name, content := range map[string]string{
+		"missing-attribute.json": `{"suppressions": [{"resource_id": "i-1"}]}`,
+		"bad-expiry.json":        `{"suppressions": [{"resource_id": "i-1", "attribute": "Tags", "expires": "next week"}]}`,
+		"not-json.json":          `suppressions:`,
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadBaseline(path); err == nil {
+			t.Errorf("expected an error for %s", name)
+		}
+	}
+}
@@ -1,10 +1,15 @@
 package models
 
 import (
+	"context"
 	"fmt"
 	"reflect"
 	"sort"
 
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 
 	flog "firefly-ec2-drift-detector/logger"
@@ -17,6 +22,12 @@ const (
 	DriftTypeMissingInTerraform DriftType = "MISSING_IN_TERRAFORM"
 )
 
+const (
+	KeyChangeAdded   KeyChangeType = "ADDED"   // present in the instance only
+	KeyChangeRemoved KeyChangeType = "REMOVED" // present in terraform only
+	KeyChangeChanged KeyChangeType = "CHANGED" // present in both with different values
+)
+
 type (
 	InstanceState struct {
 		InstanceID       string            // "i-1234567890abcdef0"
@@ -24,10 +35,39 @@ type (
 		AvailabilityZone string            // "us-east-1a"
 		SecurityGroups   []string          // ["sg-12345", "sg-67890"]
 		Tags             map[string]string // {"Name": "web", "Env": "prod"}
+		TagsAll          map[string]string // Tags merged with provider default_tags; nil when unknown
 		SubnetID         string
 		ImageID          string
 		KeyName          string
 		Monitoring       bool
+		RootBlockDevice  BlockDevice
+		MetadataOptions  MetadataOptions
+		Source           *SourceLocation // nil unless parsed from HCL
+		Address          string          // Terraform address, such as module.app.aws_instance.web[0]
+
+		// Unset holds the object fields the configuration leaves out, such
+		// as "RootBlockDevice.Iops". It is nil for live instances, whose
+		// fields are all known.
+		Unset map[string]bool
+	}
+
+	BlockDevice struct {
+		DeviceName          string // "/dev/xvda"
+		VolumeID            string
+		VolumeSize          int    // GiB
+		VolumeType          string // "gp3"
+		Iops                int
+		Throughput          int
+		Encrypted           bool
+		KmsKeyID            string
+		DeleteOnTermination bool
+	}
+
+	MetadataOptions struct {
+		HttpEndpoint            string // "enabled"
+		HttpTokens              string // "required"
+		HttpPutResponseHopLimit int
+		InstanceMetadataTags    string
 	}
 
 	AttributeDrift struct {
@@ -36,18 +76,101 @@ type (
 		ActualValue   interface{}
 		DriftType     DriftType
 		Details       string
+		Changes       []KeyChange `json:",omitempty"` // per-key changes for map attributes such as Tags
+		Added         []string    `json:",omitempty"` // set members present in the instance only, sorted
+		Removed       []string    `json:",omitempty"` // set members present in terraform only, sorted
+		Severity      Severity    `json:",omitempty"` // assigned by a SeverityPolicy
 	}
 
 	DriftType string
 
+	// KeyChange is a single key-level difference inside a map attribute.
+	KeyChange struct {
+		Key      string
+		Expected interface{} `json:",omitempty"`
+		Actual   interface{} `json:",omitempty"`
+		Change   KeyChangeType
+	}
+
+	KeyChangeType string
+
+	// driftAnalysis is the outcome of analysing a pair of unequal values.
+	driftAnalysis struct {
+		driftType DriftType
+		details   string
+		changes   []KeyChange
+		added     []string
+		removed   []string
+	}
+
 	DriftReport struct {
 		InstanceID   string
+		ResourceType string `json:",omitempty"` // aws_instance, aws_security_group, ...
 		HasDrift     bool
 		Drifts       []AttributeDrift
 		CheckedAttrs []string
+		IgnoredTags  []string          `json:",omitempty"` // tag keys excluded by TagIgnoreRules
+		Severity     Severity          `json:",omitempty"` // highest severity among Drifts
+		Source       *SourceLocation   `json:",omitempty"` // declaring .tf block, when parsed from HCL
+		Address      string            `json:",omitempty"` // Terraform address of the resource
+		Suppressed   []SuppressedDrift `json:",omitempty"` // drifts accepted by a Baseline
 	}
 )
 
+// ResourceID implements Resource.
+func (s *InstanceState) ResourceID() string {
+	return s.InstanceID
+}
+
+// EffectiveTags returns the full tag set the instance is expected to carry,
+// including provider default_tags when they are known.
+func (s *InstanceState) EffectiveTags() map[string]string {
+	if s.TagsAll != nil {
+		return s.TagsAll
+	}
+	return s.Tags
+}
+
+// ResourceTags implements TaggedResource.
+func (s *InstanceState) ResourceTags() map[string]string {
+	return s.EffectiveTags()
+}
+
+// IsUnset implements PartiallySet.
+func (s *InstanceState) IsUnset(path string) bool {
+	return s.Unset[path]
+}
+
+// MarkUnset records the fields of the object attribute object, such as
+// RootBlockDevice, that set reports as absent. set is given each field's
+// Terraform name.
+func (s *InstanceState) MarkUnset(object string, set func(tfName string) bool) {
+	attr := InstanceAttributes.byName[object]
+	if attr == nil {
+		return
+	}
+
+	for _, child := range attr.Children {
+		if set(child.TerraformName) {
+			continue
+		}
+		if s.Unset == nil {
+			s.Unset = make(map[string]bool)
+		}
+		s.Unset[attr.Name+"."+child.Name] = true
+	}
+}
+
+// SourceLocation implements Declared.
+func (s *InstanceState) SourceLocation() *SourceLocation {
+	return s.Source
+}
+
+// ResourceAddress implements Addressed.
+func (s *InstanceState) ResourceAddress() string {
+	return s.Address
+}
+
 func (d *DriftReport) AddDrift(attr string, expected, actual interface{}, driftType DriftType) {
 	d.Drifts = append(d.Drifts, AttributeDrift{
 		AttributeName: attr,
@@ -69,6 +192,12 @@ func (d *DriftReport) AddDriftWithDetails(attr string, expected, actual interfac
 	d.HasDrift = true
 }
 
+// AddAttributeDrift records a fully populated drift entry.
+func (d *DriftReport) AddAttributeDrift(drift AttributeDrift) {
+	d.Drifts = append(d.Drifts, drift)
+	d.HasDrift = true
+}
+
 func (d *DriftReport) Summary() string {
 	if !d.HasDrift {
 		return fmt.Sprintf("Instance %s: No drift detected", d.InstanceID)
@@ -76,102 +205,185 @@ func (d *DriftReport) Summary() string {
 	return fmt.Sprintf("Instance %s: Drift detected in %d attribute(s)", d.InstanceID, len(d.Drifts))
 }
 
+var tracer = otel.Tracer("firefly-ec2-drift-detector/models")
+
 type DriftDetector interface {
-	CompareAttributes(expected, actual *InstanceState, attrs []string) *DriftReport
+	CompareAttributes(ctx context.Context, expected, actual *InstanceState, attrs []string) (*DriftReport, error)
+}
+
+// ResourceComparator compares resources of any type against the attribute
+// registry for that type.
+type ResourceComparator interface {
+	CompareResource(resourceType string, registry *AttributeRegistry, expected, actual Resource, attrs []string) (*DriftReport, error)
 }
 
 type AttributeComparator struct {
-	logger *flog.Logger
+	logger      *flog.Logger
+	registry    *AttributeRegistry
+	tagIgnores  *TagIgnoreRules
+	normalizers *Normalizers
+}
+
+type ComparatorOption func(*AttributeComparator)
+
+// WithTagIgnoreRules replaces the default tag ignore rules. Passing nil
+// compares every tag.
+func WithTagIgnoreRules(rules *TagIgnoreRules) ComparatorOption {
+	return func(c *AttributeComparator) {
+		c.tagIgnores = rules
+	}
 }
 
-func NewAttributeComparator(logger *flog.Logger) *AttributeComparator {
-	return &AttributeComparator{
-		logger: logger,
+// WithNormalizers replaces the default value normalizers. Passing nil
+// compares values exactly as read.
+func WithNormalizers(normalizers *Normalizers) ComparatorOption {
+	return func(c *AttributeComparator) {
+		c.normalizers = normalizers
 	}
 }
 
-func (c *AttributeComparator) CompareAttributes(expected, actual *InstanceState, attrs []string) *DriftReport {
+func NewAttributeComparator(logger *flog.Logger, opts ...ComparatorOption) *AttributeComparator {
+	c := &AttributeComparator{
+		logger:      logger,
+		registry:    InstanceAttributes,
+		tagIgnores:  DefaultTagIgnoreRules(),
+		normalizers: DefaultNormalizers(),
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// CompareAttributes compares two instances, traced as a child span of ctx.
+func (c *AttributeComparator) CompareAttributes(ctx context.Context, expected, actual *InstanceState, attrs []string) (*DriftReport, error) {
+	_, span := tracer.Start(ctx, "AttributeComparator.CompareAttributes", trace.WithAttributes(
+		attribute.String("resource.id", actual.InstanceID),
+		attribute.Int("attribute_count", len(attrs)),
+	))
+	defer span.End()
+
+	report, err := c.CompareResource(ResourceTypeInstance, c.registry, expected, actual, attrs)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	span.SetAttributes(attribute.Int("drift.count", len(report.Drifts)))
+	return report, nil
+}
+
+// CompareResource compares two resources of any type using the attribute
+// registry for that type. Every path in attrs is looked up in registry before
+// anything is compared, and an unknown path is an error.
+func (c *AttributeComparator) CompareResource(resourceType string, registry *AttributeRegistry, expected, actual Resource, attrs []string) (*DriftReport, error) {
+	refs := make([]*AttributeRef, 0, len(attrs))
+	for _, attr := range attrs {
+		ref, err := registry.Lookup(attr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid attribute for %s: %w", resourceType, err)
+		}
+		refs = append(refs, ref)
+	}
+
 	c.logger.Info("starting attribute comparison",
-		zap.String("instance_id", actual.InstanceID),
+		zap.String("resource_type", resourceType),
+		zap.String("resource_id", actual.ResourceID()),
 		zap.Strings("attributes", attrs),
 	)
 
 	report := &DriftReport{
-		InstanceID:   actual.InstanceID,
+		InstanceID:   actual.ResourceID(),
+		ResourceType: resourceType,
 		HasDrift:     false,
 		Drifts:       []AttributeDrift{},
 		CheckedAttrs: attrs,
 	}
 
-	for _, attr := range attrs {
-		c.compareAttribute(attr, expected, actual, report)
+	for _, ref := range refs {
+		c.compareAttribute(ref, expected, actual, report)
 	}
 
 	if report.HasDrift {
 		c.logger.Warn("drift detected",
-			zap.String("instance_id", actual.InstanceID),
+			zap.String("resource_id", actual.ResourceID()),
 			zap.Int("drift_count", len(report.Drifts)),
 		)
 	} else {
 		c.logger.Info("no drift detected",
-			zap.String("instance_id", actual.InstanceID),
+			zap.String("resource_id", actual.ResourceID()),
 		)
 	}
 
-	return report
+	return report, nil
 }
 
-func (c *AttributeComparator) compareAttribute(attr string, expected, actual *InstanceState, report *DriftReport) {
-	expectedVal := c.getAttributeValue(attr, expected)
-	actualVal := c.getAttributeValue(attr, actual)
+func (c *AttributeComparator) compareAttribute(ref *AttributeRef, expected, actual Resource, report *DriftReport) {
+	if isUnset(expected, ref.Path) {
+		return
+	}
+
+	expectedVal := c.normalizers.Normalize(ref, c.getAttributeValue(ref, expected))
+	actualVal := c.normalizers.Normalize(ref, c.getAttributeValue(ref, actual))
 
-	if !c.areEqual(expectedVal, actualVal) {
-		driftType, details := c.determineDriftType(expectedVal, actualVal)
+	if ref.Attribute.TerraformName == "tags" && ref.Kind == AttributeKindStringMap {
+		expectedVal, actualVal = c.applyTagIgnores(expectedVal, actualVal, report)
+	}
+
+	if !c.attributeEqual(ref, expected, actual, expectedVal, actualVal) {
+		analysis := c.determineDriftType(expectedVal, actualVal)
 
 		c.logger.Debug("drift found in attribute",
-			zap.String("attribute", attr),
+			zap.String("attribute", ref.Path),
 			zap.Any("expected", expectedVal),
 			zap.Any("actual", actualVal),
-			zap.String("drift_type", string(driftType)),
+			zap.String("drift_type", string(analysis.driftType)),
 		)
 
-		if details != "" {
-			report.AddDriftWithDetails(attr, expectedVal, actualVal, driftType, details)
-		} else {
-			report.AddDrift(attr, expectedVal, actualVal, driftType)
-		}
+		report.AddAttributeDrift(AttributeDrift{
+			AttributeName: ref.Path,
+			ExpectedValue: expectedVal,
+			ActualValue:   actualVal,
+			DriftType:     analysis.driftType,
+			Details:       analysis.details,
+			Changes:       analysis.changes,
+			Added:         analysis.added,
+			Removed:       analysis.removed,
+		})
 	}
 }
 
-func (c *AttributeComparator) determineDriftType(expected, actual interface{}) (DriftType, string) {
+func (c *AttributeComparator) determineDriftType(expected, actual interface{}) driftAnalysis {
 	if expected == nil && actual != nil {
-		return DriftTypeMissingInTerraform, "attribute present in instance but not in terraform"
+		return driftAnalysis{driftType: DriftTypeMissingInTerraform, details: "attribute present in instance but not in terraform"}
 	}
 
 	if expected != nil && actual == nil {
-		return DriftTypeMissingInstance, "attribute present in terraform but not in instance"
+		return driftAnalysis{driftType: DriftTypeMissingInstance, details: "attribute present in terraform but not in instance"}
 	}
 
 	switch exp := expected.(type) {
 	case []string:
 		act, ok := actual.([]string)
 		if !ok {
-			return DriftTypeValueMismatch, "type mismatch"
+			return driftAnalysis{driftType: DriftTypeValueMismatch, details: "type mismatch"}
 		}
 		return c.analyzeSliceDrift(exp, act)
 
 	case map[string]string:
 		act, ok := actual.(map[string]string)
 		if !ok {
-			return DriftTypeValueMismatch, "type mismatch"
+			return driftAnalysis{driftType: DriftTypeValueMismatch, details: "type mismatch"}
 		}
 		return c.analyzeMapDrift(exp, act)
 	}
 
-	return DriftTypeValueMismatch, ""
+	return driftAnalysis{driftType: DriftTypeValueMismatch}
 }
 
-func (c *AttributeComparator) analyzeSliceDrift(expected, actual []string) (DriftType, string) {
+func (c *AttributeComparator) analyzeSliceDrift(expected, actual []string) driftAnalysis {
 	expectedSet := make(map[string]bool)
 	for _, v := range expected {
 		expectedSet[v] = true
@@ -196,44 +408,71 @@ func (c *AttributeComparator) analyzeSliceDrift(expected, actual []string) (Drif
 		}
 	}
 
-	if len(missingInExpectation) > 0 && len(extraFromInstance) == 0 {
-		return DriftTypeMissingInstance, fmt.Sprintf("missing values: %v", missingInExpectation)
-	}
+	sort.Strings(missingInExpectation)
+	sort.Strings(extraFromInstance)
 
-	if len(extraFromInstance) > 0 && len(missingInExpectation) == 0 {
-		return DriftTypeExtraInInstance, fmt.Sprintf("extra values: %v", extraFromInstance)
+	analysis := driftAnalysis{
+		driftType: DriftTypeValueMismatch,
+		added:     extraFromInstance,
+		removed:   missingInExpectation,
 	}
 
-	if len(missingInExpectation) > 0 && len(extraFromInstance) > 0 {
-		return DriftTypeValueMismatch, fmt.Sprintf("missing: %v, extra: %v", missingInExpectation, extraFromInstance)
+	switch {
+	case len(missingInExpectation) > 0 && len(extraFromInstance) == 0:
+		analysis.driftType = DriftTypeMissingInstance
+		analysis.details = fmt.Sprintf("missing values: %v", missingInExpectation)
+
+	case len(extraFromInstance) > 0 && len(missingInExpectation) == 0:
+		analysis.driftType = DriftTypeExtraInInstance
+		analysis.details = fmt.Sprintf("extra values: %v", extraFromInstance)
+
+	case len(missingInExpectation) > 0 && len(extraFromInstance) > 0:
+		analysis.details = fmt.Sprintf("missing: %v, extra: %v", missingInExpectation, extraFromInstance)
 	}
 
-	return DriftTypeValueMismatch, ""
+	return analysis
 }
 
-func (c *AttributeComparator) analyzeMapDrift(expected, actual map[string]string) (DriftType, string) {
-	var missingKeys, extraKeys, differentValues []string
+func (c *AttributeComparator) analyzeMapDrift(expected, actual map[string]string) driftAnalysis {
+	var (
+		missingKeys, extraKeys, differentValues []string
+		changes                                 []KeyChange
+	)
 
+	keys := make([]string, 0, len(expected)+len(actual))
 	for k := range expected {
-		if _, exists := actual[k]; !exists {
-			missingKeys = append(missingKeys, k)
-		} else if expected[k] != actual[k] {
-			differentValues = append(differentValues, k)
-		}
+		keys = append(keys, k)
 	}
-
 	for k := range actual {
 		if _, exists := expected[k]; !exists {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		expVal, inExpected := expected[k]
+		actVal, inActual := actual[k]
+
+		switch {
+		case inExpected && !inActual:
+			missingKeys = append(missingKeys, k)
+			changes = append(changes, KeyChange{Key: k, Expected: expVal, Change: KeyChangeRemoved})
+		case !inExpected && inActual:
 			extraKeys = append(extraKeys, k)
+			changes = append(changes, KeyChange{Key: k, Actual: actVal, Change: KeyChangeAdded})
+		case expVal != actVal:
+			differentValues = append(differentValues, k)
+			changes = append(changes, KeyChange{Key: k, Expected: expVal, Actual: actVal, Change: KeyChangeChanged})
 		}
 	}
 
 	if len(missingKeys) > 0 && len(extraKeys) == 0 && len(differentValues) == 0 {
-		return DriftTypeMissingInstance, fmt.Sprintf("missing keys: %v", missingKeys)
+		return driftAnalysis{driftType: DriftTypeMissingInstance, details: fmt.Sprintf("missing keys: %v", missingKeys), changes: changes}
 	}
 
 	if len(extraKeys) > 0 && len(missingKeys) == 0 && len(differentValues) == 0 {
-		return DriftTypeExtraInInstance, fmt.Sprintf("extra keys: %v", extraKeys)
+		return driftAnalysis{driftType: DriftTypeExtraInInstance, details: fmt.Sprintf("extra keys: %v", extraKeys), changes: changes}
 	}
 
 	details := ""
@@ -253,24 +492,76 @@ func (c *AttributeComparator) analyzeMapDrift(expected, actual map[string]string
 		details += fmt.Sprintf("different values: %v", differentValues)
 	}
 
-	return DriftTypeValueMismatch, details
+	return driftAnalysis{driftType: DriftTypeValueMismatch, details: details, changes: changes}
 }
 
-func (c *AttributeComparator) getAttributeValue(attr string, state *InstanceState) interface{} {
-	v := reflect.ValueOf(state).Elem()
-	field := v.FieldByName(attr)
+func (c *AttributeComparator) applyTagIgnores(expected, actual interface{}, report *DriftReport) (interface{}, interface{}) {
+	if c.tagIgnores == nil {
+		return expected, actual
+	}
+
+	expTags, _ := expected.(map[string]string)
+	actTags, _ := actual.(map[string]string)
+
+	expKept, expIgnored := c.tagIgnores.Filter(expTags)
+	actKept, actIgnored := c.tagIgnores.Filter(actTags)
 
-	if !field.IsValid() {
-		c.logger.Warn("invalid attribute name",
-			zap.String("attribute", attr),
+	ignored := make(map[string]bool)
+	for _, k := range report.IgnoredTags {
+		ignored[k] = true
+	}
+	for _, k := range append(expIgnored, actIgnored...) {
+		if !ignored[k] {
+			ignored[k] = true
+			report.IgnoredTags = append(report.IgnoredTags, k)
+		}
+	}
+	sort.Strings(report.IgnoredTags)
+
+	if len(report.IgnoredTags) > 0 {
+		c.logger.Debug("ignoring tags",
+			zap.String("instance_id", report.InstanceID),
+			zap.Strings("tags", report.IgnoredTags),
 		)
-		return nil
 	}
 
-	return field.Interface()
+	return expKept, actKept
+}
+
+func (c *AttributeComparator) getAttributeValue(ref *AttributeRef, state Resource) interface{} {
+	return ref.Value(state)
+}
+
+// attributeEqual compares objects field by field, so each field goes through
+// its own normalizers, and everything else by value. Fields the configuration
+// leaves unset, such as those of a root_block_device block it does not
+// declare, are not compared.
+func (c *AttributeComparator) attributeEqual(ref *AttributeRef, expected, actual Resource, expectedVal, actualVal interface{}) bool {
+	if ref.Kind != AttributeKindObject || len(ref.Attribute.Children) == 0 {
+		return c.areEqual(ref.Kind, expectedVal, actualVal)
+	}
+
+	for _, child := range ref.Attribute.Children {
+		childRef := &AttributeRef{Path: ref.Path + "." + child.Name, Attribute: child, Kind: child.Kind}
+		if isUnset(expected, childRef.Path) {
+			continue
+		}
+		exp := c.normalizers.Normalize(childRef, c.getAttributeValue(childRef, expected))
+		act := c.normalizers.Normalize(childRef, c.getAttributeValue(childRef, actual))
+		if !c.attributeEqual(childRef, expected, actual, exp, act) {
+			return false
+		}
+	}
+	return true
+}
+
+// isUnset reports whether the configuration behind expected leaves path out.
+func isUnset(expected Resource, path string) bool {
+	partial, ok := expected.(PartiallySet)
+	return ok && partial.IsUnset(path)
 }
 
-func (c *AttributeComparator) areEqual(expected, actual interface{}) bool {
+func (c *AttributeComparator) areEqual(kind AttributeKind, expected, actual interface{}) bool {
 	if expected == nil && actual == nil {
 		return true
 	}
@@ -284,6 +575,9 @@ func (c *AttributeComparator) areEqual(expected, actual interface{}) bool {
 		if !ok {
 			return false
 		}
+		if kind != AttributeKindStringSet {
+			return reflect.DeepEqual(exp, act)
+		}
 		return c.compareStringSlices(exp, act)
 	case map[string]string:
 		act, ok := actual.(map[string]string)
@@ -1,7 +1,11 @@
 package models
 
 import (
+	"context"
+	"encoding/json"
 	flog "firefly-ec2-drift-detector/logger"
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -82,10 +86,13 @@ func TestCompareAttributes_NoDrift(t *testing.T) {
 		Monitoring:   true,
 	}
 
-	report := comparator.CompareAttributes(expected, actual, []string{
+	report, err := comparator.CompareAttributes(context.Background(), expected, actual, []string{
 		"InstanceType",
 		"Monitoring",
 	})
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	if report.HasDrift {
 		t.Fatalf("expected no drift")
@@ -108,9 +115,12 @@ func TestCompareAttributes_PrimitiveDrift(t *testing.T) {
 		InstanceType: "t3.medium",
 	}
 
-	report := comparator.CompareAttributes(expected, actual, []string{
+	report, err := comparator.CompareAttributes(context.Background(), expected, actual, []string{
 		"InstanceType",
 	})
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	if !report.HasDrift {
 		t.Fatalf("expected drift")
@@ -138,9 +148,12 @@ func TestCompareAttributes_SliceComparison_OrderIndependent(t *testing.T) {
 		SecurityGroups: []string{"sg-2", "sg-1"},
 	}
 
-	report := comparator.CompareAttributes(expected, actual, []string{
+	report, err := comparator.CompareAttributes(context.Background(), expected, actual, []string{
 		"SecurityGroups",
 	})
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	if report.HasDrift {
 		t.Fatalf("expected no drift for reordered slices")
@@ -163,9 +176,12 @@ func TestCompareAttributes_MapDrift(t *testing.T) {
 		},
 	}
 
-	report := comparator.CompareAttributes(expected, actual, []string{
+	report, err := comparator.CompareAttributes(context.Background(), expected, actual, []string{
 		"Tags",
 	})
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	if !report.HasDrift {
 		t.Fatalf("expected drift due to map value mismatch")
@@ -178,15 +194,357 @@ func TestCompareAttributes_InvalidAttribute(t *testing.T) {
 	expected := &InstanceState{}
 	actual := &InstanceState{InstanceID: "i-123"}
 
-	report := comparator.CompareAttributes(expected, actual, []string{
+	report, err := comparator.CompareAttributes(context.Background(), expected, actual, []string{
+		"InstanceType",
 		"DoesNotExist",
 	})
+	if err == nil {
+		t.Fatalf("expected an error for an invalid attribute, got report %+v", report)
+	}
+	if !strings.Contains(err.Error(), "DoesNotExist") {
+		t.Errorf("error %q does not name the invalid attribute", err)
+	}
+}
+
+func TestCompareAttributes_UnsetObjectFields(t *testing.T) {
+	comparator := newTestComparator(t)
+
+	expected := &InstanceState{
+		RootBlockDevice: BlockDevice{VolumeSize: 8, Encrypted: false},
+		Unset:           map[string]bool{"RootBlockDevice.VolumeType": true},
+	}
+	actual := &InstanceState{
+		InstanceID:      "i-123",
+		RootBlockDevice: BlockDevice{VolumeSize: 8, VolumeType: "gp3", Encrypted: true},
+	}
+
+	report, err := comparator.CompareAttributes(context.Background(), expected, actual, []string{"RootBlockDevice", "RootBlockDevice.VolumeType"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(report.Drifts) != 1 || report.Drifts[0].AttributeName != "RootBlockDevice" {
+		t.Fatalf("expected drift for RootBlockDevice only, since Encrypted is set to false, got %+v", report.Drifts)
+	}
 
+	expected.RootBlockDevice.Encrypted = true
+	report, err = comparator.CompareAttributes(context.Background(), expected, actual, []string{"RootBlockDevice"})
+	if err != nil {
+		t.Fatal(err)
+	}
 	if report.HasDrift {
-		t.Fatalf("did not expect drift for invalid attribute")
+		t.Errorf("expected no drift when only an unset field differs, got %+v", report.Drifts)
 	}
+}
 
-	if len(report.Drifts) != 0 {
-		t.Fatalf("expected no drifts, got %d", len(report.Drifts))
+func TestCompareAttributes_NestedPaths(t *testing.T) {
+	comparator := newTestComparator(t)
+
+	expected := &InstanceState{
+		SecurityGroups:  []string{"sg-1", "sg-2"},
+		Tags:            map[string]string{"Owner": "team-a", "Env": "prod"},
+		MetadataOptions: MetadataOptions{HttpTokens: "required"},
+		RootBlockDevice: BlockDevice{VolumeSize: 20},
+	}
+
+	actual := &InstanceState{
+		InstanceID:      "i-123",
+		SecurityGroups:  []string{"sg-1", "sg-3"},
+		Tags:            map[string]string{"Owner": "team-b", "Env": "prod"},
+		MetadataOptions: MetadataOptions{HttpTokens: "optional"},
+		RootBlockDevice: BlockDevice{VolumeSize: 20},
+	}
+
+	report, err := comparator.CompareAttributes(context.Background(), expected, actual, []string{
+		"Tags.Owner",
+		"Tags.Env",
+		"SecurityGroups[0]",
+		"SecurityGroups[1]",
+		"MetadataOptions.HttpTokens",
+		"RootBlockDevice.VolumeSize",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	drifted := make(map[string]AttributeDrift)
+	for _, drift := range report.Drifts {
+		drifted[drift.AttributeName] = drift
+	}
+
+	for _, attr := range []string{"Tags.Owner", "SecurityGroups[1]", "MetadataOptions.HttpTokens"} {
+		if _, ok := drifted[attr]; !ok {
+			t.Errorf("expected drift for %s", attr)
+		}
+	}
+
+	if len(report.Drifts) != 3 {
+		t.Fatalf("expected 3 drifts, got %d: %+v", len(report.Drifts), report.Drifts)
+	}
+
+	if got := drifted["Tags.Owner"].ExpectedValue; got != "team-a" {
+		t.Errorf("expected Tags.Owner expected value team-a, got %v", got)
+	}
+}
+
+func TestCompareAttributes_MissingMapKey(t *testing.T) {
+	comparator := newTestComparator(t)
+
+	expected := &InstanceState{Tags: map[string]string{}}
+	actual := &InstanceState{
+		InstanceID: "i-123",
+		Tags:       map[string]string{"Owner": "team-a"},
+	}
+
+	report, err := comparator.CompareAttributes(context.Background(), expected, actual, []string{"Tags.Owner"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(report.Drifts) != 1 {
+		t.Fatalf("expected 1 drift, got %d", len(report.Drifts))
+	}
+
+	if report.Drifts[0].DriftType != DriftTypeMissingInTerraform {
+		t.Errorf("unexpected drift type: %s", report.Drifts[0].DriftType)
+	}
+}
+
+func TestCompareAttributes_TerraformNames(t *testing.T) {
+	comparator := newTestComparator(t)
+
+	expected := &InstanceState{ImageID: "ami-1"}
+	actual := &InstanceState{InstanceID: "i-123", ImageID: "ami-2"}
+
+	report, err := comparator.CompareAttributes(context.Background(), expected, actual, []string{"ami"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(report.Drifts) != 1 {
+		t.Fatalf("expected 1 drift, got %d", len(report.Drifts))
+	}
+
+	if report.Drifts[0].AttributeName != "ImageID" {
+		t.Errorf("expected canonical attribute name ImageID, got %s", report.Drifts[0].AttributeName)
+	}
+
+	if report.Drifts[0].ExpectedValue != "ami-1" || report.Drifts[0].ActualValue != "ami-2" {
+		t.Errorf("unexpected drift values: %+v", report.Drifts[0])
+	}
+}
+
+func TestCompareAttributes_TagsUseEffectiveTagSet(t *testing.T) {
+	comparator := newTestComparator(t)
+
+	expected := &InstanceState{
+		Tags:    map[string]string{"Name": "web"},
+		TagsAll: map[string]string{"Name": "web", "ManagedBy": "terraform"},
+	}
+
+	actual := &InstanceState{
+		InstanceID: "i-123",
+		Tags:       map[string]string{"Name": "web", "ManagedBy": "terraform"},
+	}
+
+	report, err := comparator.CompareAttributes(context.Background(), expected, actual, []string{"Tags", "Tags.ManagedBy"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report.HasDrift {
+		t.Fatalf("expected provider default tags not to be reported as drift, got %+v", report.Drifts)
+	}
+}
+
+func TestCompareAttributes_IgnoresAWSManagedTagsByDefault(t *testing.T) {
+	comparator := newTestComparator(t)
+
+	expected := &InstanceState{
+		Tags: map[string]string{"Name": "web"},
+	}
+
+	actual := &InstanceState{
+		InstanceID: "i-123",
+		Tags: map[string]string{
+			"Name":                      "web",
+			"aws:autoscaling:groupName": "web-asg",
+		},
+	}
+
+	report, err := comparator.CompareAttributes(context.Background(), expected, actual, []string{"Tags"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report.HasDrift {
+		t.Fatalf("expected aws: tags to be ignored, got %+v", report.Drifts)
+	}
+
+	if len(report.IgnoredTags) != 1 || report.IgnoredTags[0] != "aws:autoscaling:groupName" {
+		t.Errorf("expected ignored tag to be listed on the report, got %v", report.IgnoredTags)
+	}
+}
+
+func TestCompareAttributes_CustomTagIgnoreRules(t *testing.T) {
+	rules, err := NewTagIgnoreRules([]string{"PatchGroup"}, nil, nil, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	comparator := NewAttributeComparator(flog.NewTestLogger(), WithTagIgnoreRules(rules))
+
+	expected := &InstanceState{
+		Tags: map[string]string{"Name": "web"},
+	}
+
+	actual := &InstanceState{
+		InstanceID: "i-123",
+		Tags: map[string]string{
+			"Name":                      "web",
+			"PatchGroup":                "weekly",
+			"aws:autoscaling:groupName": "web-asg",
+		},
+	}
+
+	report, err := comparator.CompareAttributes(context.Background(), expected, actual, []string{"Tags"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !report.HasDrift {
+		t.Fatalf("expected aws: tag to be compared when defaults are disabled")
+	}
+
+	if len(report.IgnoredTags) != 1 || report.IgnoredTags[0] != "PatchGroup" {
+		t.Errorf("unexpected ignored tags: %v", report.IgnoredTags)
+	}
+}
+
+func TestCompareAttributes_TagKeyChanges(t *testing.T) {
+	comparator := newTestComparator(t)
+
+	expected := &InstanceState{
+		Tags: map[string]string{
+			"Name":  "web",
+			"Owner": "team-a",
+			"Env":   "prod",
+		},
+	}
+
+	actual := &InstanceState{
+		InstanceID: "i-123",
+		Tags: map[string]string{
+			"Name":       "web",
+			"Owner":      "team-b",
+			"PatchGroup": "weekly",
+		},
+	}
+
+	report, err := comparator.CompareAttributes(context.Background(), expected, actual, []string{"Tags"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(report.Drifts) != 1 {
+		t.Fatalf("expected 1 drift, got %d", len(report.Drifts))
+	}
+
+	expectedChanges := []KeyChange{
+		{Key: "Env", Expected: "prod", Change: KeyChangeRemoved},
+		{Key: "Owner", Expected: "team-a", Actual: "team-b", Change: KeyChangeChanged},
+		{Key: "PatchGroup", Actual: "weekly", Change: KeyChangeAdded},
+	}
+
+	if !reflect.DeepEqual(report.Drifts[0].Changes, expectedChanges) {
+		t.Errorf("expected changes %+v, got %+v", expectedChanges, report.Drifts[0].Changes)
+	}
+
+	if report.Drifts[0].DriftType != DriftTypeValueMismatch {
+		t.Errorf("unexpected drift type: %s", report.Drifts[0].DriftType)
+	}
+}
+
+func TestAttributeDrift_ChangesJSON(t *testing.T) {
+	drift := AttributeDrift{
+		AttributeName: "Tags",
+		DriftType:     DriftTypeExtraInInstance,
+		Changes: []KeyChange{
+			{Key: "PatchGroup", Actual: "weekly", Change: KeyChangeAdded},
+		},
+	}
+
+	data, err := json.Marshal(drift)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := `"Changes":[{"Key":"PatchGroup","Actual":"weekly","Change":"ADDED"}]`
+	if !strings.Contains(string(data), expected) {
+		t.Errorf("expected JSON to contain %s, got %s", expected, data)
+	}
+}
+
+func TestCompareAttributes_SetAddedRemoved(t *testing.T) {
+	comparator := newTestComparator(t)
+
+	expected := &InstanceState{
+		SecurityGroups: []string{"sg-e", "sg-a", "sg-c", "sg-shared"},
+	}
+
+	actual := &InstanceState{
+		InstanceID:     "i-123",
+		SecurityGroups: []string{"sg-shared", "sg-z", "sg-b", "sg-y"},
+	}
+
+	// Repeat to catch map iteration order leaking into the output.
+	for i := 0; i < 20; i++ {
+		report, err := comparator.CompareAttributes(context.Background(), expected, actual, []string{"SecurityGroups"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(report.Drifts) != 1 {
+			t.Fatalf("expected 1 drift, got %d", len(report.Drifts))
+		}
+
+		drift := report.Drifts[0]
+
+		if drift.DriftType != DriftTypeValueMismatch {
+			t.Errorf("unexpected drift type: %s", drift.DriftType)
+		}
+
+		if !reflect.DeepEqual(drift.Added, []string{"sg-b", "sg-y", "sg-z"}) {
+			t.Fatalf("unexpected added values: %v", drift.Added)
+		}
+
+		if !reflect.DeepEqual(drift.Removed, []string{"sg-a", "sg-c", "sg-e"}) {
+			t.Fatalf("unexpected removed values: %v", drift.Removed)
+		}
+
+		if drift.Details != "missing: [sg-a sg-c sg-e], extra: [sg-b sg-y sg-z]" {
+			t.Fatalf("unexpected details: %s", drift.Details)
+		}
+	}
+}
+
+func TestCompareAttributes_SetOnlyRemoved(t *testing.T) {
+	comparator := newTestComparator(t)
+
+	expected := &InstanceState{SecurityGroups: []string{"sg-1", "sg-2"}}
+	actual := &InstanceState{InstanceID: "i-123", SecurityGroups: []string{"sg-1"}}
+
+	report, err := comparator.CompareAttributes(context.Background(), expected, actual, []string{"SecurityGroups"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	drift := report.Drifts[0]
+	if drift.DriftType != DriftTypeMissingInstance {
+		t.Errorf("unexpected drift type: %s", drift.DriftType)
+	}
+
+	if len(drift.Added) != 0 || !reflect.DeepEqual(drift.Removed, []string{"sg-2"}) {
+		t.Errorf("unexpected added/removed: %v / %v", drift.Added, drift.Removed)
 	}
 }
@@ -0,0 +1,141 @@
+package models
+
+import "strings"
+
+type (
+	// Normalizer canonicalises an attribute value so that cosmetic
+	// differences between Terraform and AWS do not count as drift.
+	Normalizer func(value interface{}) interface{}
+
+	// Normalizers holds the normalizers applied to both sides of a
+	// comparison, by attribute kind and by Terraform attribute name.
+	Normalizers struct {
+		byKind map[AttributeKind][]Normalizer
+		byName map[string][]Normalizer
+	}
+)
+
+// DefaultNormalizers trims strings, treats missing and empty collections
+// alike and folds the case of attributes AWS treats case-insensitively.
+func DefaultNormalizers() *Normalizers {
+	n := &Normalizers{
+		byKind: make(map[AttributeKind][]Normalizer),
+		byName: make(map[string][]Normalizer),
+	}
+
+	n.AddKind(AttributeKindString, TrimSpace)
+	n.AddKind(AttributeKindStringSet, EmptySlice, trimSlice)
+	n.AddKind(AttributeKindStringMap, EmptyMap)
+
+	for _, name := range []string{"instance_type", "type", "volume_type", "availability_zone",
+		"http_endpoint", "http_tokens", "instance_metadata_tags"} {
+		n.Add(name, Lowercase)
+	}
+
+	return n
+}
+
+// AddKind registers normalizers for every attribute of a kind.
+func (n *Normalizers) AddKind(kind AttributeKind, fns ...Normalizer) *Normalizers {
+	n.byKind[kind] = append(n.byKind[kind], fns...)
+	return n
+}
+
+// Add registers normalizers for an attribute by its Terraform name. They run
+// after the normalizers for its kind.
+func (n *Normalizers) Add(terraformName string, fns ...Normalizer) *Normalizers {
+	n.byName[terraformName] = append(n.byName[terraformName], fns...)
+	return n
+}
+
+// WithAMIAliases resolves AMI aliases, such as resolve:ssm: parameters, to
+// the AMI IDs they stand for.
+func (n *Normalizers) WithAMIAliases(aliases map[string]string) *Normalizers {
+	if len(aliases) == 0 {
+		return n
+	}
+	return n.Add("ami", func(value interface{}) interface{} {
+		if s, ok := value.(string); ok {
+			if id, ok := aliases[s]; ok {
+				return id
+			}
+		}
+		return value
+	})
+}
+
+// Normalize applies the normalizers registered for the referenced attribute.
+// Name normalizers only apply to the attribute as a whole, not to a tag or
+// element selected from it.
+func (n *Normalizers) Normalize(ref *AttributeRef, value interface{}) interface{} {
+	if n == nil {
+		return value
+	}
+
+	for _, fn := range n.byKind[ref.Kind] {
+		value = fn(value)
+	}
+
+	if ref.Kind == ref.Attribute.Kind {
+		for _, fn := range n.byName[ref.Attribute.TerraformName] {
+			value = fn(value)
+		}
+	}
+
+	return value
+}
+
+// TrimSpace trims surrounding whitespace from strings.
+func TrimSpace(value interface{}) interface{} {
+	if s, ok := value.(string); ok {
+		return strings.TrimSpace(s)
+	}
+	return value
+}
+
+// Lowercase folds strings to lower case.
+func Lowercase(value interface{}) interface{} {
+	if s, ok := value.(string); ok {
+		return strings.ToLower(s)
+	}
+	return value
+}
+
+// EmptySlice treats a missing list as an empty one.
+func EmptySlice(value interface{}) interface{} {
+	switch v := value.(type) {
+	case nil:
+		return []string{}
+	case []string:
+		if v == nil {
+			return []string{}
+		}
+	}
+	return value
+}
+
+// EmptyMap treats a missing map as an empty one.
+func EmptyMap(value interface{}) interface{} {
+	switch v := value.(type) {
+	case nil:
+		return map[string]string{}
+	case map[string]string:
+		if v == nil {
+			return map[string]string{}
+		}
+	}
+	return value
+}
+
+func trimSlice(value interface{}) interface{} {
+	values, ok := value.([]string)
+	if !ok {
+		return value
+	}
+
+	trimmed := make([]string, len(values))
+	for i, v := range values {
+		trimmed[i] = strings.TrimSpace(v)
+	}
+	return trimmed
+}
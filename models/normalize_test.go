@@ -0,0 +1,78 @@
+package models
+
+import (
+	"context"
+	"testing"
+
+	flog "firefly-ec2-drift-detector/logger"
+)
+
+func TestAttributeComparator_Normalization(t *testing.T) {
+	alias := "resolve:ssm:/aws/service/ami-amazon-linux-latest/al2023-ami-kernel-default-x86_64"
+
+	expected := &InstanceState{
+		InstanceID:      "i-1",
+		InstanceType:    "T3.Micro",
+		ImageID:         alias,
+		KeyName:         "deploy ",
+		SecurityGroups:  nil,
+		Tags:            nil,
+		MetadataOptions: MetadataOptions{HttpTokens: "Required"},
+	}
+	actual := &InstanceState{
+		InstanceID:      "i-1",
+		InstanceType:    "t3.micro",
+		ImageID:         "ami-0abc",
+		KeyName:         "deploy",
+		SecurityGroups:  []string{},
+		Tags:            map[string]string{},
+		MetadataOptions: MetadataOptions{HttpTokens: "required"},
+	}
+
+	attrs := []string{"InstanceType", "ImageID", "KeyName", "SecurityGroups", "Tags", "MetadataOptions"}
+
+	comparator := NewAttributeComparator(flog.NewTestLogger(),
+		WithNormalizers(DefaultNormalizers().WithAMIAliases(map[string]string{alias: "ami-0abc"})),
+	)
+	report, err := comparator.CompareAttributes(context.Background(), expected, actual, attrs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.HasDrift {
+		t.Errorf("expected cosmetic differences to be normalised, got %+v", report.Drifts)
+	}
+
+	strict := NewAttributeComparator(flog.NewTestLogger(), WithNormalizers(nil))
+	report, err = strict.CompareAttributes(context.Background(), expected, actual, attrs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	drifted := make(map[string]bool)
+	for _, drift := range report.Drifts {
+		drifted[drift.AttributeName] = true
+	}
+	for _, attr := range []string{"InstanceType", "ImageID", "KeyName", "MetadataOptions"} {
+		if !drifted[attr] {
+			t.Errorf("expected %s to drift without normalisation", attr)
+		}
+	}
+}
+
+func TestNormalizers_SelectedValues(t *testing.T) {
+	n := DefaultNormalizers()
+
+	ref, err := InstanceAttributes.Lookup("Tags.Owner")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := n.Normalize(ref, " alice "); got != "alice" {
+		t.Errorf("expected selected tag values to be trimmed, got %q", got)
+	}
+
+	// A missing tag is not the same as an empty one.
+	if got := n.Normalize(ref, nil); got != nil {
+		t.Errorf("expected nil to be kept for a selected tag, got %v", got)
+	}
+}
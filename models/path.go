@@ -0,0 +1,118 @@
+package models
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type (
+	// AttributePath is the parsed form of an attribute path, e.g.
+	// "InstanceType", "Tags.Owner", `Tags["aws:cloudformation:stack-name"]`,
+	// "SecurityGroups[0]" or "MetadataOptions.HttpTokens".
+	AttributePath struct {
+		raw      string
+		segments []pathSegment
+	}
+
+	pathSegment struct {
+		name    string
+		index   int
+		isIndex bool
+	}
+)
+
+// ParseAttributePath parses a dotted/indexed attribute path. Field and map key
+// segments are separated by dots; list elements are selected with [n] and map
+// keys containing dots can be quoted with ["key"].
+func ParseAttributePath(raw string) (AttributePath, error) {
+	path := AttributePath{raw: raw}
+
+	if strings.TrimSpace(raw) == "" {
+		return path, fmt.Errorf("empty attribute path")
+	}
+
+	i := 0
+	expectName := true
+
+	for i < len(raw) {
+		switch {
+		case raw[i] == '[':
+			end := strings.IndexByte(raw[i:], ']')
+			if end < 0 {
+				return path, fmt.Errorf("invalid attribute path %q: unterminated '['", raw)
+			}
+			seg, err := parseBracketSegment(raw[i+1 : i+end])
+			if err != nil {
+				return path, fmt.Errorf("invalid attribute path %q: %w", raw, err)
+			}
+			path.segments = append(path.segments, seg)
+			i += end + 1
+			expectName = false
+
+		case raw[i] == '.':
+			if expectName {
+				return path, fmt.Errorf("invalid attribute path %q: empty segment", raw)
+			}
+			i++
+			expectName = true
+			if i == len(raw) {
+				return path, fmt.Errorf("invalid attribute path %q: trailing '.'", raw)
+			}
+
+		default:
+			if !expectName {
+				return path, fmt.Errorf("invalid attribute path %q: expected '.' or '[' at offset %d", raw, i)
+			}
+			end := strings.IndexAny(raw[i:], ".[")
+			if end < 0 {
+				end = len(raw) - i
+			}
+			path.segments = append(path.segments, pathSegment{name: raw[i : i+end]})
+			i += end
+			expectName = false
+		}
+	}
+
+	if path.segments[0].isIndex {
+		return path, fmt.Errorf("invalid attribute path %q: must start with an attribute name", raw)
+	}
+
+	return path, nil
+}
+
+func parseBracketSegment(inner string) (pathSegment, error) {
+	if len(inner) >= 2 && inner[0] == '"' && inner[len(inner)-1] == '"' {
+		key, err := strconv.Unquote(inner)
+		if err != nil {
+			return pathSegment{}, fmt.Errorf("invalid quoted key %s", inner)
+		}
+		return pathSegment{name: key}, nil
+	}
+
+	index, err := strconv.Atoi(inner)
+	if err != nil || index < 0 {
+		return pathSegment{}, fmt.Errorf("invalid index [%s]", inner)
+	}
+
+	return pathSegment{index: index, isIndex: true}, nil
+}
+
+func (p AttributePath) String() string {
+	return p.raw
+}
+
+// Root returns the top-level attribute name of the path.
+func (p AttributePath) Root() string {
+	if len(p.segments) == 0 {
+		return ""
+	}
+	return p.segments[0].name
+}
+
+func (s pathSegment) describe() string {
+	if s.isIndex {
+		return fmt.Sprintf("index [%d]", s.index)
+	}
+	return fmt.Sprintf("field %q", s.name)
+}
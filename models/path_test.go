@@ -0,0 +1,59 @@
+package models
+
+import (
+	"testing"
+)
+
+func TestParseAttributePath_Valid(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+		root string
+	}{
+		{name: "top level", path: "InstanceType", root: "InstanceType"},
+		{name: "map key", path: "Tags.Owner", root: "Tags"},
+		{name: "quoted map key", path: `Tags["app.kubernetes.io/name"]`, root: "Tags"},
+		{name: "map key with colons", path: "Tags.aws:cloudformation:stack-name", root: "Tags"},
+		{name: "slice index", path: "SecurityGroups[0]", root: "SecurityGroups"},
+		{name: "nested struct", path: "MetadataOptions.HttpTokens", root: "MetadataOptions"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path, err := ParseAttributePath(tt.path)
+			if err != nil {
+				t.Fatalf("unexpected parse error: %v", err)
+			}
+
+			if path.Root() != tt.root {
+				t.Errorf("expected root %q, got %q", tt.root, path.Root())
+			}
+
+			if path.String() != tt.path {
+				t.Errorf("expected string %q, got %q", tt.path, path.String())
+			}
+		})
+	}
+}
+
+func TestParseAttributePath_Invalid(t *testing.T) {
+	tests := []string{
+		"",
+		".InstanceType",
+		"Tags.",
+		"Tags..Owner",
+		"SecurityGroups[",
+		"SecurityGroups[-1]",
+		"SecurityGroups[x]",
+		"[0]",
+		"SecurityGroups[0]Name",
+	}
+
+	for _, raw := range tests {
+		t.Run(raw, func(t *testing.T) {
+			if _, err := ParseAttributePath(raw); err == nil {
+				t.Fatalf("expected parse error for %q", raw)
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package models
+
+// Terraform resource types with drift detection support.
+const (
+	ResourceTypeInstance         = "aws_instance"
+	ResourceTypeSecurityGroup    = "aws_security_group"
+	ResourceTypeVolume           = "aws_ebs_volume"
+	ResourceTypeAutoScalingGroup = "aws_autoscaling_group"
+)
+
+// Resource is the normalised state of a single resource of any supported
+// Terraform type, as read from state or fetched from AWS.
+type Resource interface {
+	// ResourceID is the ID the resource is reported under: the AWS ID, or
+	// "hcl:<name>" for resources only declared in configuration.
+	ResourceID() string
+}
+
+// AttributeRegistryFor returns the attribute registry of a resource type, or
+// nil for types compared on a fixed set of attributes.
+func AttributeRegistryFor(resourceType string) *AttributeRegistry {
+	switch resourceType {
+	case ResourceTypeInstance:
+		return InstanceAttributes
+	case ResourceTypeVolume:
+		return VolumeAttributes
+	default:
+		return nil
+	}
+}
+
+// SourceLocation is where a resource is declared in a .tf file.
+type SourceLocation struct {
+	File      string
+	StartLine int
+	EndLine   int
+}
+
+// Declared is implemented by resources that know where they are declared.
+// Resources read from state files have no location.
+type Declared interface {
+	SourceLocation() *SourceLocation
+}
+
+// Addressed is implemented by resources that know their Terraform address.
+type Addressed interface {
+	ResourceAddress() string
+}
+
+// PartiallySet is implemented by expected resources whose configuration may
+// leave object fields out. Fields that are unset are not compared, while a
+// field set to false or 0 is.
+type PartiallySet interface {
+	IsUnset(path string) bool
+}
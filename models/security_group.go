@@ -0,0 +1,226 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// SelfSource is the rule source used for rules that reference the group
+	// they belong to (Terraform's self = true).
+	SelfSource = "self"
+
+	// AllProtocols is the normalised protocol for "all traffic" rules.
+	AllProtocols = "-1"
+)
+
+type (
+	// SecurityGroupState is a security group and its rules. Rules are kept in
+	// normalised form with exactly one source per rule, which is how
+	// DescribeSecurityGroupRules reports them.
+	SecurityGroupState struct {
+		GroupID     string
+		Name        string
+		Description string
+		VpcID       string
+		Tags        map[string]string
+		Ingress     []SecurityGroupRule
+		Egress      []SecurityGroupRule
+
+		// Partial is set when only some of the group's rules are managed
+		// (standalone rule resources without an aws_security_group), so
+		// rules found only in AWS are not reported as drift.
+		Partial bool
+
+		Source  *SourceLocation // nil unless parsed from HCL
+		Address string          // Terraform address
+	}
+
+	SecurityGroupRule struct {
+		Protocol    string // "tcp", "udp", "icmp", "-1"
+		FromPort    int
+		ToPort      int
+		Source      string // CIDR, IPv6 CIDR, prefix list ID, security group ID or "self"
+		Description string
+	}
+)
+
+// ResourceID implements Resource.
+func (g *SecurityGroupState) ResourceID() string {
+	return g.GroupID
+}
+
+// ResourceTags implements TaggedResource.
+func (g *SecurityGroupState) ResourceTags() map[string]string {
+	return g.Tags
+}
+
+// SourceLocation implements Declared.
+func (g *SecurityGroupState) SourceLocation() *SourceLocation {
+	return g.Source
+}
+
+// ResourceAddress implements Addressed.
+func (g *SecurityGroupState) ResourceAddress() string {
+	return g.Address
+}
+
+// NewSecurityGroupRule builds a normalised rule: protocol numbers and names
+// are folded to the form AWS reports and ports are zeroed for all-traffic
+// rules, where Terraform uses 0 and AWS uses -1.
+func NewSecurityGroupRule(protocol string, fromPort, toPort int, source, description string) SecurityGroupRule {
+	protocol = NormalizeProtocol(protocol)
+	if protocol == AllProtocols {
+		fromPort, toPort = 0, 0
+	}
+
+	return SecurityGroupRule{
+		Protocol:    protocol,
+		FromPort:    fromPort,
+		ToPort:      toPort,
+		Source:      source,
+		Description: description,
+	}
+}
+
+// NormalizeProtocol maps protocol numbers and aliases to the names AWS
+// returns ("6" -> "tcp", "all" -> "-1").
+func NormalizeProtocol(protocol string) string {
+	switch p := strings.ToLower(strings.TrimSpace(protocol)); p {
+	case "", "-1", "all":
+		return AllProtocols
+	case "6":
+		return "tcp"
+	case "17":
+		return "udp"
+	case "1":
+		return "icmp"
+	case "58":
+		return "icmpv6"
+	default:
+		return p
+	}
+}
+
+// Key identifies a rule independently of its description.
+func (r SecurityGroupRule) Key() string {
+	return fmt.Sprintf("%s %s %s", r.Protocol, r.portRange(), r.Source)
+}
+
+func (r SecurityGroupRule) String() string {
+	if r.Description == "" {
+		return r.Key()
+	}
+	return fmt.Sprintf("%s (%s)", r.Key(), r.Description)
+}
+
+func (r SecurityGroupRule) portRange() string {
+	switch {
+	case r.Protocol == AllProtocols:
+		return "all"
+	case r.FromPort == r.ToPort:
+		return strconv.Itoa(r.FromPort)
+	default:
+		return fmt.Sprintf("%d-%d", r.FromPort, r.ToPort)
+	}
+}
+
+// CompareSecurityGroupRules reports added, removed and modified ingress and
+// egress rules between the expected and live state of a security group.
+func CompareSecurityGroupRules(expected, actual *SecurityGroupState) *DriftReport {
+	report := &DriftReport{
+		InstanceID:   actual.GroupID,
+		ResourceType: ResourceTypeSecurityGroup,
+		Drifts:       []AttributeDrift{},
+		CheckedAttrs: []string{"IngressRules", "EgressRules"},
+	}
+
+	compareRuleSet("IngressRules", expected.Ingress, actual.Ingress, expected.Partial, report)
+	compareRuleSet("EgressRules", expected.Egress, actual.Egress, expected.Partial, report)
+
+	return report
+}
+
+func compareRuleSet(attr string, expected, actual []SecurityGroupRule, partial bool, report *DriftReport) {
+	expByKey := indexRules(expected)
+	actByKey := indexRules(actual)
+
+	keys := make([]string, 0, len(expByKey)+len(actByKey))
+	for k := range expByKey {
+		keys = append(keys, k)
+	}
+	for k := range actByKey {
+		if _, ok := expByKey[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	var (
+		added, removed []string
+		changes        []KeyChange
+		modified       int
+	)
+
+	for _, key := range keys {
+		exp, inExpected := expByKey[key]
+		act, inActual := actByKey[key]
+
+		switch {
+		case inExpected && !inActual:
+			removed = append(removed, key)
+			changes = append(changes, KeyChange{Key: key, Expected: exp.Description, Change: KeyChangeRemoved})
+		case !inExpected && inActual:
+			if partial {
+				continue
+			}
+			added = append(added, key)
+			changes = append(changes, KeyChange{Key: key, Actual: act.Description, Change: KeyChangeAdded})
+		case exp.Description != act.Description:
+			modified++
+			changes = append(changes, KeyChange{Key: key, Expected: exp.Description, Actual: act.Description, Change: KeyChangeChanged})
+		}
+	}
+
+	if len(changes) == 0 {
+		return
+	}
+
+	driftType := DriftTypeValueMismatch
+	switch {
+	case len(removed) > 0 && len(added) == 0 && modified == 0:
+		driftType = DriftTypeMissingInstance
+	case len(added) > 0 && len(removed) == 0 && modified == 0:
+		driftType = DriftTypeExtraInInstance
+	}
+
+	report.AddAttributeDrift(AttributeDrift{
+		AttributeName: attr,
+		ExpectedValue: ruleStrings(expected),
+		ActualValue:   ruleStrings(actual),
+		DriftType:     driftType,
+		Details:       fmt.Sprintf("added: %d, removed: %d, modified: %d", len(added), len(removed), modified),
+		Changes:       changes,
+		Added:         added,
+		Removed:       removed,
+	})
+}
+
+func indexRules(rules []SecurityGroupRule) map[string]SecurityGroupRule {
+	index := make(map[string]SecurityGroupRule, len(rules))
+	for _, rule := range rules {
+		index[rule.Key()] = rule
+	}
+	return index
+}
+
+func ruleStrings(rules []SecurityGroupRule) []string {
+	result := make([]string, 0, len(rules))
+	for _, rule := range rules {
+		result = append(result, rule.String())
+	}
+	sort.Strings(result)
+	return result
+}
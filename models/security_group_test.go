@@ -0,0 +1,104 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNewSecurityGroupRule_Normalises(t *testing.T) {
+	tests := []struct {
+		name     string
+		protocol string
+		from, to int
+		wantKey  string
+	}{
+		{"tcp by number", "6", 443, 443, "tcp 443 0.0.0.0/0"},
+		{"port range", "tcp", 1024, 2048, "tcp 1024-2048 0.0.0.0/0"},
+		{"all traffic from terraform", "-1", 0, 0, "-1 all 0.0.0.0/0"},
+		{"all traffic from aws", "-1", -1, -1, "-1 all 0.0.0.0/0"},
+		{"all alias", "all", 0, 65535, "-1 all 0.0.0.0/0"},
+		{"upper case", "UDP", 53, 53, "udp 53 0.0.0.0/0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := NewSecurityGroupRule(tt.protocol, tt.from, tt.to, "0.0.0.0/0", "")
+			if got := rule.Key(); got != tt.wantKey {
+				t.Errorf("Key() = %q, want %q", got, tt.wantKey)
+			}
+		})
+	}
+}
+
+func TestCompareSecurityGroupRules(t *testing.T) {
+	expected := &SecurityGroupState{
+		GroupID: "sg-1",
+		Ingress: []SecurityGroupRule{
+			NewSecurityGroupRule("tcp", 443, 443, "0.0.0.0/0", "https"),
+			NewSecurityGroupRule("tcp", 80, 80, "0.0.0.0/0", "http"),
+		},
+		Egress: []SecurityGroupRule{
+			NewSecurityGroupRule("-1", 0, 0, "0.0.0.0/0", ""),
+		},
+	}
+	actual := &SecurityGroupState{
+		GroupID: "sg-1",
+		Ingress: []SecurityGroupRule{
+			NewSecurityGroupRule("tcp", 443, 443, "0.0.0.0/0", "public https"),
+			NewSecurityGroupRule("tcp", 22, 22, "10.0.0.0/8", ""),
+		},
+		Egress: []SecurityGroupRule{
+			NewSecurityGroupRule("-1", -1, -1, "0.0.0.0/0", ""),
+		},
+	}
+
+	report := CompareSecurityGroupRules(expected, actual)
+
+	if !report.HasDrift || len(report.Drifts) != 1 {
+		t.Fatalf("expected a single ingress drift, got %+v", report.Drifts)
+	}
+	if report.ResourceType != ResourceTypeSecurityGroup {
+		t.Errorf("expected resource type %q, got %q", ResourceTypeSecurityGroup, report.ResourceType)
+	}
+
+	drift := report.Drifts[0]
+	if drift.AttributeName != "IngressRules" || drift.DriftType != DriftTypeValueMismatch {
+		t.Errorf("unexpected drift: %+v", drift)
+	}
+	if !reflect.DeepEqual(drift.Added, []string{"tcp 22 10.0.0.0/8"}) {
+		t.Errorf("Added = %v", drift.Added)
+	}
+	if !reflect.DeepEqual(drift.Removed, []string{"tcp 80 0.0.0.0/0"}) {
+		t.Errorf("Removed = %v", drift.Removed)
+	}
+
+	wantChanges := []KeyChange{
+		{Key: "tcp 22 10.0.0.0/8", Actual: "", Change: KeyChangeAdded},
+		{Key: "tcp 443 0.0.0.0/0", Expected: "https", Actual: "public https", Change: KeyChangeChanged},
+		{Key: "tcp 80 0.0.0.0/0", Expected: "http", Change: KeyChangeRemoved},
+	}
+	if !reflect.DeepEqual(drift.Changes, wantChanges) {
+		t.Errorf("Changes = %+v, want %+v", drift.Changes, wantChanges)
+	}
+}
+
+func TestCompareSecurityGroupRules_PartialIgnoresUnmanagedRules(t *testing.T) {
+	expected := &SecurityGroupState{
+		GroupID: "sg-1",
+		Partial: true,
+		Ingress: []SecurityGroupRule{
+			NewSecurityGroupRule("tcp", 443, 443, "0.0.0.0/0", ""),
+		},
+	}
+	actual := &SecurityGroupState{
+		GroupID: "sg-1",
+		Ingress: []SecurityGroupRule{
+			NewSecurityGroupRule("tcp", 443, 443, "0.0.0.0/0", ""),
+			NewSecurityGroupRule("tcp", 22, 22, "0.0.0.0/0", ""),
+		},
+	}
+
+	if report := CompareSecurityGroupRules(expected, actual); report.HasDrift {
+		t.Errorf("expected no drift for rules outside a partially managed group, got %+v", report.Drifts)
+	}
+}
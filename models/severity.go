@@ -0,0 +1,191 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+)
+
+const (
+	SeverityCritical Severity = "critical"
+	SeverityHigh     Severity = "high"
+	SeverityMedium   Severity = "medium"
+	SeverityLow      Severity = "low"
+	SeverityInfo     Severity = "info"
+)
+
+// severityRanks orders severities from least to most urgent.
+var severityRanks = map[Severity]int{
+	SeverityInfo:     1,
+	SeverityLow:      2,
+	SeverityMedium:   3,
+	SeverityHigh:     4,
+	SeverityCritical: 5,
+}
+
+type (
+	// Severity says how urgent a drift is.
+	Severity string
+
+	// SeverityRule assigns a severity to drifts it matches. Empty fields
+	// match anything.
+	SeverityRule struct {
+		// Attribute matches the drift's attribute path exactly, as a prefix
+		// ("Tags" matches "Tags.Owner") or as a path.Match pattern.
+		Attribute    string            `json:"attribute,omitempty"`
+		ResourceType string            `json:"resource_type,omitempty"`
+		DriftType    DriftType         `json:"drift_type,omitempty"`
+		Tags         map[string]string `json:"tags,omitempty"` // resource tags that must all be present
+		Severity     Severity          `json:"severity"`
+	}
+
+	// SeverityPolicy classifies drifts. The first matching rule wins; drifts
+	// no rule matches get Default.
+	SeverityPolicy struct {
+		Default Severity       `json:"default,omitempty"`
+		Rules   []SeverityRule `json:"rules"`
+	}
+
+	// TaggedResource is implemented by resources whose tags severity rules
+	// can match.
+	TaggedResource interface {
+		ResourceTags() map[string]string
+	}
+)
+
+// ParseSeverity validates a severity name, case-insensitively.
+func ParseSeverity(s string) (Severity, error) {
+	severity := Severity(strings.ToLower(strings.TrimSpace(s)))
+	if _, ok := severityRanks[severity]; !ok {
+		return "", fmt.Errorf("unknown severity %q (use critical, high, medium, low or info)", s)
+	}
+	return severity, nil
+}
+
+// AtLeast reports whether s is as urgent as other or more. Unset severities
+// are below every level.
+func (s Severity) AtLeast(other Severity) bool {
+	return severityRanks[s] >= severityRanks[other]
+}
+
+// DefaultSeverityPolicy rates network exposure and encryption changes above
+// everything else and tag changes below it.
+func DefaultSeverityPolicy() *SeverityPolicy {
+	return &SeverityPolicy{
+		Default: SeverityMedium,
+		Rules: []SeverityRule{
+			{ResourceType: ResourceTypeSecurityGroup, Attribute: "IngressRules", Severity: SeverityCritical},
+			{ResourceType: ResourceTypeSecurityGroup, Attribute: "EgressRules", Severity: SeverityHigh},
+			{Attribute: "SecurityGroups", Severity: SeverityHigh},
+			{Attribute: "MetadataOptions", Severity: SeverityHigh},
+			{Attribute: "RootBlockDevice.Encrypted", Severity: SeverityHigh},
+			{Attribute: "RootBlockDevice.KmsKeyID", Severity: SeverityHigh},
+			{ResourceType: ResourceTypeVolume, Attribute: "Encrypted", Severity: SeverityHigh},
+			{ResourceType: ResourceTypeVolume, Attribute: "KmsKeyID", Severity: SeverityHigh},
+			{Attribute: "Tags", Severity: SeverityLow},
+		},
+	}
+}
+
+// LoadSeverityPolicy reads a JSON policy file. Its rules are checked before
+// the built-in ones, so a file only needs to list what it changes.
+func LoadSeverityPolicy(filename string) (*SeverityPolicy, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read severity policy: %w", err)
+	}
+
+	var policy SeverityPolicy
+	if err := json.Unmarshal(data, &policy); err != nil {
+		return nil, fmt.Errorf("failed to parse severity policy %s: %w", filename, err)
+	}
+
+	if err := policy.validate(); err != nil {
+		return nil, fmt.Errorf("invalid severity policy %s: %w", filename, err)
+	}
+
+	defaults := DefaultSeverityPolicy()
+	if policy.Default == "" {
+		policy.Default = defaults.Default
+	}
+	policy.Rules = append(policy.Rules, defaults.Rules...)
+
+	return &policy, nil
+}
+
+func (p *SeverityPolicy) validate() error {
+	if p.Default != "" {
+		severity, err := ParseSeverity(string(p.Default))
+		if err != nil {
+			return fmt.Errorf("default: %w", err)
+		}
+		p.Default = severity
+	}
+
+	for i := range p.Rules {
+		severity, err := ParseSeverity(string(p.Rules[i].Severity))
+		if err != nil {
+			return fmt.Errorf("rule %d: %w", i+1, err)
+		}
+		p.Rules[i].Severity = severity
+
+		if _, err := path.Match(p.Rules[i].Attribute, ""); err != nil {
+			return fmt.Errorf("rule %d: invalid attribute pattern %q: %w", i+1, p.Rules[i].Attribute, err)
+		}
+	}
+
+	return nil
+}
+
+// Apply sets the severity of every drift in the report, and of the report
+// itself to the highest of them.
+func (p *SeverityPolicy) Apply(report *DriftReport, tags map[string]string) {
+	if p == nil {
+		return
+	}
+
+	report.Severity = ""
+	for i := range report.Drifts {
+		drift := &report.Drifts[i]
+		drift.Severity = p.Classify(report.ResourceType, drift, tags)
+
+		if report.Severity == "" || !report.Severity.AtLeast(drift.Severity) {
+			report.Severity = drift.Severity
+		}
+	}
+}
+
+// Classify returns the severity of a single drift.
+func (p *SeverityPolicy) Classify(resourceType string, drift *AttributeDrift, tags map[string]string) Severity {
+	for _, rule := range p.Rules {
+		if rule.matches(resourceType, drift, tags) {
+			return rule.Severity
+		}
+	}
+	return p.Default
+}
+
+func (r SeverityRule) matches(resourceType string, drift *AttributeDrift, tags map[string]string) bool {
+	if r.ResourceType != "" && r.ResourceType != resourceType {
+		return false
+	}
+	if r.DriftType != "" && r.DriftType != drift.DriftType {
+		return false
+	}
+	for key, value := range r.Tags {
+		if tags[key] != value {
+			return false
+		}
+	}
+	return r.Attribute == "" || matchAttribute(r.Attribute, drift.AttributeName)
+}
+
+func matchAttribute(pattern, name string) bool {
+	if pattern == name || strings.HasPrefix(name, pattern+".") || strings.HasPrefix(name, pattern+"[") {
+		return true
+	}
+	matched, _ := path.Match(pattern, name)
+	return matched
+}
@@ -0,0 +1,117 @@
+package models
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseSeverity(t *testing.T) {
+	if got, err := ParseSeverity(" High "); err != nil || got != SeverityHigh {
+		t.Errorf("ParseSeverity(High) = %q, %v", got, err)
+	}
+	if _, err := ParseSeverity("urgent"); err == nil {
+		t.Error("expected an error for an unknown severity")
+	}
+}
+
+func TestSeverity_AtLeast(t *testing.T) {
+	tests := []struct {
+		severity, threshold Severity
+		want                bool
+	}{
+		{SeverityCritical, SeverityHigh, true},
+		{SeverityHigh, SeverityHigh, true},
+		{SeverityMedium, SeverityHigh, false},
+		{SeverityInfo, SeverityInfo, true},
+		{"", SeverityInfo, false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.severity.AtLeast(tt.threshold); got != tt.want {
+			t.Errorf("%q.AtLeast(%q) = %v, want %v", tt.severity, tt.threshold, got, tt.want)
+		}
+	}
+}
+
+func TestDefaultSeverityPolicy(t *testing.T) {
+	policy := DefaultSeverityPolicy()
+
+	tests := []struct {
+		resourceType string
+		attribute    string
+		want         Severity
+	}{
+		{ResourceTypeSecurityGroup, "IngressRules", SeverityCritical},
+		{ResourceTypeInstance, "SecurityGroups", SeverityHigh},
+		{ResourceTypeInstance, "MetadataOptions.HttpTokens", SeverityHigh},
+		{ResourceTypeInstance, "Tags.Owner", SeverityLow},
+		{ResourceTypeInstance, "InstanceType", SeverityMedium},
+		{ResourceTypeVolume, "Encrypted", SeverityHigh},
+	}
+
+	for _, tt := range tests {
+		drift := &AttributeDrift{AttributeName: tt.attribute, DriftType: DriftTypeValueMismatch}
+		if got := policy.Classify(tt.resourceType, drift, nil); got != tt.want {
+			t.Errorf("Classify(%s %s) = %s, want %s", tt.resourceType, tt.attribute, got, tt.want)
+		}
+	}
+}
+
+func TestLoadSeverityPolicy(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "severity.json")
+	policy := `{
+		"default": "low",
+		"rules": [
+			{"tags": {"Environment": "prod"}, "attribute": "InstanceType", "severity": "critical"},
+			{"drift_type": "EXTRA_IN_INSTANCE", "attribute": "Instances*", "severity": "High"}
+		]
+	}`
+	if err := os.WriteFile(file, []byte(policy), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadSeverityPolicy(file)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	report := &DriftReport{ResourceType: ResourceTypeInstance}
+	report.AddDrift("InstanceType", "t3.micro", "t3.large", DriftTypeValueMismatch)
+	report.AddDrift("KeyName", "a", "b", DriftTypeValueMismatch)
+	report.AddDrift("SecurityGroups", []string{"sg-1"}, []string{"sg-2"}, DriftTypeValueMismatch)
+
+	loaded.Apply(report, map[string]string{"Environment": "prod"})
+
+	want := []Severity{SeverityCritical, SeverityLow, SeverityHigh}
+	for i, drift := range report.Drifts {
+		if drift.Severity != want[i] {
+			t.Errorf("%s: severity = %s, want %s", drift.AttributeName, drift.Severity, want[i])
+		}
+	}
+	if report.Severity != SeverityCritical {
+		t.Errorf("report severity = %s, want critical", report.Severity)
+	}
+
+	// Outside prod the InstanceType rule does not apply.
+	loaded.Apply(report, map[string]string{"Environment": "dev"})
+	if report.Drifts[0].Severity != SeverityLow || report.Severity != SeverityHigh {
+		t.Errorf("unexpected severities outside prod: %s, report %s", report.Drifts[0].Severity, report.Severity)
+	}
+
+	member := &AttributeDrift{AttributeName: `Instances["i-1"].Tags`, DriftType: DriftTypeExtraInInstance}
+	if got := loaded.Classify(ResourceTypeAutoScalingGroup, member, nil); got != SeverityHigh {
+		t.Errorf("expected the pattern rule to match, got %s", got)
+	}
+}
+
+func TestLoadSeverityPolicy_Invalid(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "severity.json")
+	if err := os.WriteFile(file, []byte(`{"rules": [{"attribute": "Tags", "severity": "urgent"}]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := LoadSeverityPolicy(file); err == nil {
+		t.Error("expected an error for an unknown severity")
+	}
+}
@@ -0,0 +1,99 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// DefaultIgnoredTagPrefix covers tags AWS writes itself (aws:autoscaling:*,
+// aws:cloudformation:*, aws:ec2launchtemplate:*, ...). Terraform cannot manage
+// these, so they are never meaningful drift.
+const DefaultIgnoredTagPrefix = "aws:"
+
+// TagIgnoreRules decides which tag keys are left out of a Tags comparison.
+type TagIgnoreRules struct {
+	Keys     []string
+	Prefixes []string
+	Patterns []*regexp.Regexp
+}
+
+// DefaultTagIgnoreRules ignores AWS-managed tags only.
+func DefaultTagIgnoreRules() *TagIgnoreRules {
+	return &TagIgnoreRules{
+		Prefixes: []string{DefaultIgnoredTagPrefix},
+	}
+}
+
+// NewTagIgnoreRules builds rules from exact keys, key prefixes and regular
+// expressions, optionally including the built-in AWS-managed prefix.
+func NewTagIgnoreRules(keys, prefixes, patterns []string, includeDefaults bool) (*TagIgnoreRules, error) {
+	rules := &TagIgnoreRules{}
+	if includeDefaults {
+		rules = DefaultTagIgnoreRules()
+	}
+
+	rules.Keys = append(rules.Keys, keys...)
+	rules.Prefixes = append(rules.Prefixes, prefixes...)
+
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid tag ignore pattern %q: %w", pattern, err)
+		}
+		rules.Patterns = append(rules.Patterns, re)
+	}
+
+	return rules, nil
+}
+
+// Matches reports whether a tag key should be ignored.
+func (r *TagIgnoreRules) Matches(key string) bool {
+	if r == nil {
+		return false
+	}
+
+	for _, k := range r.Keys {
+		if k == key {
+			return true
+		}
+	}
+
+	for _, prefix := range r.Prefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+
+	for _, re := range r.Patterns {
+		if re.MatchString(key) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Filter returns a copy of tags without the ignored keys, along with the
+// sorted list of keys that were removed.
+func (r *TagIgnoreRules) Filter(tags map[string]string) (map[string]string, []string) {
+	if tags == nil {
+		return nil, nil
+	}
+
+	kept := make(map[string]string, len(tags))
+	var ignored []string
+
+	for k, v := range tags {
+		if r.Matches(k) {
+			ignored = append(ignored, k)
+			continue
+		}
+		kept[k] = v
+	}
+
+	sort.Strings(ignored)
+
+	return kept, ignored
+}
@@ -0,0 +1,76 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTagIgnoreRules_Matches(t *testing.T) {
+	rules, err := NewTagIgnoreRules(
+		[]string{"PatchGroup"},
+		[]string{"patching:"},
+		[]string{`^backup-\d+$`},
+		true,
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		key     string
+		ignored bool
+	}{
+		{key: "aws:autoscaling:groupName", ignored: true},
+		{key: "aws:cloudformation:stack-name", ignored: true},
+		{key: "PatchGroup", ignored: true},
+		{key: "patching:last-run", ignored: true},
+		{key: "backup-42", ignored: true},
+		{key: "backup-latest", ignored: false},
+		{key: "Name", ignored: false},
+		{key: "Patchgroup", ignored: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			if got := rules.Matches(tt.key); got != tt.ignored {
+				t.Errorf("Matches(%q) = %v, want %v", tt.key, got, tt.ignored)
+			}
+		})
+	}
+}
+
+func TestTagIgnoreRules_WithoutDefaults(t *testing.T) {
+	rules, err := NewTagIgnoreRules(nil, nil, nil, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if rules.Matches("aws:autoscaling:groupName") {
+		t.Errorf("expected aws: tags to be compared when defaults are disabled")
+	}
+}
+
+func TestTagIgnoreRules_InvalidPattern(t *testing.T) {
+	if _, err := NewTagIgnoreRules(nil, nil, []string{"("}, true); err == nil {
+		t.Fatalf("expected error for invalid regular expression")
+	}
+}
+
+func TestTagIgnoreRules_Filter(t *testing.T) {
+	rules := DefaultTagIgnoreRules()
+
+	kept, ignored := rules.Filter(map[string]string{
+		"Name":                          "web",
+		"aws:cloudformation:stack-name": "stack",
+		"aws:autoscaling:groupName":     "asg",
+	})
+
+	if !reflect.DeepEqual(kept, map[string]string{"Name": "web"}) {
+		t.Errorf("unexpected kept tags: %v", kept)
+	}
+
+	expectedIgnored := []string{"aws:autoscaling:groupName", "aws:cloudformation:stack-name"}
+	if !reflect.DeepEqual(ignored, expectedIgnored) {
+		t.Errorf("expected ignored %v, got %v", expectedIgnored, ignored)
+	}
+}
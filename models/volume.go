@@ -0,0 +1,108 @@
+package models
+
+import "fmt"
+
+type (
+	// VolumeState is a standalone EBS volume (aws_ebs_volume) together with
+	// the instance it is attached to (aws_volume_attachment).
+	VolumeState struct {
+		VolumeID         string
+		AvailabilityZone string
+		Size             int
+		VolumeType       string
+		Iops             int
+		Throughput       int
+		Encrypted        bool
+		KmsKeyID         string
+		SnapshotID       string
+		Tags             map[string]string
+		TagsAll          map[string]string
+		Attachment       VolumeAttachment
+		Source           *SourceLocation // nil unless parsed from HCL
+		Address          string          // Terraform address
+	}
+
+	// VolumeAttachment is empty when the volume is not attached.
+	VolumeAttachment struct {
+		InstanceID string
+		DeviceName string
+	}
+)
+
+// VolumeAttributes is the registry of attributes that can be compared on an
+// aws_ebs_volume.
+var VolumeAttributes = NewAttributeRegistry(
+	volumeAttr("AvailabilityZone", "availability_zone", AttributeKindString, "Availability zone of the volume",
+		func(v *VolumeState) interface{} { return v.AvailabilityZone }),
+	volumeAttr("Size", "size", AttributeKindInt, "Volume size in GiB",
+		func(v *VolumeState) interface{} { return v.Size }),
+	volumeAttr("VolumeType", "type", AttributeKindString, "Volume type (gp3, io2, ...)",
+		func(v *VolumeState) interface{} { return v.VolumeType }),
+	volumeAttr("Iops", "iops", AttributeKindInt, "Provisioned IOPS",
+		func(v *VolumeState) interface{} { return v.Iops }),
+	volumeAttr("Throughput", "throughput", AttributeKindInt, "Provisioned throughput in MiB/s",
+		func(v *VolumeState) interface{} { return v.Throughput }),
+	volumeAttr("Encrypted", "encrypted", AttributeKindBool, "Volume encryption",
+		func(v *VolumeState) interface{} { return v.Encrypted }),
+	volumeAttr("KmsKeyID", "kms_key_id", AttributeKindString, "KMS key used for encryption",
+		func(v *VolumeState) interface{} { return v.KmsKeyID }),
+	volumeAttr("SnapshotID", "snapshot_id", AttributeKindString, "Snapshot the volume was created from",
+		func(v *VolumeState) interface{} { return v.SnapshotID }),
+	volumeAttr("Tags", "tags", AttributeKindStringMap, "Volume tags, including provider default_tags",
+		func(v *VolumeState) interface{} { return v.EffectiveTags() }),
+	volumeAttr("Attachment", "attachment", AttributeKindObject, "Instance the volume is attached to (aws_volume_attachment)",
+		func(v *VolumeState) interface{} { return v.Attachment },
+		volumeAttr("InstanceID", "instance_id", AttributeKindString, "Attached instance ID",
+			func(v *VolumeState) interface{} { return v.Attachment.InstanceID }),
+		volumeAttr("DeviceName", "device_name", AttributeKindString, "Device name on the instance",
+			func(v *VolumeState) interface{} { return v.Attachment.DeviceName }),
+	),
+)
+
+func volumeAttr(name, tfName string, kind AttributeKind, description string, get func(*VolumeState) interface{}, children ...*Attribute) *Attribute {
+	return stateAttr(name, tfName, kind, description, get, children...)
+}
+
+// ResourceID implements Resource.
+func (v *VolumeState) ResourceID() string {
+	return v.VolumeID
+}
+
+// EffectiveTags returns tags_all when known, otherwise tags.
+func (v *VolumeState) EffectiveTags() map[string]string {
+	if v.TagsAll != nil {
+		return v.TagsAll
+	}
+	return v.Tags
+}
+
+// ResourceTags implements TaggedResource.
+func (v *VolumeState) ResourceTags() map[string]string {
+	return v.EffectiveTags()
+}
+
+// SourceLocation implements Declared.
+func (v *VolumeState) SourceLocation() *SourceLocation {
+	return v.Source
+}
+
+// ResourceAddress implements Addressed.
+func (v *VolumeState) ResourceAddress() string {
+	return v.Address
+}
+
+// DescribeAttachmentDrift explains an attachment drift in terms of the
+// instance the volume is supposed to belong to, or "" when expected and
+// actual attachments agree.
+func DescribeAttachmentDrift(expected, actual VolumeAttachment) (DriftType, string) {
+	switch {
+	case expected.InstanceID == actual.InstanceID:
+		return "", ""
+	case expected.InstanceID == "":
+		return DriftTypeMissingInTerraform, fmt.Sprintf("volume is attached to %s but no aws_volume_attachment is declared", actual.InstanceID)
+	case actual.InstanceID == "":
+		return DriftTypeMissingInstance, fmt.Sprintf("volume is detached from %s", expected.InstanceID)
+	default:
+		return DriftTypeValueMismatch, fmt.Sprintf("volume is attached to %s instead of %s", actual.InstanceID, expected.InstanceID)
+	}
+}
@@ -0,0 +1,93 @@
+package models
+
+import (
+	"testing"
+
+	flog "firefly-ec2-drift-detector/logger"
+)
+
+func TestCompareResource_Volume(t *testing.T) {
+	comparator := NewAttributeComparator(flog.NewTestLogger())
+
+	expected := &VolumeState{
+		VolumeID:   "vol-1",
+		Size:       100,
+		VolumeType: "gp3",
+		Encrypted:  true,
+		Tags:       map[string]string{"Name": "data"},
+		Attachment: VolumeAttachment{InstanceID: "i-1", DeviceName: "/dev/sdf"},
+	}
+	actual := &VolumeState{
+		VolumeID:   "vol-1",
+		Size:       200,
+		VolumeType: "gp3",
+		Encrypted:  true,
+		Tags:       map[string]string{"Name": "data", "aws:backup:source": "plan"},
+		Attachment: VolumeAttachment{InstanceID: "i-1", DeviceName: "/dev/sdf"},
+	}
+
+	attrs, err := VolumeAttributes.Resolve([]string{AllAttributes})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	report, err := comparator.CompareResource(ResourceTypeVolume, VolumeAttributes, expected, actual, attrs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report.ResourceType != ResourceTypeVolume || report.InstanceID != "vol-1" {
+		t.Errorf("unexpected report identity: %s %s", report.ResourceType, report.InstanceID)
+	}
+	if len(report.Drifts) != 1 || report.Drifts[0].AttributeName != "Size" {
+		t.Fatalf("expected only Size to drift, got %+v", report.Drifts)
+	}
+	if len(report.IgnoredTags) != 1 {
+		t.Errorf("expected aws:* volume tags to be ignored, got %v", report.IgnoredTags)
+	}
+}
+
+func TestVolumeAttributes_Lookup(t *testing.T) {
+	ref, err := VolumeAttributes.Lookup("attachment.instance_id")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ref.Path != "Attachment.InstanceID" {
+		t.Errorf("expected canonical path Attachment.InstanceID, got %s", ref.Path)
+	}
+
+	volume := &VolumeState{Attachment: VolumeAttachment{InstanceID: "i-1"}}
+	if got := ref.Value(volume); got != "i-1" {
+		t.Errorf("Value() = %v, want i-1", got)
+	}
+}
+
+func TestDescribeAttachmentDrift(t *testing.T) {
+	tests := []struct {
+		name      string
+		expected  string
+		actual    string
+		wantType  DriftType
+		wantMatch bool
+	}{
+		{"same instance", "i-1", "i-1", "", true},
+		{"detached", "i-1", "", DriftTypeMissingInstance, false},
+		{"reattached", "i-1", "i-2", DriftTypeValueMismatch, false},
+		{"attached outside terraform", "", "i-2", DriftTypeMissingInTerraform, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			driftType, details := DescribeAttachmentDrift(
+				VolumeAttachment{InstanceID: tt.expected},
+				VolumeAttachment{InstanceID: tt.actual},
+			)
+			if driftType != tt.wantType {
+				t.Errorf("drift type = %q, want %q", driftType, tt.wantType)
+			}
+			if (details == "") != tt.wantMatch {
+				t.Errorf("unexpected details %q", details)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package output
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+
+	flog "firefly-ec2-drift-detector/logger"
+	"firefly-ec2-drift-detector/models"
+)
+
+var csvHeader = []string{
+	"resource_type", "resource_id", "address", "attribute",
+	"expected", "actual", "drift_type", "severity",
+}
+
+// csvFormatter writes one row per drifted attribute. Resources without drift
+// have no rows.
+type csvFormatter struct {
+	logger *flog.Logger
+}
+
+func newCSVFormatter(opts Options) (Formatter, error) {
+	return &csvFormatter{logger: opts.Logger}, nil
+}
+
+func (f *csvFormatter) Format(w io.Writer, run *Run) error {
+	f.logger.Debug("formatting output as CSV")
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(csvHeader); err != nil {
+		return fmt.Errorf("failed to write CSV output: %w", err)
+	}
+
+	for _, report := range run.Reports {
+		resourceType := report.ResourceType
+		if resourceType == "" {
+			resourceType = models.ResourceTypeInstance
+		}
+
+		for _, drift := range report.Drifts {
+			row := []string{
+				resourceType,
+				report.InstanceID,
+				report.Address,
+				drift.AttributeName,
+				formatValue(drift.ExpectedValue),
+				formatValue(drift.ActualValue),
+				string(drift.DriftType),
+				string(drift.Severity),
+			}
+			if err := writer.Write(row); err != nil {
+				return fmt.Errorf("failed to write CSV output: %w", err)
+			}
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("failed to write CSV output: %w", err)
+	}
+
+	return nil
+}
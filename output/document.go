@@ -0,0 +1,321 @@
+package output
+
+import (
+	"crypto/rand"
+	_ "embed"
+	"encoding"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+	"unicode"
+
+	"firefly-ec2-drift-detector/models"
+	"firefly-ec2-drift-detector/service"
+)
+
+// SchemaVersion is the version of the JSON report document described by
+// Schema. It changes only when a field is removed, renamed or changes
+// meaning; adding an optional field does not change it.
+const SchemaVersion = "1.0"
+
+// Schema is the JSON Schema of the document written by -f json.
+//
+//go:embed report.schema.json
+var Schema []byte
+
+// The JSON report document. Its fields are decoupled from models so that
+// internal changes do not leak into the format consumers parse.
+type (
+	// Document is the envelope written by -f json.
+	Document struct {
+		SchemaVersion string             `json:"schema_version"`
+		ToolVersion   string             `json:"tool_version"`
+		RunID         string             `json:"run_id"`
+		StartedAt     time.Time          `json:"started_at"`
+		FinishedAt    time.Time          `json:"finished_at"`
+		Region        string             `json:"region,omitempty"`
+		StateSource   string             `json:"state_source"`
+		Attributes    []string           `json:"attributes"`
+		Summary       DocumentSummary    `json:"summary"`
+		Resources     []DocumentResource `json:"resources"`
+		Errors        []DocumentError    `json:"errors"`
+	}
+
+	// DocumentSummary holds the run's counts.
+	DocumentSummary struct {
+		ResourcesChecked    int            `json:"resources_checked"`
+		ResourcesDrifted    int            `json:"resources_drifted"`
+		DriftedAttributes   int            `json:"drifted_attributes"`
+		ResourcesNotChecked int            `json:"resources_not_checked"`
+		SuppressedDrifts    int            `json:"suppressed_drifts,omitempty"`
+		HighestSeverity     string         `json:"highest_severity,omitempty"`
+		BySeverity          map[string]int `json:"by_severity"`
+	}
+
+	// DocumentResource is the result for one checked resource. -f ndjson
+	// writes one per line.
+	DocumentResource struct {
+		ResourceType      string          `json:"resource_type"`
+		ResourceID        string          `json:"resource_id"`
+		Address           string          `json:"address,omitempty"`
+		HasDrift          bool            `json:"has_drift"`
+		Severity          string          `json:"severity,omitempty"`
+		Source            *DocumentSource `json:"source,omitempty"`
+		CheckedAttributes []string        `json:"checked_attributes"`
+		IgnoredTags       []string        `json:"ignored_tags,omitempty"`
+		Drifts            []DocumentDrift `json:"drifts"`
+		Suppressed        []DocumentDrift `json:"suppressed,omitempty"` // accepted by a baseline
+	}
+
+	// DocumentSource is the .tf block that declares a resource.
+	DocumentSource struct {
+		File      string `json:"file"`
+		StartLine int    `json:"start_line"`
+		EndLine   int    `json:"end_line"`
+	}
+
+	// DocumentDrift is one drifted attribute. Expected and Actual are null,
+	// strings, numbers, booleans, or arrays and objects of those.
+	DocumentDrift struct {
+		Attribute string              `json:"attribute"`
+		DriftType string              `json:"drift_type"`
+		Severity  string              `json:"severity,omitempty"`
+		Expected  interface{}         `json:"expected"`
+		Actual    interface{}         `json:"actual"`
+		Details   string              `json:"details,omitempty"`
+		Changes   []DocumentKeyChange `json:"changes,omitempty"`
+		Added     []string            `json:"added,omitempty"`
+		Removed   []string            `json:"removed,omitempty"`
+
+		// Set on suppressed drifts only.
+		SuppressionReason  string     `json:"suppression_reason,omitempty"`
+		SuppressionExpires *time.Time `json:"suppression_expires,omitempty"`
+	}
+
+	// DocumentKeyChange is one key-level difference inside a map attribute.
+	DocumentKeyChange struct {
+		Key      string      `json:"key"`
+		Change   string      `json:"change"`
+		Expected interface{} `json:"expected,omitempty"`
+		Actual   interface{} `json:"actual,omitempty"`
+	}
+
+	// DocumentError is a resource that could not be checked.
+	DocumentError struct {
+		ResourceType string `json:"resource_type"`
+		ResourceID   string `json:"resource_id,omitempty"`
+		Message      string `json:"message"`
+	}
+)
+
+// NewRunID returns a random RFC 4122 version 4 UUID.
+func NewRunID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		// crypto/rand does not fail on supported platforms.
+		panic(fmt.Sprintf("failed to generate run ID: %v", err))
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// NewDocument converts a run to the JSON report document.
+func NewDocument(run *Run) *Document {
+	summary := run.Summary()
+
+	doc := &Document{
+		SchemaVersion: SchemaVersion,
+		ToolVersion:   run.ToolVersion,
+		RunID:         run.ID,
+		StartedAt:     run.Started.UTC(),
+		FinishedAt:    run.Finished.UTC(),
+		Region:        run.Region,
+		StateSource:   run.StatePath,
+		Attributes:    nonNil(run.Attributes),
+		Summary: DocumentSummary{
+			ResourcesChecked:    summary.Resources,
+			ResourcesDrifted:    summary.Drifted,
+			DriftedAttributes:   summary.Drifts,
+			ResourcesNotChecked: summary.NotChecked,
+			SuppressedDrifts:    summary.Suppressed,
+			HighestSeverity:     string(summary.Severity),
+			BySeverity:          make(map[string]int, len(summary.BySeverity)),
+		},
+		Resources: make([]DocumentResource, 0, len(run.Reports)),
+		Errors:    make([]DocumentError, 0, len(run.Failures)),
+	}
+
+	for severity, n := range summary.BySeverity {
+		doc.Summary.BySeverity[string(severity)] = n
+	}
+	for _, report := range run.Reports {
+		doc.Resources = append(doc.Resources, newDocumentResource(report))
+	}
+	for _, failure := range run.Failures {
+		doc.Errors = append(doc.Errors, newDocumentError(failure))
+	}
+
+	return doc
+}
+
+func newDocumentResource(report *models.DriftReport) DocumentResource {
+	resource := DocumentResource{
+		ResourceType:      report.ResourceType,
+		ResourceID:        report.InstanceID,
+		Address:           report.Address,
+		HasDrift:          report.HasDrift,
+		Severity:          string(report.Severity),
+		CheckedAttributes: nonNil(report.CheckedAttrs),
+		IgnoredTags:       report.IgnoredTags,
+		Drifts:            make([]DocumentDrift, 0, len(report.Drifts)),
+	}
+	if resource.ResourceType == "" {
+		resource.ResourceType = models.ResourceTypeInstance
+	}
+	if report.Source != nil {
+		resource.Source = &DocumentSource{
+			File:      report.Source.File,
+			StartLine: report.Source.StartLine,
+			EndLine:   report.Source.EndLine,
+		}
+	}
+
+	for _, drift := range report.Drifts {
+		resource.Drifts = append(resource.Drifts, newDocumentDrift(drift))
+	}
+	for _, suppressed := range report.Suppressed {
+		entry := newDocumentDrift(suppressed.AttributeDrift)
+		entry.SuppressionReason = suppressed.Reason
+		if suppressed.Expires != nil {
+			expires := suppressed.Expires.UTC()
+			entry.SuppressionExpires = &expires
+		}
+		resource.Suppressed = append(resource.Suppressed, entry)
+	}
+
+	return resource
+}
+
+func newDocumentDrift(drift models.AttributeDrift) DocumentDrift {
+	entry := DocumentDrift{
+		Attribute: drift.AttributeName,
+		DriftType: string(drift.DriftType),
+		Severity:  string(drift.Severity),
+		Expected:  documentValue(drift.ExpectedValue),
+		Actual:    documentValue(drift.ActualValue),
+		Details:   drift.Details,
+		Added:     drift.Added,
+		Removed:   drift.Removed,
+	}
+	for _, change := range drift.Changes {
+		entry.Changes = append(entry.Changes, DocumentKeyChange{
+			Key:      change.Key,
+			Change:   string(change.Change),
+			Expected: documentValue(change.Expected),
+			Actual:   documentValue(change.Actual),
+		})
+	}
+	return entry
+}
+
+func newDocumentError(failure *service.ResourceError) DocumentError {
+	return DocumentError{
+		ResourceType: failure.ResourceType,
+		ResourceID:   failure.ResourceID,
+		Message:      failure.Error(),
+	}
+}
+
+// documentValue keeps scalars, and slices and string-keyed maps of them, as
+// they are. Structs, such as a root block device, become objects keyed by
+// their fields in snake_case, as Terraform names them. Anything else is
+// written as its text form.
+func documentValue(v interface{}) interface{} {
+	if v == nil {
+		return nil
+	}
+
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+
+	switch rv.Kind() {
+	case reflect.Bool:
+		return rv.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return rv.Uint()
+	case reflect.Float32, reflect.Float64:
+		return rv.Float()
+	case reflect.String:
+		return rv.String()
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && rv.IsNil() {
+			return nil
+		}
+		values := make([]interface{}, rv.Len())
+		for i := range values {
+			values[i] = documentValue(rv.Index(i).Interface())
+		}
+		return values
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			break
+		}
+		if rv.IsNil() {
+			return nil
+		}
+		values := make(map[string]interface{}, rv.Len())
+		for _, key := range rv.MapKeys() {
+			values[key.String()] = documentValue(rv.MapIndex(key).Interface())
+		}
+		return values
+	case reflect.Struct:
+		if _, ok := rv.Interface().(encoding.TextMarshaler); ok {
+			break
+		}
+		values := make(map[string]interface{}, rv.NumField())
+		for i := range rv.NumField() {
+			field := rv.Type().Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			values[snakeCase(field.Name)] = documentValue(rv.Field(i).Interface())
+		}
+		return values
+	}
+
+	return formatValue(v)
+}
+
+// snakeCase turns a Go field name into snake_case, keeping initialisms
+// together: KmsKeyID becomes kms_key_id.
+func snakeCase(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) {
+			prevLower := unicode.IsLower(runes[i-1])
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if prevLower || (unicode.IsUpper(runes[i-1]) && nextLower) {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+func nonNil(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
@@ -0,0 +1,273 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+
+	"firefly-ec2-drift-detector/models"
+)
+
+func TestTextFormatter(t *testing.T) {
+	out := format(t, "text", newTestRun(), Options{})
+
+	for _, want := range []string{"Instance: i-1", "DRIFT DETECTED (high)", "Expected: t3.micro", "Summary: 1/2 instances have drift"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected text output to contain %q:\n%s", want, out)
+		}
+	}
+}
+
+// compileSchema compiles the published schema, or the definition under it
+// named by fragment, such as "#/$defs/resource".
+func compileSchema(t *testing.T, fragment string) *jsonschema.Schema {
+	t.Helper()
+
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(Schema))
+	if err != nil {
+		t.Fatalf("invalid schema JSON: %v", err)
+	}
+
+	compiler := jsonschema.NewCompiler()
+	compiler.AssertFormat()
+	if err := compiler.AddResource("report.schema.json", doc); err != nil {
+		t.Fatalf("failed to add schema: %v", err)
+	}
+	schema, err := compiler.Compile("report.schema.json" + fragment)
+	if err != nil {
+		t.Fatalf("failed to compile schema: %v", err)
+	}
+	return schema
+}
+
+func validate(t *testing.T, schema *jsonschema.Schema, data string) {
+	t.Helper()
+
+	value, err := jsonschema.UnmarshalJSON(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, data)
+	}
+	if err := schema.Validate(value); err != nil {
+		t.Errorf("output does not match the schema: %v\n%s", err, data)
+	}
+}
+
+func TestJSONFormatter_MatchesSchema(t *testing.T) {
+	run := newTestRun()
+	expires := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	run.Reports = append(run.Reports, &models.DriftReport{
+		InstanceID:   "i-4",
+		ResourceType: models.ResourceTypeInstance,
+		HasDrift:     true,
+		Severity:     models.SeverityHigh,
+		CheckedAttrs: []string{"MetadataOptions", "SecurityGroups", "Tags"},
+		IgnoredTags:  []string{"aws:autoscaling:groupName"},
+		Suppressed: []models.SuppressedDrift{{
+			AttributeDrift: models.AttributeDrift{AttributeName: "InstanceType", ExpectedValue: "t3.micro", ActualValue: "t3.large", DriftType: models.DriftTypeValueMismatch, Severity: models.SeverityMedium},
+			Reason:         "resize pending",
+			Expires:        &expires,
+		}},
+		Drifts: []models.AttributeDrift{
+			{AttributeName: "MetadataOptions", ExpectedValue: models.MetadataOptions{HttpTokens: "required"}, ActualValue: &models.MetadataOptions{HttpTokens: "optional"}, DriftType: models.DriftTypeValueMismatch, Severity: models.SeverityHigh},
+			{AttributeName: "SecurityGroups", ExpectedValue: []string{"sg-1"}, ActualValue: []string{"sg-2"}, DriftType: models.DriftTypeValueMismatch, Added: []string{"sg-2"}, Removed: []string{"sg-1"}},
+			{AttributeName: "Tags", ExpectedValue: map[string]string{"Env": "prod"}, ActualValue: nil, DriftType: models.DriftTypeMissingInstance,
+				Changes: []models.KeyChange{{Key: "Env", Expected: "prod", Change: models.KeyChangeRemoved}}},
+		},
+	})
+
+	out := format(t, "json", run, Options{})
+	validate(t, compileSchema(t, ""), out)
+
+	var doc map[string]interface{}
+	if err := json.Unmarshal([]byte(out), &doc); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if doc["schema_version"] != SchemaVersion || doc["run_id"] != run.ID || doc["finished_at"] != "2024-01-02T03:04:09Z" {
+		t.Errorf("unexpected envelope: %v", doc)
+	}
+
+	drift := doc["resources"].([]interface{})[2].(map[string]interface{})["drifts"].([]interface{})[0].(map[string]interface{})
+	expected, ok := drift["expected"].(map[string]interface{})
+	if !ok || expected["http_tokens"] != "required" || expected["http_put_response_hop_limit"] != float64(0) {
+		t.Errorf("expected a struct value to be written as an object, got %#v", drift["expected"])
+	}
+	if actual, ok := drift["actual"].(map[string]interface{}); !ok || actual["http_tokens"] != "optional" {
+		t.Errorf("expected a struct pointer to be written as an object, got %#v", drift["actual"])
+	}
+}
+
+func TestJSONFormatter_EmptyRunMatchesSchema(t *testing.T) {
+	out := format(t, "json", &Run{ID: "run"}, Options{})
+	validate(t, compileSchema(t, ""), out)
+}
+
+func TestSchemaVersion(t *testing.T) {
+	var schema struct {
+		Properties struct {
+			SchemaVersion struct {
+				Const string `json:"const"`
+			} `json:"schema_version"`
+		} `json:"properties"`
+	}
+	if err := json.Unmarshal(Schema, &schema); err != nil {
+		t.Fatalf("invalid schema JSON: %v", err)
+	}
+	if schema.Properties.SchemaVersion.Const != SchemaVersion {
+		t.Errorf("schema is for version %q, SchemaVersion is %q", schema.Properties.SchemaVersion.Const, SchemaVersion)
+	}
+}
+
+func TestNDJSONFormatter_MatchesSchema(t *testing.T) {
+	lines := strings.Split(strings.TrimSpace(format(t, "ndjson", newTestRun(), Options{})), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 2 resources and 1 error, got %d lines", len(lines))
+	}
+
+	resource := compileSchema(t, "#/$defs/resource")
+	for _, line := range lines[:2] {
+		validate(t, resource, line)
+	}
+	validate(t, compileSchema(t, "#/$defs/error"), lines[2])
+}
+
+func TestSARIFFormatter(t *testing.T) {
+	var log sarifLog
+	if err := json.Unmarshal([]byte(format(t, "sarif", newTestRun(), Options{})), &log); err != nil {
+		t.Fatalf("invalid SARIF JSON: %v", err)
+	}
+
+	run := log.Runs[0]
+	if len(run.Tool.Driver.Rules) != 2 || len(run.Results) != 2 {
+		t.Fatalf("expected 2 rules and 2 results, got %d and %d", len(run.Tool.Driver.Rules), len(run.Results))
+	}
+
+	result := run.Results[0]
+	if result.RuleID != "aws_instance/InstanceType/VALUE_MISMATCH" || result.Level != "error" {
+		t.Errorf("unexpected result: %s at level %s", result.RuleID, result.Level)
+	}
+	if run.Results[1].Level != "note" {
+		t.Errorf("expected low severity to map to note, got %s", run.Results[1].Level)
+	}
+
+	location := result.Locations[0].PhysicalLocation
+	if location == nil || location.ArtifactLocation.URI != "main.tf" || location.Region.StartLine != 3 {
+		t.Errorf("expected the result to point at main.tf:3, got %+v", location)
+	}
+}
+
+func TestSARIFFormatter_RulePerAttribute(t *testing.T) {
+	reports := []*models.DriftReport{
+		{InstanceID: "i-1", HasDrift: true, Drifts: []models.AttributeDrift{
+			{AttributeName: "Tags.Owner", DriftType: models.DriftTypeValueMismatch, Severity: models.SeverityLow},
+		}},
+		{InstanceID: "i-2", HasDrift: true, Drifts: []models.AttributeDrift{
+			{AttributeName: `Tags["app.kubernetes.io/name"]`, DriftType: models.DriftTypeValueMismatch, Severity: models.SeverityHigh},
+			{AttributeName: "RootBlockDevice.Encrypted", DriftType: models.DriftTypeValueMismatch, Severity: models.SeverityHigh},
+		}},
+	}
+
+	run := buildSARIF(reports, "terraform.tfstate", "test").Runs[0]
+
+	if len(run.Tool.Driver.Rules) != 2 {
+		t.Fatalf("expected Tags and RootBlockDevice.Encrypted rules, got %+v", run.Tool.Driver.Rules)
+	}
+
+	tags := run.Tool.Driver.Rules[0]
+	if tags.ID != "aws_instance/Tags/VALUE_MISMATCH" {
+		t.Errorf("expected tag keys to share a rule, got %s", tags.ID)
+	}
+	if tags.DefaultConfiguration.Level != "error" || tags.Properties["security-severity"] != "8.0" {
+		t.Errorf("expected the rule to take its most severe result, got %s and %v",
+			tags.DefaultConfiguration.Level, tags.Properties["security-severity"])
+	}
+	if run.Results[0].Level != "note" || run.Results[1].Level != "error" {
+		t.Errorf("expected each result to keep its own level, got %s and %s", run.Results[0].Level, run.Results[1].Level)
+	}
+	if id := run.Tool.Driver.Rules[1].ID; id != "aws_instance/RootBlockDevice.Encrypted/VALUE_MISMATCH" {
+		t.Errorf("expected object fields to keep their path, got %s", id)
+	}
+}
+
+func TestJUnitFormatter(t *testing.T) {
+	var suites junitTestSuites
+	if err := xml.Unmarshal([]byte(format(t, "junit", newTestRun(), Options{})), &suites); err != nil {
+		t.Fatalf("invalid JUnit XML: %v", err)
+	}
+
+	if suites.Tests != 5 || suites.Failures != 2 || suites.Skipped != 1 {
+		t.Errorf("expected 5 tests, 2 failures and 1 skipped, got %d, %d and %d", suites.Tests, suites.Failures, suites.Skipped)
+	}
+
+	cases := suites.Suites[0].Cases
+	if cases[1].Name != "Tags" || cases[1].Failure == nil || !strings.Contains(cases[1].Failure.Text, "Tags.Owner") {
+		t.Errorf("expected Tags.Owner to fail the Tags case, got %+v", cases[1])
+	}
+	if last := cases[len(cases)-1]; last.Skipped == nil || last.ClassName != "aws_instance.i-3" {
+		t.Errorf("expected i-3 to be skipped, got %+v", last)
+	}
+}
+
+func TestMarkdownFormatter(t *testing.T) {
+	run := newTestRun()
+	run.Reports[0].Drifts[0].ExpectedValue = "a|b"
+
+	out := format(t, "markdown", run, Options{})
+
+	for _, want := range []string{"**1/2 resource(s) have drift**", "1 high, 1 low", "| `InstanceType` | a\\|b |", "### Not checked", "main.tf:3"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected Markdown output to contain %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestHTMLFormatter(t *testing.T) {
+	run := newTestRun()
+	run.Reports[0].Drifts[0].ActualValue = "<script>"
+
+	out := format(t, "html", run, Options{})
+
+	if strings.Contains(out, "<code><script></code>") || !strings.Contains(out, "&lt;script&gt;") {
+		t.Error("expected values to be escaped")
+	}
+	for _, want := range []string{"<details open>", `class="sortable"`, "Not checked", "terraform.tfstate"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected HTML output to contain %q", want)
+		}
+	}
+}
+
+func TestCSVFormatter(t *testing.T) {
+	lines := strings.Split(strings.TrimSpace(format(t, "csv", newTestRun(), Options{})), "\n")
+
+	if len(lines) != 3 {
+		t.Fatalf("expected a header and one row per drift, got %d lines", len(lines))
+	}
+	if lines[1] != "aws_instance,i-1,aws_instance.web,InstanceType,t3.micro,t3.large,VALUE_MISMATCH,high" {
+		t.Errorf("unexpected row: %s", lines[1])
+	}
+}
+
+func TestTemplateFormatter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report.tmpl")
+	tmpl := `{{.Summary.Drifted}} drifted{{range .Reports}}{{if .HasDrift}}; {{label .}} {{.InstanceID}}: {{range .Drifts}}{{.AttributeName}}={{value .ActualValue}} {{end}}{{end}}{{end}}`
+	if err := os.WriteFile(path, []byte(tmpl), 0644); err != nil {
+		t.Fatalf("failed to write template: %v", err)
+	}
+
+	out := format(t, "template", newTestRun(), Options{TemplatePath: path})
+
+	if want := "1 drifted; Instance i-1: InstanceType=t3.large Tags.Owner=bob "; out != want {
+		t.Errorf("got %q, want %q", out, want)
+	}
+
+	if _, err := NewRegistry().New("template", Options{}); err == nil {
+		t.Error("expected an error without a template file")
+	}
+}
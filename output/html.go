@@ -0,0 +1,172 @@
+package output
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+
+	flog "firefly-ec2-drift-detector/logger"
+)
+
+var htmlTemplate = template.Must(template.New("report").Funcs(templateFuncs).Parse(htmlReportTemplate))
+
+// htmlFormatter renders a self-contained page, with no external assets, to
+// publish as a build artifact.
+type htmlFormatter struct {
+	logger *flog.Logger
+}
+
+func newHTMLFormatter(opts Options) (Formatter, error) {
+	return &htmlFormatter{logger: opts.Logger}, nil
+}
+
+func (f *htmlFormatter) Format(w io.Writer, run *Run) error {
+	f.logger.Debug("formatting output as HTML")
+
+	if err := htmlTemplate.Execute(w, run); err != nil {
+		return fmt.Errorf("failed to render HTML output: %w", err)
+	}
+
+	return nil
+}
+
+const htmlReportTemplate = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Firefly Drift Detection Report</title>
+<style>
+  body { font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2rem; color: #1f2328; }
+  h1 { margin-bottom: 0.25rem; }
+  .meta { color: #59636e; margin-bottom: 1.5rem; }
+  .cards { display: flex; gap: 1rem; flex-wrap: wrap; margin-bottom: 2rem; }
+  .card { border: 1px solid #d1d9e0; border-radius: 6px; padding: 0.75rem 1.25rem; min-width: 9rem; }
+  .card .n { font-size: 1.75rem; font-weight: 600; }
+  table { border-collapse: collapse; width: 100%; margin: 0.5rem 0 1rem; }
+  th, td { border: 1px solid #d1d9e0; padding: 0.4rem 0.6rem; text-align: left; vertical-align: top; }
+  th { background: #f6f8fa; }
+  table.sortable th { cursor: pointer; user-select: none; }
+  table.sortable th::after { content: " \2195"; color: #9198a1; }
+  code { font-family: ui-monospace, SFMono-Regular, Menlo, monospace; font-size: 0.9em; }
+  details { border: 1px solid #d1d9e0; border-radius: 6px; padding: 0.5rem 1rem; margin-bottom: 0.75rem; }
+  summary { cursor: pointer; font-weight: 600; }
+  .ok { color: #1a7f37; }
+  .drift { color: #9a6700; }
+  .sev { border-radius: 1em; padding: 0.1rem 0.6rem; font-size: 0.85em; color: #fff; background: #59636e; }
+  .sev-critical { background: #82071e; }
+  .sev-high { background: #cf222e; }
+  .sev-medium { background: #bf8700; }
+  .sev-low { background: #0969da; }
+  .sev-info { background: #59636e; }
+  .details { color: #59636e; margin: 0.25rem 0; }
+</style>
+</head>
+<body>
+{{- $summary := .Summary}}
+<h1>Firefly Drift Detection Report</h1>
+<div class="meta">Generated {{.Started.UTC.Format "Mon, 02 Jan 2006 15:04:05 MST"}}{{if .StatePath}} from <code>{{.StatePath}}</code>{{end}}</div>
+
+<div class="cards">
+  <div class="card"><div class="n">{{$summary.Resources}}</div>resources checked</div>
+  <div class="card"><div class="n {{if $summary.Drifted}}drift{{else}}ok{{end}}">{{$summary.Drifted}}</div>with drift</div>
+  <div class="card"><div class="n">{{$summary.Drifts}}</div>drifted attributes</div>
+  {{- range $sev := severities}}{{with $n := index $summary.BySeverity $sev}}
+  <div class="card"><div class="n">{{$n}}</div><span class="sev sev-{{$sev}}">{{$sev}}</span></div>
+  {{- end}}{{end}}
+  {{- if $summary.NotChecked}}
+  <div class="card"><div class="n drift">{{$summary.NotChecked}}</div>not checked</div>
+  {{- end}}
+</div>
+
+{{if .Reports -}}
+<h2>Resources</h2>
+<table class="sortable">
+  <thead><tr><th>Resource</th><th>ID</th><th>Status</th><th>Severity</th><th>Drifts</th><th>Declared in</th></tr></thead>
+  <tbody>
+  {{- range .Reports}}
+    <tr>
+      <td>{{label .}}</td>
+      <td><code>{{.InstanceID}}</code></td>
+      <td>{{if .HasDrift}}<span class="drift">Drift</span>{{else}}<span class="ok">No drift</span>{{end}}</td>
+      <td>{{with .Severity}}<span class="sev sev-{{.}}">{{.}}</span>{{end}}</td>
+      <td>{{len .Drifts}}</td>
+      <td>{{with source .Source}}<code>{{.}}</code>{{end}}</td>
+    </tr>
+  {{- end}}
+  </tbody>
+</table>
+
+{{if $summary.Drifted -}}
+<h2>Drift</h2>
+{{- range .Reports}}{{if .HasDrift}}
+<details open>
+  <summary>{{label .}} <code>{{.InstanceID}}</code> &mdash; {{len .Drifts}} drifted attribute(s){{with .Severity}} <span class="sev sev-{{.}}">{{.}}</span>{{end}}</summary>
+  <table class="sortable">
+    <thead><tr><th>Attribute</th><th>Expected</th><th>Actual</th><th>Type</th><th>Severity</th></tr></thead>
+    <tbody>
+    {{- range .Drifts}}
+      <tr>
+        <td><code>{{.AttributeName}}</code>{{with .Details}}<div class="details">{{.}}</div>{{end}}</td>
+        <td><code>{{value .ExpectedValue}}</code></td>
+        <td><code>{{value .ActualValue}}</code></td>
+        <td>{{.DriftType}}</td>
+        <td>{{with .Severity}}<span class="sev sev-{{.}}">{{.}}</span>{{end}}</td>
+      </tr>
+    {{- end}}
+    </tbody>
+  </table>
+  {{- with .IgnoredTags}}
+  <div class="details">Ignored tags: {{range $i, $t := .}}{{if $i}}, {{end}}{{$t}}{{end}}</div>
+  {{- end}}
+</details>
+{{- end}}{{end}}
+{{- else}}
+<p class="ok">No drift detected.</p>
+{{- end}}
+{{- else}}
+<p>No resources were checked.</p>
+{{- end}}
+
+{{if .Failures -}}
+<h2>Not checked</h2>
+<table class="sortable">
+  <thead><tr><th>Resource</th><th>Error</th></tr></thead>
+  <tbody>
+  {{- range .Failures}}
+    <tr><td><code>{{failed .}}</code></td><td>{{.Error}}</td></tr>
+  {{- end}}
+  </tbody>
+</table>
+{{- end}}
+
+<script>
+// Click a column header to sort by it; click again to reverse.
+document.querySelectorAll("table.sortable th").forEach(function (th) {
+  th.addEventListener("click", function () {
+    var table = th.closest("table");
+    var tbody = table.tBodies[0];
+    var index = Array.prototype.indexOf.call(th.parentNode.children, th);
+    var ascending = th.dataset.order !== "asc";
+    var rank = { critical: 5, high: 4, medium: 3, low: 2, info: 1 };
+    var key = function (row) {
+      var text = row.children[index].textContent.trim();
+      if (rank[text] !== undefined) return rank[text];
+      var n = parseFloat(text);
+      return isNaN(n) || String(n) !== text ? text.toLowerCase() : n;
+    };
+    Array.prototype.slice.call(tbody.rows)
+      .sort(function (a, b) {
+        var x = key(a), y = key(b);
+        var c = typeof x === typeof y ? (x < y ? -1 : x > y ? 1 : 0) : (typeof x === "number" ? -1 : 1);
+        return ascending ? c : -c;
+      })
+      .forEach(function (row) { tbody.appendChild(row); });
+    table.querySelectorAll("th").forEach(function (h) { delete h.dataset.order; });
+    th.dataset.order = ascending ? "asc" : "desc";
+  });
+});
+</script>
+</body>
+</html>
+`
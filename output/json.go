@@ -0,0 +1,31 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	flog "firefly-ec2-drift-detector/logger"
+)
+
+// jsonFormatter writes the run as a Document, described by Schema.
+type jsonFormatter struct {
+	logger *flog.Logger
+}
+
+func newJSONFormatter(opts Options) (Formatter, error) {
+	return &jsonFormatter{logger: opts.Logger}, nil
+}
+
+func (f *jsonFormatter) Format(w io.Writer, run *Run) error {
+	f.logger.Debug("formatting output as JSON")
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(NewDocument(run)); err != nil {
+		return fmt.Errorf("failed to encode JSON output: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,197 @@
+package output
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	flog "firefly-ec2-drift-detector/logger"
+	"firefly-ec2-drift-detector/models"
+	"firefly-ec2-drift-detector/service"
+)
+
+// JUnit XML in the form Jenkins and GitLab render: one suite per run, one
+// case per resource and checked attribute.
+type (
+	junitTestSuites struct {
+		XMLName  xml.Name         `xml:"testsuites"`
+		Name     string           `xml:"name,attr"`
+		Tests    int              `xml:"tests,attr"`
+		Failures int              `xml:"failures,attr"`
+		Skipped  int              `xml:"skipped,attr"`
+		Suites   []junitTestSuite `xml:"testsuite"`
+	}
+
+	junitTestSuite struct {
+		Name      string          `xml:"name,attr"`
+		Tests     int             `xml:"tests,attr"`
+		Failures  int             `xml:"failures,attr"`
+		Skipped   int             `xml:"skipped,attr"`
+		Timestamp string          `xml:"timestamp,attr"`
+		Cases     []junitTestCase `xml:"testcase"`
+	}
+
+	junitTestCase struct {
+		Name      string        `xml:"name,attr"`
+		ClassName string        `xml:"classname,attr"`
+		File      string        `xml:"file,attr,omitempty"`
+		Failure   *junitFailure `xml:"failure,omitempty"`
+		Skipped   *junitSkipped `xml:"skipped,omitempty"`
+	}
+
+	junitFailure struct {
+		Message string `xml:"message,attr"`
+		Type    string `xml:"type,attr"`
+		Text    string `xml:",chardata"`
+	}
+
+	junitSkipped struct {
+		Message string `xml:"message,attr"`
+	}
+)
+
+type junitFormatter struct {
+	logger *flog.Logger
+}
+
+func newJUnitFormatter(opts Options) (Formatter, error) {
+	return &junitFormatter{logger: opts.Logger}, nil
+}
+
+func (f *junitFormatter) Format(w io.Writer, run *Run) error {
+	f.logger.Debug("formatting output as JUnit XML")
+
+	if _, err := fmt.Fprint(w, xml.Header); err != nil {
+		return fmt.Errorf("failed to write JUnit output: %w", err)
+	}
+
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+
+	if err := encoder.Encode(buildJUnit(run.Reports, run.Failures, run.Started)); err != nil {
+		return fmt.Errorf("failed to encode JUnit output: %w", err)
+	}
+
+	_, err := fmt.Fprintln(w)
+	return err
+}
+
+// buildJUnit turns each checked attribute into a test case that fails when
+// the attribute drifted, and each resource that could not be checked into a
+// skipped case.
+func buildJUnit(reports []*models.DriftReport, failures []*service.ResourceError, now time.Time) *junitTestSuites {
+	suite := junitTestSuite{
+		Name:      "drift detection",
+		Timestamp: now.UTC().Format(time.RFC3339),
+		Cases:     []junitTestCase{},
+	}
+
+	for _, report := range reports {
+		className := junitClassName(report.ResourceType, report.InstanceID)
+		var file string
+		if report.Source != nil {
+			file = report.Source.File
+		}
+
+		drifts := groupDrifts(report)
+		for _, attr := range drifts.attributes {
+			tc := junitTestCase{Name: attr, ClassName: className, File: file}
+			if attrDrifts := drifts.byAttribute[attr]; len(attrDrifts) > 0 {
+				tc.Failure = junitDriftFailure(attrDrifts)
+				suite.Failures++
+			}
+			suite.Cases = append(suite.Cases, tc)
+		}
+	}
+
+	for _, failure := range failures {
+		suite.Cases = append(suite.Cases, junitTestCase{
+			Name:      "drift detection",
+			ClassName: junitClassName(failure.ResourceType, failure.ResourceID),
+			Skipped:   &junitSkipped{Message: failure.Error()},
+		})
+		suite.Skipped++
+	}
+
+	suite.Tests = len(suite.Cases)
+
+	return &junitTestSuites{
+		Name:     "firefly",
+		Tests:    suite.Tests,
+		Failures: suite.Failures,
+		Skipped:  suite.Skipped,
+		Suites:   []junitTestSuite{suite},
+	}
+}
+
+type attributeDrifts struct {
+	attributes  []string
+	byAttribute map[string][]models.AttributeDrift
+}
+
+// groupDrifts files each drift under the checked attribute it belongs to, so
+// Tags.Owner fails the Tags case. Drifts outside every checked attribute,
+// such as Auto Scaling group members, get a case of their own.
+func groupDrifts(report *models.DriftReport) attributeDrifts {
+	groups := attributeDrifts{
+		attributes:  append([]string(nil), report.CheckedAttrs...),
+		byAttribute: make(map[string][]models.AttributeDrift),
+	}
+
+	for _, drift := range report.Drifts {
+		attr := drift.AttributeName
+		for _, checked := range report.CheckedAttrs {
+			if attr == checked || strings.HasPrefix(attr, checked+".") || strings.HasPrefix(attr, checked+"[") {
+				attr = checked
+				break
+			}
+		}
+
+		if !slices.Contains(groups.attributes, attr) {
+			groups.attributes = append(groups.attributes, attr)
+		}
+		groups.byAttribute[attr] = append(groups.byAttribute[attr], drift)
+	}
+
+	return groups
+}
+
+func junitDriftFailure(drifts []models.AttributeDrift) *junitFailure {
+	lines := make([]string, 0, len(drifts))
+	for _, drift := range drifts {
+		line := fmt.Sprintf("%s (%s): expected %s, actual %s",
+			drift.AttributeName, drift.DriftType, formatValue(drift.ExpectedValue), formatValue(drift.ActualValue))
+		if drift.Severity != "" {
+			line += fmt.Sprintf(" [%s]", drift.Severity)
+		}
+		if drift.Details != "" {
+			line += "\n  " + drift.Details
+		}
+		lines = append(lines, line)
+	}
+
+	first := drifts[0]
+	message := fmt.Sprintf("expected %s, actual %s", formatValue(first.ExpectedValue), formatValue(first.ActualValue))
+	if len(drifts) > 1 {
+		message = fmt.Sprintf("%d drifts", len(drifts))
+	}
+
+	return &junitFailure{
+		Message: message,
+		Type:    string(first.DriftType),
+		Text:    strings.Join(lines, "\n"),
+	}
+}
+
+func junitClassName(resourceType, id string) string {
+	if resourceType == "" {
+		resourceType = models.ResourceTypeInstance
+	}
+	if id == "" {
+		return resourceType
+	}
+	return resourceType + "." + id
+}
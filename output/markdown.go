@@ -0,0 +1,130 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	flog "firefly-ec2-drift-detector/logger"
+	"firefly-ec2-drift-detector/models"
+)
+
+// markdownFormatter renders GitHub-flavoured Markdown for pull request
+// comments: a summary table of every resource, then the drifted attributes of
+// each resource with drift.
+type markdownFormatter struct {
+	logger *flog.Logger
+}
+
+func newMarkdownFormatter(opts Options) (Formatter, error) {
+	return &markdownFormatter{logger: opts.Logger}, nil
+}
+
+func (f *markdownFormatter) Format(w io.Writer, run *Run) error {
+	f.logger.Debug("formatting output as Markdown")
+
+	var b strings.Builder
+	reports, failures := run.Reports, run.Failures
+	summary := run.Summary()
+
+	b.WriteString("## Firefly Drift Detection Report\n\n")
+
+	switch {
+	case summary.Resources == 0 && summary.NotChecked == 0:
+		b.WriteString("No resources were checked.\n")
+		_, err := io.WriteString(w, b.String())
+		return err
+	case summary.Drifted == 0:
+		fmt.Fprintf(&b, "✅ **No drift** in %d resource(s).\n\n", summary.Resources)
+	default:
+		fmt.Fprintf(&b, "⚠️ **%d/%d resource(s) have drift** (%d drifted attribute(s)", summary.Drifted, summary.Resources, summary.Drifts)
+		if breakdown := summary.SeverityBreakdown(); breakdown != "" {
+			fmt.Fprintf(&b, ": %s", breakdown)
+		}
+		b.WriteString(").\n\n")
+	}
+
+	if summary.NotChecked > 0 {
+		fmt.Fprintf(&b, "❗ %d resource(s) could not be checked.\n\n", summary.NotChecked)
+	}
+
+	if len(reports) > 0 {
+		b.WriteString("| Resource | ID | Status | Severity | Drifts | Declared in |\n")
+		b.WriteString("|----------|----|--------|----------|--------|-------------|\n")
+		for _, report := range reports {
+			status := "✅ No drift"
+			if report.HasDrift {
+				status = "⚠️ Drift"
+			}
+			fmt.Fprintf(&b, "| %s | `%s` | %s | %s | %d | %s |\n",
+				resourceLabel(report), markdownCode(report.InstanceID), status,
+				markdownCell(string(report.Severity)), len(report.Drifts), markdownCell(sourceLabel(report.Source)))
+		}
+		b.WriteString("\n")
+	}
+
+	for _, report := range reports {
+		if !report.HasDrift {
+			continue
+		}
+
+		fmt.Fprintf(&b, "### %s `%s`\n\n", resourceLabel(report), markdownCode(report.InstanceID))
+		b.WriteString("| Attribute | Expected | Actual | Type | Severity |\n")
+		b.WriteString("|-----------|----------|--------|------|----------|\n")
+		for _, drift := range report.Drifts {
+			fmt.Fprintf(&b, "| `%s` | %s | %s | %s | %s |\n",
+				markdownCode(drift.AttributeName),
+				markdownCell(formatValue(drift.ExpectedValue)),
+				markdownCell(formatValue(drift.ActualValue)),
+				drift.DriftType,
+				markdownCell(string(drift.Severity)))
+		}
+		b.WriteString("\n")
+
+		for _, drift := range report.Drifts {
+			if drift.Details != "" {
+				fmt.Fprintf(&b, "- `%s`: %s\n", markdownCode(drift.AttributeName), markdownText(drift.Details))
+			}
+		}
+		if len(report.IgnoredTags) > 0 {
+			fmt.Fprintf(&b, "- Ignored tags: %s\n", markdownText(strings.Join(report.IgnoredTags, ", ")))
+		}
+		b.WriteString("\n")
+	}
+
+	if len(failures) > 0 {
+		b.WriteString("### Not checked\n\n")
+		for _, failure := range failures {
+			fmt.Fprintf(&b, "- `%s`: %s\n", markdownCode(junitClassName(failure.ResourceType, failure.ResourceID)), markdownText(failure.Error()))
+		}
+		b.WriteString("\n")
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// sourceLabel formats a declaration as file:line.
+func sourceLabel(source *models.SourceLocation) string {
+	if source == nil {
+		return ""
+	}
+	return fmt.Sprintf("%s:%d", source.File, source.StartLine)
+}
+
+// markdownCell escapes a value for use in a table cell.
+func markdownCell(s string) string {
+	if s == "" {
+		return "–"
+	}
+	return strings.ReplaceAll(markdownText(s), "\n", "<br>")
+}
+
+func markdownText(s string) string {
+	return strings.NewReplacer("|", `\|`, "<", "&lt;", ">", "&gt;").Replace(s)
+}
+
+// markdownCode keeps identifiers from closing their code span.
+func markdownCode(s string) string {
+	return strings.NewReplacer("`", "'", "|", `\|`).Replace(s)
+}
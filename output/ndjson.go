@@ -0,0 +1,51 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	flog "firefly-ec2-drift-detector/logger"
+	"firefly-ec2-drift-detector/models"
+)
+
+// ndjsonFormatter writes one JSON object per line: each resource as in the
+// resources of -f json, then each of its errors. As a
+// StreamingFormatter it writes each report as soon as it is compared, so
+// consumers see results before the scan ends.
+type ndjsonFormatter struct {
+	logger *flog.Logger
+}
+
+func newNDJSONFormatter(opts Options) (Formatter, error) {
+	return &ndjsonFormatter{logger: opts.Logger}, nil
+}
+
+func (f *ndjsonFormatter) Format(w io.Writer, run *Run) error {
+	f.logger.Debug("formatting output as NDJSON")
+
+	for _, report := range run.Reports {
+		if err := f.WriteReport(w, report); err != nil {
+			return err
+		}
+	}
+	return f.Finish(w, run)
+}
+
+func (f *ndjsonFormatter) WriteReport(w io.Writer, report *models.DriftReport) error {
+	if err := json.NewEncoder(w).Encode(newDocumentResource(report)); err != nil {
+		return fmt.Errorf("failed to encode NDJSON output: %w", err)
+	}
+	return nil
+}
+
+// Finish writes the errors; the reports have already been written.
+func (f *ndjsonFormatter) Finish(w io.Writer, run *Run) error {
+	encoder := json.NewEncoder(w)
+	for _, failure := range run.Failures {
+		if err := encoder.Encode(newDocumentError(failure)); err != nil {
+			return fmt.Errorf("failed to encode NDJSON output: %w", err)
+		}
+	}
+	return nil
+}
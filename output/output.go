@@ -0,0 +1,164 @@
+// Package output renders drift reports. Each format is a Formatter created
+// by a Factory registered under the name users pass to -f.
+package output
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	flog "firefly-ec2-drift-detector/logger"
+	"firefly-ec2-drift-detector/models"
+	"firefly-ec2-drift-detector/service"
+)
+
+type (
+	// Run is everything a formatter can render about one drift detection
+	// run.
+	Run struct {
+		ID          string // unique per run, see NewRunID
+		ToolVersion string
+		Reports     []*models.DriftReport
+		Failures    []*service.ResourceError // resources that could not be checked
+		StatePath   string
+		Region      string
+		Attributes  []string // aws_instance attributes compared
+		Started     time.Time
+		Finished    time.Time
+	}
+
+	// Formatter writes a run in one format.
+	Formatter interface {
+		Format(w io.Writer, run *Run) error
+	}
+
+	// StreamingFormatter is implemented by formatters that can write each
+	// report as soon as it is complete. WriteReport is called once per
+	// report during the run, then Finish once with the whole run, instead
+	// of Format.
+	StreamingFormatter interface {
+		Formatter
+		WriteReport(w io.Writer, report *models.DriftReport) error
+		Finish(w io.Writer, run *Run) error
+	}
+
+	// Options configure the formatter a Factory creates.
+	Options struct {
+		Logger       *flog.Logger
+		TemplatePath string // text/template file for the "template" format
+	}
+
+	// Factory creates a Formatter.
+	Factory func(opts Options) (Formatter, error)
+
+	// Summary holds the counts shown at the top of a report.
+	Summary struct {
+		Resources  int
+		Drifted    int
+		Drifts     int
+		NotChecked int
+		Suppressed int             // drifts accepted by a baseline
+		Severity   models.Severity // highest across all reports
+		BySeverity map[models.Severity]int
+	}
+
+	// Registry maps format names to factories.
+	Registry struct {
+		factories map[string]Factory
+	}
+)
+
+// Summary counts the resources checked, drifted and not checked, and the
+// drifts by severity.
+func (r *Run) Summary() Summary {
+	summary := Summary{
+		Resources:  len(r.Reports),
+		NotChecked: len(r.Failures),
+		BySeverity: make(map[models.Severity]int),
+	}
+
+	for _, report := range r.Reports {
+		summary.Suppressed += len(report.Suppressed)
+		if !report.HasDrift {
+			continue
+		}
+		summary.Drifted++
+		summary.Drifts += len(report.Drifts)
+
+		if report.Severity != "" && (summary.Severity == "" || !summary.Severity.AtLeast(report.Severity)) {
+			summary.Severity = report.Severity
+		}
+		for _, drift := range report.Drifts {
+			if drift.Severity != "" {
+				summary.BySeverity[drift.Severity]++
+			}
+		}
+	}
+
+	return summary
+}
+
+// SeverityBreakdown lists drift counts from most to least urgent, such as
+// "1 critical, 3 low".
+func (s Summary) SeverityBreakdown() string {
+	var parts []string
+	for _, severity := range severities {
+		if n := s.BySeverity[severity]; n > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", n, severity))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// severities lists severities from most to least urgent.
+var severities = []models.Severity{models.SeverityCritical, models.SeverityHigh,
+	models.SeverityMedium, models.SeverityLow, models.SeverityInfo}
+
+// NewRegistry returns a registry with every built-in format.
+func NewRegistry() *Registry {
+	r := &Registry{factories: make(map[string]Factory)}
+
+	r.Register("text", newTextFormatter)
+	r.Register("json", newJSONFormatter)
+	r.Register("sarif", newSARIFFormatter)
+	r.Register("junit", newJUnitFormatter)
+	r.Register("markdown", newMarkdownFormatter)
+	r.Register("html", newHTMLFormatter)
+	r.Register("csv", newCSVFormatter)
+	r.Register("ndjson", newNDJSONFormatter)
+	r.Register("template", newTemplateFormatter)
+
+	return r
+}
+
+// Register adds a format, replacing any format with the same name.
+func (r *Registry) Register(name string, factory Factory) {
+	r.factories[name] = factory
+}
+
+// New creates the formatter registered under name.
+func (r *Registry) New(name string, opts Options) (Formatter, error) {
+	factory, ok := r.factories[name]
+	if !ok {
+		return nil, fmt.Errorf("unsupported output format %q (supported: %v)", name, r.Names())
+	}
+
+	if opts.Logger == nil {
+		opts.Logger = &flog.Logger{Logger: zap.NewNop()}
+	}
+	return factory(opts)
+}
+
+// Names returns the registered format names, sorted.
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.factories))
+	for name := range r.factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,222 @@
+package output
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	flog "firefly-ec2-drift-detector/logger"
+	"firefly-ec2-drift-detector/models"
+	"firefly-ec2-drift-detector/service"
+)
+
+func newTestRun() *Run {
+	return &Run{
+		Reports: []*models.DriftReport{
+			{
+				InstanceID:   "i-1",
+				ResourceType: models.ResourceTypeInstance,
+				Address:      "aws_instance.web",
+				HasDrift:     true,
+				Severity:     models.SeverityHigh,
+				CheckedAttrs: []string{"InstanceType", "Tags"},
+				Source:       &models.SourceLocation{File: "main.tf", StartLine: 3, EndLine: 9},
+				Drifts: []models.AttributeDrift{
+					{AttributeName: "InstanceType", ExpectedValue: "t3.micro", ActualValue: "t3.large", DriftType: models.DriftTypeValueMismatch, Severity: models.SeverityHigh},
+					{AttributeName: "Tags.Owner", ExpectedValue: "alice", ActualValue: "bob", DriftType: models.DriftTypeValueMismatch, Severity: models.SeverityLow},
+				},
+			},
+			{
+				InstanceID:   "i-2",
+				ResourceType: models.ResourceTypeInstance,
+				CheckedAttrs: []string{"InstanceType", "Tags"},
+			},
+		},
+		Failures: []*service.ResourceError{
+			{ResourceType: models.ResourceTypeInstance, ResourceID: "i-3", Err: errors.New("boom")},
+		},
+		ID:          "0b6f2c1e-3f4a-4d5b-9c6d-7e8f9a0b1c2d",
+		ToolVersion: "1.0.0",
+		StatePath:   "terraform.tfstate",
+		Region:      "us-east-1",
+		Attributes:  []string{"InstanceType", "Tags"},
+		Started:     time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Finished:    time.Date(2024, 1, 2, 3, 4, 9, 0, time.UTC),
+	}
+}
+
+func format(t *testing.T, name string, run *Run, opts Options) string {
+	t.Helper()
+
+	opts.Logger = flog.NewTestLogger()
+	formatter, err := NewRegistry().New(name, opts)
+	if err != nil {
+		t.Fatalf("failed to create %s formatter: %v", name, err)
+	}
+
+	var buf bytes.Buffer
+	if err := formatter.Format(&buf, run); err != nil {
+		t.Fatalf("failed to format %s: %v", name, err)
+	}
+	return buf.String()
+}
+
+func TestRegistry(t *testing.T) {
+	registry := NewRegistry()
+
+	want := []string{"csv", "html", "json", "junit", "markdown", "ndjson", "sarif", "template", "text"}
+	if got := registry.Names(); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("Names() = %v, want %v", got, want)
+	}
+
+	if _, err := registry.New("yaml", Options{}); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+
+	registry.Register("count", func(opts Options) (Formatter, error) {
+		return formatterFunc(func(w io.Writer, run *Run) error {
+			_, err := io.WriteString(w, "2 reports")
+			return err
+		}), nil
+	})
+	if _, err := registry.New("count", Options{}); err != nil {
+		t.Errorf("expected a registered format to be available: %v", err)
+	}
+}
+
+type formatterFunc func(w io.Writer, run *Run) error
+
+func (f formatterFunc) Format(w io.Writer, run *Run) error {
+	return f(w, run)
+}
+
+func TestParseSpecs(t *testing.T) {
+	specs, err := ParseSpecs([]string{"text,json=report.json", " sarif = drift.sarif "}, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []Spec{{Format: "text"}, {Format: "json", Path: "report.json"}, {Format: "sarif", Path: "drift.sarif"}}
+	if len(specs) != len(want) {
+		t.Fatalf("expected %d specs, got %+v", len(want), specs)
+	}
+	for i := range want {
+		if specs[i] != want[i] {
+			t.Errorf("specs[%d] = %+v, want %+v", i, specs[i], want[i])
+		}
+	}
+
+	for _, values := range [][]string{
+		{"text,json"},
+		{"json=a.json,csv=a.json"},
+		{"=report.json"},
+		{""},
+	} {
+		if _, err := ParseSpecs(values, ""); err == nil {
+			t.Errorf("expected an error for %q", values)
+		}
+	}
+
+	// -o applies to outputs without a file, which then share it.
+	specs, err = ParseSpecs([]string{"text,json=report.json"}, "out.txt")
+	if err != nil || specs[0].Path != "out.txt" || specs[1].Path != "report.json" {
+		t.Errorf("expected text to go to the default path, got %+v, %v", specs, err)
+	}
+	if _, err := ParseSpecs([]string{"json,sarif"}, "out"); err == nil {
+		t.Error("expected an error for two outputs sharing the default path")
+	}
+}
+
+func TestSet_StreamsAndWritesFiles(t *testing.T) {
+	dir := t.TempDir()
+	jsonPath := filepath.Join(dir, "report.json")
+	ndjsonPath := filepath.Join(dir, "report.ndjson")
+
+	var stdout bytes.Buffer
+	specs := []Spec{{Format: "csv"}, {Format: "json", Path: jsonPath}, {Format: "ndjson", Path: ndjsonPath}}
+
+	set, err := Open(NewRegistry(), specs, &stdout, Options{Logger: flog.NewTestLogger()})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !set.Streaming() {
+		t.Fatal("expected the ndjson output to stream")
+	}
+
+	run := newTestRun()
+	for _, report := range run.Reports {
+		set.WriteReport(report)
+	}
+	if err := set.Write(run); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := set.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !strings.HasPrefix(stdout.String(), strings.Join(csvHeader, ",")) {
+		t.Errorf("expected CSV on stdout, got %q", stdout.String())
+	}
+
+	data, err := os.ReadFile(jsonPath)
+	if err != nil || !strings.Contains(string(data), `"resource_id": "i-1"`) {
+		t.Errorf("expected the JSON report in %s, got %q (%v)", jsonPath, data, err)
+	}
+
+	data, err = os.ReadFile(ndjsonPath)
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", ndjsonPath, err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 2 reports and 1 failure, each written once, got %d lines:\n%s", len(lines), data)
+	}
+	if !strings.Contains(lines[2], `"message":"boom"`) {
+		t.Errorf("expected the error last, got %s", lines[2])
+	}
+}
+
+func TestOpen_UnknownFormatCreatesNoFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report.json")
+
+	_, err := Open(NewRegistry(), []Spec{{Format: "json", Path: path}, {Format: "yaml"}}, io.Discard, Options{})
+	if err == nil {
+		t.Fatal("expected an error for an unknown format")
+	}
+	if _, statErr := os.Stat(path); !os.IsNotExist(statErr) {
+		t.Errorf("expected %s not to be created", path)
+	}
+}
+
+func TestSnakeCase(t *testing.T) {
+	for name, want := range map[string]string{
+		"VolumeSize":              "volume_size",
+		"KmsKeyID":                "kms_key_id",
+		"HttpPutResponseHopLimit": "http_put_response_hop_limit",
+		"Iops":                    "iops",
+		"ID":                      "id",
+	} {
+		if got := snakeCase(name); got != want {
+			t.Errorf("snakeCase(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestRunSummary(t *testing.T) {
+	summary := newTestRun().Summary()
+
+	if summary.Resources != 2 || summary.Drifted != 1 || summary.Drifts != 2 || summary.NotChecked != 1 {
+		t.Errorf("unexpected counts: %+v", summary)
+	}
+	if summary.Severity != models.SeverityHigh {
+		t.Errorf("expected the highest severity to be high, got %s", summary.Severity)
+	}
+	if got := summary.SeverityBreakdown(); got != "1 high, 1 low" {
+		t.Errorf("SeverityBreakdown() = %q", got)
+	}
+}
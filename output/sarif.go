@@ -0,0 +1,294 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	flog "firefly-ec2-drift-detector/logger"
+	"firefly-ec2-drift-detector/models"
+)
+
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+)
+
+// SARIF 2.1.0, limited to the properties code scanning uses.
+type (
+	sarifLog struct {
+		Schema  string     `json:"$schema"`
+		Version string     `json:"version"`
+		Runs    []sarifRun `json:"runs"`
+	}
+
+	sarifRun struct {
+		Tool    sarifTool     `json:"tool"`
+		Results []sarifResult `json:"results"`
+	}
+
+	sarifTool struct {
+		Driver sarifDriver `json:"driver"`
+	}
+
+	sarifDriver struct {
+		Name           string      `json:"name"`
+		Version        string      `json:"version,omitempty"`
+		InformationURI string      `json:"informationUri,omitempty"`
+		Rules          []sarifRule `json:"rules"`
+	}
+
+	sarifRule struct {
+		ID                   string             `json:"id"`
+		Name                 string             `json:"name"`
+		ShortDescription     sarifMessage       `json:"shortDescription"`
+		DefaultConfiguration sarifConfiguration `json:"defaultConfiguration"`
+		Properties           sarifProperties    `json:"properties,omitempty"`
+	}
+
+	sarifConfiguration struct {
+		Level string `json:"level"`
+	}
+
+	sarifProperties map[string]interface{}
+
+	sarifMessage struct {
+		Text string `json:"text"`
+	}
+
+	sarifResult struct {
+		RuleID              string            `json:"ruleId"`
+		RuleIndex           int               `json:"ruleIndex"`
+		Level               string            `json:"level"`
+		Message             sarifMessage      `json:"message"`
+		Locations           []sarifLocation   `json:"locations"`
+		PartialFingerprints map[string]string `json:"partialFingerprints,omitempty"`
+	}
+
+	sarifLocation struct {
+		PhysicalLocation *sarifPhysicalLocation `json:"physicalLocation,omitempty"`
+		LogicalLocations []sarifLogicalLocation `json:"logicalLocations,omitempty"`
+	}
+
+	sarifPhysicalLocation struct {
+		ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+		Region           *sarifRegion          `json:"region,omitempty"`
+	}
+
+	sarifArtifactLocation struct {
+		URI string `json:"uri"`
+	}
+
+	sarifRegion struct {
+		StartLine int `json:"startLine"`
+		EndLine   int `json:"endLine,omitempty"`
+	}
+
+	sarifLogicalLocation struct {
+		Name               string `json:"name"`
+		FullyQualifiedName string `json:"fullyQualifiedName"`
+		Kind               string `json:"kind"`
+	}
+)
+
+// selectorPattern matches bracketed map keys and list indexes, such as the
+// member IDs in Instances["i-0abc"].InstanceType, in attribute paths of types
+// without an attribute registry.
+var selectorPattern = regexp.MustCompile(`\[[^\]]*\]`)
+
+type sarifFormatter struct {
+	logger *flog.Logger
+}
+
+func newSARIFFormatter(opts Options) (Formatter, error) {
+	return &sarifFormatter{logger: opts.Logger}, nil
+}
+
+func (f *sarifFormatter) Format(w io.Writer, run *Run) error {
+	f.logger.Debug("formatting output as SARIF")
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(buildSARIF(run.Reports, run.StatePath, run.ToolVersion)); err != nil {
+		return fmt.Errorf("failed to encode SARIF output: %w", err)
+	}
+
+	return nil
+}
+
+// buildSARIF emits one rule per resource type, attribute and drift type, and
+// one result per drift. Results point at the declaring .tf block when the
+// resource was parsed from HCL, and at the state file otherwise.
+func buildSARIF(reports []*models.DriftReport, statePath, toolVersion string) *sarifLog {
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           "firefly",
+			Version:        toolVersion,
+			InformationURI: "https://github.com/tejiriaustin/firefly-ec2-drift-detector",
+			Rules:          []sarifRule{},
+		}},
+		Results: []sarifResult{},
+	}
+
+	var (
+		ruleIndex    = make(map[string]int)
+		ruleSeverity []models.Severity
+	)
+
+	for _, report := range reports {
+		resourceType := report.ResourceType
+		if resourceType == "" {
+			resourceType = models.ResourceTypeInstance
+		}
+
+		for _, drift := range report.Drifts {
+			attribute := ruleAttribute(resourceType, drift.AttributeName)
+			ruleID := fmt.Sprintf("%s/%s/%s", resourceType, attribute, drift.DriftType)
+			level := sarifLevel(drift.Severity)
+
+			index, ok := ruleIndex[ruleID]
+			if !ok {
+				index = len(run.Tool.Driver.Rules)
+				ruleIndex[ruleID] = index
+				ruleSeverity = append(ruleSeverity, "")
+				run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{
+					ID:   ruleID,
+					Name: sarifRuleName(attribute, drift.DriftType),
+					ShortDescription: sarifMessage{
+						Text: fmt.Sprintf("%s drift (%s) on %s", attribute, drift.DriftType, resourceType),
+					},
+				})
+			}
+
+			// A rule is rated by the most severe of its results.
+			if ruleSeverity[index] == "" || !ruleSeverity[index].AtLeast(drift.Severity) {
+				ruleSeverity[index] = drift.Severity
+				rule := &run.Tool.Driver.Rules[index]
+				rule.DefaultConfiguration = sarifConfiguration{Level: level}
+				rule.Properties = sarifRuleProperties(drift.Severity)
+			}
+
+			run.Results = append(run.Results, sarifResult{
+				RuleID:    ruleID,
+				RuleIndex: index,
+				Level:     level,
+				Message:   sarifMessage{Text: sarifResultMessage(report, drift)},
+				Locations: []sarifLocation{sarifResourceLocation(report, resourceType, statePath)},
+				PartialFingerprints: map[string]string{
+					"driftFingerprint/v1": fmt.Sprintf("%s/%s/%s", resourceType, report.InstanceID, drift.AttributeName),
+				},
+			})
+		}
+	}
+
+	return &sarifLog{
+		Schema:  sarifSchema,
+		Version: sarifVersion,
+		Runs:    []sarifRun{run},
+	}
+}
+
+// ruleAttribute is the attribute a drift is filed under in rule IDs. Map keys
+// and list indexes are left out, so that Tags.Owner and Tags.Team share the
+// Tags rule of a resource type, and so do the members of an Auto Scaling
+// group.
+func ruleAttribute(resourceType, name string) string {
+	if registry := models.AttributeRegistryFor(resourceType); registry != nil {
+		if ref, err := registry.Lookup(name); err == nil {
+			return ref.Field
+		}
+	}
+	return selectorPattern.ReplaceAllString(name, "")
+}
+
+// sarifLevel maps severities onto the three SARIF levels code scanning shows.
+func sarifLevel(severity models.Severity) string {
+	switch severity {
+	case models.SeverityCritical, models.SeverityHigh:
+		return "error"
+	case models.SeverityLow, models.SeverityInfo:
+		return "note"
+	default:
+		return "warning"
+	}
+}
+
+// sarifRuleProperties sets the security-severity score GitHub uses to rank
+// alerts, on the same scale as CVSS.
+func sarifRuleProperties(severity models.Severity) sarifProperties {
+	scores := map[models.Severity]string{
+		models.SeverityCritical: "9.5",
+		models.SeverityHigh:     "8.0",
+		models.SeverityMedium:   "5.5",
+		models.SeverityLow:      "3.0",
+		models.SeverityInfo:     "1.0",
+	}
+
+	score, ok := scores[severity]
+	if !ok {
+		return nil
+	}
+	return sarifProperties{"security-severity": score, "tags": []string{"drift", "terraform"}}
+}
+
+func sarifRuleName(attribute string, driftType models.DriftType) string {
+	var name strings.Builder
+	for _, part := range strings.FieldsFunc(attribute+"_"+strings.ToLower(string(driftType)), func(r rune) bool {
+		return r == '.' || r == '_'
+	}) {
+		name.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return name.String()
+}
+
+func sarifResultMessage(report *models.DriftReport, drift models.AttributeDrift) string {
+	message := fmt.Sprintf("%s %s: %s drifted (%s). Expected %s, actual %s.",
+		resourceLabel(report), report.InstanceID, drift.AttributeName, drift.DriftType,
+		formatValue(drift.ExpectedValue), formatValue(drift.ActualValue))
+	if drift.Details != "" {
+		message += " " + drift.Details
+	}
+	return message
+}
+
+func sarifResourceLocation(report *models.DriftReport, resourceType, statePath string) sarifLocation {
+	location := sarifLocation{
+		LogicalLocations: []sarifLogicalLocation{{
+			Name:               report.InstanceID,
+			FullyQualifiedName: resourceType + "." + report.InstanceID,
+			Kind:               "resource",
+		}},
+	}
+
+	switch {
+	case report.Source != nil:
+		location.PhysicalLocation = &sarifPhysicalLocation{
+			ArtifactLocation: sarifArtifactLocation{URI: sarifURI(report.Source.File)},
+			Region:           &sarifRegion{StartLine: report.Source.StartLine, EndLine: report.Source.EndLine},
+		}
+	case statePath != "":
+		location.PhysicalLocation = &sarifPhysicalLocation{
+			ArtifactLocation: sarifArtifactLocation{URI: sarifURI(statePath)},
+		}
+	}
+
+	return location
+}
+
+// sarifURI makes paths relative to the working directory, which code
+// scanning resolves against the repository root.
+func sarifURI(path string) string {
+	if filepath.IsAbs(path) {
+		if wd, err := os.Getwd(); err == nil {
+			if rel, err := filepath.Rel(wd, path); err == nil && !strings.HasPrefix(rel, "..") {
+				path = rel
+			}
+		}
+	}
+	return filepath.ToSlash(filepath.Clean(path))
+}
@@ -0,0 +1,123 @@
+package output
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"go.uber.org/zap"
+
+	flog "firefly-ec2-drift-detector/logger"
+	"firefly-ec2-drift-detector/models"
+)
+
+// Set writes one run to several outputs at once, streaming reports to the
+// formatters that support it.
+type Set struct {
+	outputs []*destination
+	logger  *flog.Logger
+}
+
+type destination struct {
+	spec      Spec
+	formatter Formatter
+	w         io.Writer
+	file      *os.File // nil for stdout
+}
+
+// Open creates a formatter for every spec and opens its file. stdout is used
+// for specs without a path. Nothing is opened if any formatter cannot be
+// created.
+func Open(registry *Registry, specs []Spec, stdout io.Writer, opts Options) (*Set, error) {
+	set := &Set{logger: opts.Logger}
+	if set.logger == nil {
+		set.logger = &flog.Logger{Logger: zap.NewNop()}
+	}
+
+	for _, spec := range specs {
+		formatter, err := registry.New(spec.Format, opts)
+		if err != nil {
+			return nil, err
+		}
+		set.outputs = append(set.outputs, &destination{spec: spec, formatter: formatter, w: stdout})
+	}
+
+	for _, out := range set.outputs {
+		if out.spec.Path == "" {
+			continue
+		}
+
+		f, err := os.Create(out.spec.Path)
+		if err != nil {
+			set.Close()
+			return nil, fmt.Errorf("failed to create output file: %w", err)
+		}
+		out.file, out.w = f, f
+	}
+
+	return set, nil
+}
+
+// Streaming reports whether any output writes reports as they complete.
+func (s *Set) Streaming() bool {
+	for _, out := range s.outputs {
+		if _, ok := out.formatter.(StreamingFormatter); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// WriteReport streams a completed report to every StreamingFormatter. It
+// suits service.DetectRequest.OnReport; errors are logged rather than
+// aborting the scan.
+func (s *Set) WriteReport(report *models.DriftReport) {
+	for _, out := range s.outputs {
+		streaming, ok := out.formatter.(StreamingFormatter)
+		if !ok {
+			continue
+		}
+		if err := streaming.WriteReport(out.w, report); err != nil {
+			s.logger.Error("failed to stream report",
+				zap.String("format", out.spec.Format),
+				zap.String("resource_id", report.InstanceID),
+				zap.Error(err),
+			)
+		}
+	}
+}
+
+// Write renders the run to every output. Streaming outputs only write what
+// WriteReport has not, so they must have been fed every report in run.
+func (s *Set) Write(run *Run) error {
+	var errs []error
+	for _, out := range s.outputs {
+		var err error
+		if streaming, ok := out.formatter.(StreamingFormatter); ok {
+			err = streaming.Finish(out.w, run)
+		} else {
+			err = out.formatter.Format(out.w, run)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s output: %w", out.spec.Format, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close closes the output files.
+func (s *Set) Close() error {
+	var errs []error
+	for _, out := range s.outputs {
+		if out.file == nil {
+			continue
+		}
+		if err := out.file.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to write output file %s: %w", out.spec.Path, err))
+			continue
+		}
+		s.logger.Info("report written", zap.String("path", out.spec.Path), zap.String("format", out.spec.Format))
+	}
+	return errors.Join(errs...)
+}
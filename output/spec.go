@@ -0,0 +1,55 @@
+package output
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Spec is one requested output: a format and the file it goes to, or stdout
+// when Path is empty.
+type Spec struct {
+	Format string
+	Path   string
+}
+
+// ParseSpecs parses -f values such as "text" or "text,json=report.json".
+// Outputs without a file of their own write to defaultPath, or to stdout when
+// it is empty. Each destination may only be used once.
+func ParseSpecs(values []string, defaultPath string) ([]Spec, error) {
+	var specs []Spec
+	seen := make(map[string]string)
+
+	for _, value := range values {
+		for _, entry := range strings.Split(value, ",") {
+			entry = strings.TrimSpace(entry)
+			if entry == "" {
+				continue
+			}
+
+			format, path, _ := strings.Cut(entry, "=")
+			spec := Spec{Format: strings.TrimSpace(format), Path: strings.TrimSpace(path)}
+			if spec.Format == "" {
+				return nil, fmt.Errorf("invalid output %q: missing format", entry)
+			}
+			if spec.Path == "" {
+				spec.Path = defaultPath
+			}
+
+			dest := spec.Path
+			if dest == "" {
+				dest = "stdout"
+			}
+			if other, ok := seen[dest]; ok {
+				return nil, fmt.Errorf("outputs %q and %q both write to %s", other, spec.Format, dest)
+			}
+			seen[dest] = spec.Format
+
+			specs = append(specs, spec)
+		}
+	}
+
+	if len(specs) == 0 {
+		return nil, fmt.Errorf("no output format given")
+	}
+	return specs, nil
+}
@@ -0,0 +1,66 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	flog "firefly-ec2-drift-detector/logger"
+	"firefly-ec2-drift-detector/models"
+	"firefly-ec2-drift-detector/service"
+)
+
+// templateFuncs are available to custom templates and to the HTML report.
+var templateFuncs = map[string]interface{}{
+	"label":     resourceLabel,
+	"status":    getDriftStatus,
+	"value":     formatValue,
+	"keyChange": formatKeyChange,
+	"source":    sourceLabel,
+	"failed": func(f *service.ResourceError) string {
+		return junitClassName(f.ResourceType, f.ResourceID)
+	},
+	"severities": func() []models.Severity {
+		return severities
+	},
+	"join":  strings.Join,
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+}
+
+// templateFormatter executes a user-supplied text/template with the Run as
+// its data.
+type templateFormatter struct {
+	tmpl   *template.Template
+	logger *flog.Logger
+}
+
+func newTemplateFormatter(opts Options) (Formatter, error) {
+	if opts.TemplatePath == "" {
+		return nil, fmt.Errorf("the template format needs a template file (--template)")
+	}
+
+	data, err := os.ReadFile(opts.TemplatePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template: %w", err)
+	}
+
+	tmpl, err := template.New(filepath.Base(opts.TemplatePath)).Funcs(templateFuncs).Parse(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template %s: %w", opts.TemplatePath, err)
+	}
+
+	return &templateFormatter{tmpl: tmpl, logger: opts.Logger}, nil
+}
+
+func (f *templateFormatter) Format(w io.Writer, run *Run) error {
+	f.logger.Debug("formatting output with custom template")
+
+	if err := f.tmpl.Execute(w, run); err != nil {
+		return fmt.Errorf("failed to execute template: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"go.uber.org/zap"
+
+	flog "firefly-ec2-drift-detector/logger"
+	"firefly-ec2-drift-detector/models"
+)
+
+// textFormatter prints the human-readable report the CLI shows by default.
+type textFormatter struct {
+	logger *flog.Logger
+}
+
+func newTextFormatter(opts Options) (Formatter, error) {
+	return &textFormatter{logger: opts.Logger}, nil
+}
+
+func (f *textFormatter) Format(w io.Writer, run *Run) error {
+	f.logger.Debug("formatting output as text")
+
+	reports := run.Reports
+
+	fmt.Fprintf(w, "\n")
+	fmt.Fprintf(w, "╔═══════════════════════════════════════════════════════════╗\n")
+	fmt.Fprintf(w, "║           FIREFLY DRIFT DETECTION REPORT                  ║\n")
+	fmt.Fprintf(w, "╚═══════════════════════════════════════════════════════════╝\n")
+	fmt.Fprintf(w, "\n")
+
+	if len(reports) == 0 {
+		fmt.Fprintf(w, "No instances were checked.\n\n")
+		return nil
+	}
+
+	noun := "instances"
+	totalDrifts := 0
+	for _, report := range reports {
+		label := resourceLabel(report)
+		if label != "Instance" {
+			noun = "resources"
+		}
+
+		fmt.Fprintf(w, "%s: %s\n", label, report.InstanceID)
+		fmt.Fprintf(w, "Status: %s\n", getDriftStatus(report))
+
+		if report.HasDrift {
+			totalDrifts++
+			fmt.Fprintf(w, "Drifted Attributes (%d):\n", len(report.Drifts))
+			for _, drift := range report.Drifts {
+				fmt.Fprintf(w, "  • %s:\n", drift.AttributeName)
+				fmt.Fprintf(w, "    Expected: %v\n", formatValue(drift.ExpectedValue))
+				fmt.Fprintf(w, "    Actual:   %v\n", formatValue(drift.ActualValue))
+				fmt.Fprintf(w, "    Type:     %s\n", drift.DriftType)
+				if drift.Severity != "" {
+					fmt.Fprintf(w, "    Severity: %s\n", drift.Severity)
+				}
+				if len(drift.Added) > 0 {
+					fmt.Fprintf(w, "    Added:    %s\n", formatValue(drift.Added))
+				}
+				if len(drift.Removed) > 0 {
+					fmt.Fprintf(w, "    Removed:  %s\n", formatValue(drift.Removed))
+				}
+				if len(drift.Changes) > 0 {
+					fmt.Fprintf(w, "    Changes:\n")
+					for _, change := range drift.Changes {
+						fmt.Fprintf(w, "      %s\n", formatKeyChange(change, strings.ToLower(label)))
+					}
+				}
+			}
+		}
+		if len(report.IgnoredTags) > 0 {
+			fmt.Fprintf(w, "Ignored Tags: %s\n", strings.Join(report.IgnoredTags, ", "))
+		}
+		if len(report.Suppressed) > 0 {
+			fmt.Fprintf(w, "Suppressed by baseline: %s\n", suppressedLabel(report.Suppressed))
+		}
+		fmt.Fprintln(w)
+	}
+
+	fmt.Fprintf(w, "───────────────────────────────────────────────────────────\n")
+	fmt.Fprintf(w, "Summary: %d/%d %s have drift\n", totalDrifts, len(reports), noun)
+	fmt.Fprintf(w, "\n")
+
+	f.logger.Info("drift report generated",
+		zap.Int("total_instances", len(reports)),
+		zap.Int("instances_with_drift", totalDrifts),
+	)
+
+	return nil
+}
+
+// suppressedLabel lists suppressed attributes with their reasons, such as
+// "InstanceType (resize pending), Tags.Owner".
+func suppressedLabel(suppressed []models.SuppressedDrift) string {
+	parts := make([]string, len(suppressed))
+	for i, drift := range suppressed {
+		parts[i] = drift.AttributeName
+		if drift.Reason != "" {
+			parts[i] += " (" + drift.Reason + ")"
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+func getDriftStatus(report *models.DriftReport) string {
+	switch {
+	case !report.HasDrift:
+		return "✓ NO DRIFT"
+	case report.Severity != "":
+		return fmt.Sprintf("⚠  DRIFT DETECTED (%s)", report.Severity)
+	default:
+		return "⚠  DRIFT DETECTED"
+	}
+}
+
+func resourceLabel(report *models.DriftReport) string {
+	switch report.ResourceType {
+	case models.ResourceTypeSecurityGroup:
+		return "Security Group"
+	case models.ResourceTypeVolume:
+		return "Volume"
+	case models.ResourceTypeAutoScalingGroup:
+		return "Auto Scaling Group"
+	default:
+		return "Instance"
+	}
+}
+
+func formatKeyChange(change models.KeyChange, resource string) string {
+	switch change.Change {
+	case models.KeyChangeAdded:
+		return fmt.Sprintf("+ %s = %q (not in terraform)", change.Key, formatValue(change.Actual))
+	case models.KeyChangeRemoved:
+		return fmt.Sprintf("- %s = %q (missing from %s)", change.Key, formatValue(change.Expected), resource)
+	default:
+		return fmt.Sprintf("~ %s: %q → %q", change.Key, formatValue(change.Expected), formatValue(change.Actual))
+	}
+}
+
+func formatValue(v interface{}) string {
+	switch val := v.(type) {
+	case []string:
+		return fmt.Sprintf("[%s]", strings.Join(val, ", "))
+	case map[string]string:
+		return fmt.Sprintf("%v", val)
+	default:
+		return fmt.Sprintf("%v", val)
+	}
+}
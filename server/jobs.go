@@ -0,0 +1,177 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"firefly-ec2-drift-detector/models"
+	"firefly-ec2-drift-detector/output"
+	"firefly-ec2-drift-detector/service"
+)
+
+// Job statuses, in the order a job goes through them.
+const (
+	StatusQueued    = "queued"
+	StatusRunning   = "running"
+	StatusSucceeded = "succeeded" // possibly with resources not checked
+	StatusFailed    = "failed"
+)
+
+type (
+	// ScanRequest is the body of POST /v1/scans.
+	ScanRequest struct {
+		// State is a state file or .tf directory, relative to the server's
+		// state directory.
+		State string `json:"state"`
+		// Instances restricts aws_instance to these IDs; empty checks every
+		// instance in the state.
+		Instances []string `json:"instances,omitempty"`
+		// Attributes are the aws_instance attributes to compare, as for -a;
+		// empty compares InstanceType.
+		Attributes []string `json:"attributes,omitempty"`
+		// ResourceTypes defaults to aws_instance.
+		ResourceTypes []string `json:"resource_types,omitempty"`
+	}
+
+	// Job is a scan requested through the API, as returned by GET
+	// /v1/scans/{id}. Its ID is the run ID of its report.
+	Job struct {
+		ID         string                  `json:"id"`
+		Status     string                  `json:"status"`
+		Request    ScanRequest             `json:"request"`
+		CreatedAt  time.Time               `json:"created_at"`
+		StartedAt  time.Time               `json:"started_at,omitzero"`
+		FinishedAt time.Time               `json:"finished_at,omitzero"`
+		Error      string                  `json:"error,omitempty"`
+		Summary    *output.DocumentSummary `json:"summary,omitempty"`
+
+		statePath string
+		report    *output.Document
+	}
+
+	// LatestResource is the most recent result for one resource, as returned
+	// by GET /v1/instances/{id}.
+	LatestResource struct {
+		RunID     string    `json:"run_id"`
+		CheckedAt time.Time `json:"checked_at"`
+		output.DocumentResource
+	}
+)
+
+// worker runs queued jobs until ctx is cancelled.
+func (s *Server) worker(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case job := <-s.queue:
+			s.runJob(ctx, job)
+		}
+	}
+}
+
+func (s *Server) runJob(ctx context.Context, job *Job) {
+	s.mu.Lock()
+	job.Status = StatusRunning
+	job.StartedAt = s.now()
+	req := job.Request
+	s.mu.Unlock()
+
+	s.logger.Info("scan started", zap.String("job_id", job.ID), zap.String("state", req.State))
+
+	ctx, cancel := context.WithTimeout(ctx, s.scanTimeout)
+	defer cancel()
+
+	reports, err := s.scanner.DetectResources(ctx, job.statePath, service.DetectRequest{
+		Types: req.ResourceTypes,
+		IDs: map[string][]string{
+			models.ResourceTypeInstance: req.Instances,
+		},
+		Attributes: map[string][]string{
+			models.ResourceTypeInstance: req.Attributes,
+		},
+	})
+	if ctx.Err() == context.DeadlineExceeded {
+		err = fmt.Errorf("scan timed out after %s", s.scanTimeout)
+		reports = nil
+	}
+
+	finished := s.now()
+	if err != nil && len(reports) == 0 {
+		s.logger.Error("scan failed", zap.String("job_id", job.ID), zap.Error(err))
+		if s.metrics != nil {
+			s.metrics.ScanFailed()
+		}
+
+		s.mu.Lock()
+		job.Status = StatusFailed
+		job.FinishedAt = finished
+		job.Error = err.Error()
+		s.mu.Unlock()
+		return
+	}
+
+	run := &output.Run{
+		ID:          job.ID,
+		ToolVersion: s.version,
+		Reports:     reports,
+		Failures:    service.Failures(err),
+		StatePath:   req.State,
+		Region:      s.region,
+		Attributes:  req.Attributes,
+		Started:     job.StartedAt,
+		Finished:    finished,
+	}
+	doc := output.NewDocument(run)
+	if s.metrics != nil {
+		s.metrics.ObserveScan(run)
+	}
+
+	// The job finishes once the hook is done with its run.
+	if s.onRun != nil {
+		s.runHookMu.Lock()
+		s.onRun(run)
+		s.runHookMu.Unlock()
+	}
+
+	s.mu.Lock()
+	job.Status = StatusSucceeded
+	job.FinishedAt = finished
+	job.Summary = &doc.Summary
+	job.report = doc
+	for _, resource := range doc.Resources {
+		s.latest[resourceKey(resource.ResourceType, resource.ResourceID)] = &LatestResource{
+			RunID:            job.ID,
+			CheckedAt:        finished,
+			DocumentResource: resource,
+		}
+	}
+	s.mu.Unlock()
+
+	s.logger.Info("scan finished", zap.String("job_id", job.ID),
+		zap.Int("resources", doc.Summary.ResourcesChecked), zap.Int("drifted", doc.Summary.ResourcesDrifted))
+}
+
+// forget drops the oldest finished jobs beyond the retention limit. Callers
+// hold s.mu.
+func (s *Server) forget() {
+	kept := s.order[:0]
+	excess := len(s.order) - s.maxJobs
+	for _, id := range s.order {
+		job := s.jobs[id]
+		if excess > 0 && (job.Status == StatusSucceeded || job.Status == StatusFailed) {
+			delete(s.jobs, id)
+			excess--
+			continue
+		}
+		kept = append(kept, id)
+	}
+	s.order = kept
+}
+
+func resourceKey(resourceType, resourceID string) string {
+	return resourceType + "/" + resourceID
+}
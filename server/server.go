@@ -0,0 +1,383 @@
+// Package server exposes drift detection over HTTP. Scans are submitted as
+// jobs, run in the background by a fixed number of workers, and their reports
+// are served in the JSON report schema of the output package.
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	flog "firefly-ec2-drift-detector/logger"
+	"firefly-ec2-drift-detector/metrics"
+	"firefly-ec2-drift-detector/models"
+	"firefly-ec2-drift-detector/output"
+	"firefly-ec2-drift-detector/service"
+)
+
+const (
+	defaultScanTimeout    = 10 * time.Minute
+	defaultRequestTimeout = 30 * time.Second
+	defaultWorkers        = 2
+	defaultQueueSize      = 100
+	defaultMaxJobs        = 1000
+
+	// maxRequestBody bounds the body of POST /v1/scans.
+	maxRequestBody = 1 << 20
+	// shutdownTimeout bounds how long Run waits for requests in flight.
+	shutdownTimeout = 10 * time.Second
+)
+
+type (
+	// Scanner runs one scan. *service.DriftService implements it.
+	Scanner interface {
+		DetectResources(ctx context.Context, tfStatePath string, req service.DetectRequest) ([]*models.DriftReport, error)
+		// ResourceTypes returns the resource types scans can check.
+		ResourceTypes() []string
+	}
+
+	// Server runs scans requested over HTTP.
+	Server struct {
+		scanner        Scanner
+		logger         *flog.Logger
+		stateDir       string
+		version        string
+		region         string
+		scanTimeout    time.Duration
+		requestTimeout time.Duration
+		workers        int
+		maxJobs        int
+		onRun          func(run *output.Run)
+		metrics        *metrics.Metrics
+		now            func() time.Time
+
+		queue     chan *Job
+		runHookMu sync.Mutex
+
+		mu     sync.Mutex
+		jobs   map[string]*Job
+		order  []string // job IDs, oldest first
+		latest map[string]*LatestResource
+	}
+
+	// Option configures a Server.
+	Option func(*Server)
+
+	errorResponse struct {
+		Error string `json:"error"`
+	}
+)
+
+// WithStateDir sets the directory that scan requests name state paths in.
+// It defaults to the working directory.
+func WithStateDir(dir string) Option {
+	return func(s *Server) {
+		s.stateDir = dir
+	}
+}
+
+// WithRunInfo sets the tool version and AWS region recorded in reports.
+func WithRunInfo(version, region string) Option {
+	return func(s *Server) {
+		s.version = version
+		s.region = region
+	}
+}
+
+// WithScanTimeout bounds how long one scan may run.
+func WithScanTimeout(timeout time.Duration) Option {
+	return func(s *Server) {
+		s.scanTimeout = timeout
+	}
+}
+
+// WithRequestTimeout bounds how long an HTTP request may take.
+func WithRequestTimeout(timeout time.Duration) Option {
+	return func(s *Server) {
+		s.requestTimeout = timeout
+	}
+}
+
+// WithWorkers sets how many scans run at once.
+func WithWorkers(n int) Option {
+	return func(s *Server) {
+		s.workers = n
+	}
+}
+
+// WithMaxJobs sets how many jobs are kept. The oldest finished jobs are
+// forgotten beyond it.
+func WithMaxJobs(n int) Option {
+	return func(s *Server) {
+		s.maxJobs = n
+	}
+}
+
+// WithMetrics records scans in m and serves it at GET /metrics.
+func WithMetrics(m *metrics.Metrics) Option {
+	return func(s *Server) {
+		s.metrics = m
+	}
+}
+
+// WithRunHook sets a function called with the run of each scan that
+// succeeded, such as to save it to the run history. Calls are not
+// concurrent.
+func WithRunHook(fn func(run *output.Run)) Option {
+	return func(s *Server) {
+		s.onRun = fn
+	}
+}
+
+// New returns a server running scans with scanner. Call Start, or Run, for
+// queued scans to run.
+func New(scanner Scanner, logger *flog.Logger, opts ...Option) *Server {
+	s := &Server{
+		scanner:        scanner,
+		logger:         logger,
+		stateDir:       ".",
+		scanTimeout:    defaultScanTimeout,
+		requestTimeout: defaultRequestTimeout,
+		workers:        defaultWorkers,
+		maxJobs:        defaultMaxJobs,
+		now:            time.Now,
+		queue:          make(chan *Job, defaultQueueSize),
+		jobs:           make(map[string]*Job),
+		latest:         make(map[string]*LatestResource),
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// Start starts the workers, which stop when ctx is cancelled, abandoning
+// the scans they run.
+func (s *Server) Start(ctx context.Context) {
+	for range s.workers {
+		go s.worker(ctx)
+	}
+}
+
+// Run starts the workers and serves the API on addr until ctx is cancelled,
+// then shuts down, letting requests in flight finish.
+func (s *Server) Run(ctx context.Context, addr string) error {
+	s.Start(ctx)
+
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: s.requestTimeout,
+		ReadTimeout:       s.requestTimeout,
+		// Leave the timeout handler time to write its response.
+		WriteTimeout: s.requestTimeout + 5*time.Second,
+		IdleTimeout:  2 * time.Minute,
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- srv.ListenAndServe()
+	}()
+	s.logger.Info("drift API listening", zap.String("addr", addr))
+
+	select {
+	case err := <-errCh:
+		return fmt.Errorf("drift API server failed: %w", err)
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("failed to shut down drift API server: %w", err)
+	}
+	s.logger.Info("drift API stopped")
+	return nil
+}
+
+// Handler returns the API's HTTP handler.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/scans", s.handleCreateScan)
+	mux.HandleFunc("GET /v1/scans/{id}", s.handleGetScan)
+	mux.HandleFunc("GET /v1/scans/{id}/report", s.handleGetReport)
+	mux.HandleFunc("GET /v1/instances/{id}", s.handleGetInstance)
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+	if s.metrics != nil {
+		mux.Handle("GET /metrics", s.metrics.Handler())
+	}
+
+	timeoutBody, _ := json.Marshal(errorResponse{Error: "request timed out"})
+	return http.TimeoutHandler(mux, s.requestTimeout, string(timeoutBody))
+}
+
+func (s *Server) handleCreateScan(w http.ResponseWriter, r *http.Request) {
+	var req ScanRequest
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBody))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid scan request: %w", err))
+		return
+	}
+
+	statePath, err := s.validate(&req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	job := &Job{
+		ID:        output.NewRunID(),
+		Status:    StatusQueued,
+		Request:   req,
+		CreatedAt: s.now(),
+		statePath: statePath,
+	}
+
+	s.mu.Lock()
+	select {
+	case s.queue <- job:
+	default:
+		s.mu.Unlock()
+		writeError(w, http.StatusServiceUnavailable, errors.New("too many scans queued, try again later"))
+		return
+	}
+	s.jobs[job.ID] = job
+	s.order = append(s.order, job.ID)
+	s.forget()
+	response := *job
+	s.mu.Unlock()
+
+	s.logger.Info("scan queued", zap.String("job_id", job.ID), zap.String("state", req.State))
+
+	w.Header().Set("Location", "/v1/scans/"+job.ID)
+	writeJSON(w, http.StatusAccepted, response)
+}
+
+// validate checks a scan request, filling in defaults, and returns the state
+// path it names.
+func (s *Server) validate(req *ScanRequest) (string, error) {
+	if req.State == "" {
+		return "", errors.New("state is required")
+	}
+	if !filepath.IsLocal(req.State) {
+		return "", fmt.Errorf("state %q must be a relative path within the state directory", req.State)
+	}
+	statePath, err := s.resolveState(req.State)
+	if err != nil {
+		return "", err
+	}
+
+	if len(req.Attributes) == 0 {
+		req.Attributes = []string{"InstanceType"}
+	}
+	attrs, err := models.ResolveAttributes(req.Attributes)
+	if err != nil {
+		return "", fmt.Errorf("invalid attributes: %w", err)
+	}
+	req.Attributes = attrs
+
+	if len(req.ResourceTypes) == 0 {
+		req.ResourceTypes = []string{models.ResourceTypeInstance}
+	}
+	supported := s.scanner.ResourceTypes()
+	for _, resourceType := range req.ResourceTypes {
+		if !slices.Contains(supported, resourceType) {
+			return "", fmt.Errorf("unsupported resource type %q (supported: %v)", resourceType, supported)
+		}
+	}
+
+	return statePath, nil
+}
+
+// resolveState returns the path of the state file named by a scan request,
+// with symlinks resolved, once it is known to lie within the state
+// directory.
+func (s *Server) resolveState(state string) (string, error) {
+	dir, err := filepath.EvalSymlinks(s.stateDir)
+	if err != nil {
+		return "", fmt.Errorf("state directory not found: %w", err)
+	}
+	statePath, err := filepath.EvalSymlinks(filepath.Join(dir, state))
+	if err != nil {
+		return "", fmt.Errorf("terraform state not found: %s", state)
+	}
+	if rel, err := filepath.Rel(dir, statePath); err != nil || !filepath.IsLocal(rel) {
+		return "", fmt.Errorf("state %q must be a relative path within the state directory", state)
+	}
+	if _, err := os.Stat(statePath); err != nil {
+		return "", fmt.Errorf("terraform state not found: %s", state)
+	}
+	return statePath, nil
+}
+
+func (s *Server) handleGetScan(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	job, ok := s.jobs[r.PathValue("id")]
+	var response Job
+	if ok {
+		response = *job
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		writeError(w, http.StatusNotFound, errors.New("scan not found"))
+		return
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (s *Server) handleGetReport(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	job, ok := s.jobs[r.PathValue("id")]
+	var status string
+	var report *output.Document
+	if ok {
+		status, report = job.Status, job.report
+	}
+	s.mu.Unlock()
+
+	switch {
+	case !ok:
+		writeError(w, http.StatusNotFound, errors.New("scan not found"))
+	case report == nil:
+		writeError(w, http.StatusConflict, fmt.Errorf("scan has no report: it is %s", status))
+	default:
+		writeJSON(w, http.StatusOK, report)
+	}
+}
+
+func (s *Server) handleGetInstance(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	latest, ok := s.latest[resourceKey(models.ResourceTypeInstance, r.PathValue("id"))]
+	s.mu.Unlock()
+
+	if !ok {
+		writeError(w, http.StatusNotFound, errors.New("instance not checked by any scan"))
+		return
+	}
+	writeJSON(w, http.StatusOK, latest)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
@@ -0,0 +1,256 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	flog "firefly-ec2-drift-detector/logger"
+	"firefly-ec2-drift-detector/metrics"
+	"firefly-ec2-drift-detector/models"
+	"firefly-ec2-drift-detector/output"
+	"firefly-ec2-drift-detector/service"
+)
+
+type fakeScanner struct {
+	reports []*models.DriftReport
+	err     error
+	block   bool // wait for the context to end
+	reqs    chan service.DetectRequest
+}
+
+func (f *fakeScanner) DetectResources(ctx context.Context, tfStatePath string, req service.DetectRequest) ([]*models.DriftReport, error) {
+	if f.reqs != nil {
+		f.reqs <- req
+	}
+	if f.block {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	return f.reports, f.err
+}
+
+func (f *fakeScanner) ResourceTypes() []string {
+	return []string{models.ResourceTypeInstance, models.ResourceTypeVolume}
+}
+
+func newTestServer(t *testing.T, scanner Scanner, opts ...Option) (*Server, *httptest.Server) {
+	t.Helper()
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "terraform.tfstate"), []byte("{}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	s := New(scanner, flog.NewTestLogger(), append([]Option{WithStateDir(dir), WithRunInfo("1.0.0", "us-east-1")}, opts...)...)
+	ctx, cancel := context.WithCancel(context.Background())
+	s.Start(ctx)
+
+	ts := httptest.NewServer(s.Handler())
+	t.Cleanup(func() {
+		ts.Close()
+		cancel()
+	})
+	return s, ts
+}
+
+func doJSON(t *testing.T, method, url, body string, v interface{}) int {
+	t.Helper()
+
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if v != nil {
+		if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+			t.Fatalf("failed to decode %s %s: %v", method, url, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+// waitForJob polls a job until it finishes.
+func waitForJob(t *testing.T, baseURL, id string) Job {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		var job Job
+		if status := doJSON(t, http.MethodGet, baseURL+"/v1/scans/"+id, "", &job); status != http.StatusOK {
+			t.Fatalf("expected 200 for the scan, got %d", status)
+		}
+		if job.Status == StatusSucceeded || job.Status == StatusFailed {
+			return job
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("scan %s did not finish", id)
+	return Job{}
+}
+
+func TestServer_ScanLifecycle(t *testing.T) {
+	report := &models.DriftReport{
+		InstanceID:   "i-1",
+		ResourceType: models.ResourceTypeInstance,
+		CheckedAttrs: []string{"InstanceType"},
+	}
+	report.AddAttributeDrift(models.AttributeDrift{AttributeName: "InstanceType", ExpectedValue: "t3.micro", ActualValue: "t3.large", DriftType: models.DriftTypeValueMismatch})
+
+	scanner := &fakeScanner{
+		reports: []*models.DriftReport{report},
+		err: &service.PartialFailureError{Failures: []*service.ResourceError{
+			{ResourceType: models.ResourceTypeInstance, ResourceID: "i-2", Err: errors.New("boom")},
+		}},
+		reqs: make(chan service.DetectRequest, 1),
+	}
+	var hooked []*output.Run
+	_, ts := newTestServer(t, scanner, WithMetrics(metrics.New()), WithRunHook(func(run *output.Run) { hooked = append(hooked, run) }))
+
+	var job Job
+	status := doJSON(t, http.MethodPost, ts.URL+"/v1/scans", `{"state": "terraform.tfstate", "instances": ["i-1", "i-2"], "attributes": ["instance_type"]}`, &job)
+	if status != http.StatusAccepted || job.ID == "" || job.Status != StatusQueued {
+		t.Fatalf("expected a queued scan, got %d %+v", status, job)
+	}
+
+	req := <-scanner.reqs
+	if got := req.Attributes[models.ResourceTypeInstance]; len(got) != 1 || got[0] != "InstanceType" {
+		t.Errorf("expected attributes to be resolved, got %v", got)
+	}
+	if got := req.IDs[models.ResourceTypeInstance]; len(got) != 2 {
+		t.Errorf("expected the requested instances, got %v", got)
+	}
+
+	job = waitForJob(t, ts.URL, job.ID)
+	if job.Status != StatusSucceeded || job.Summary == nil || job.Summary.ResourcesDrifted != 1 || job.Summary.ResourcesNotChecked != 1 {
+		t.Fatalf("unexpected finished scan: %+v", job)
+	}
+
+	var doc output.Document
+	if status := doJSON(t, http.MethodGet, ts.URL+"/v1/scans/"+job.ID+"/report", "", &doc); status != http.StatusOK {
+		t.Fatalf("expected 200 for the report, got %d", status)
+	}
+	if doc.RunID != job.ID || doc.SchemaVersion != output.SchemaVersion || doc.Region != "us-east-1" || len(doc.Errors) != 1 {
+		t.Errorf("unexpected report: %+v", doc)
+	}
+
+	var latest LatestResource
+	if status := doJSON(t, http.MethodGet, ts.URL+"/v1/instances/i-1", "", &latest); status != http.StatusOK {
+		t.Fatalf("expected 200 for the instance, got %d", status)
+	}
+	if latest.RunID != job.ID || !latest.HasDrift || len(latest.Drifts) != 1 {
+		t.Errorf("unexpected latest result: %+v", latest)
+	}
+	if status := doJSON(t, http.MethodGet, ts.URL+"/v1/instances/i-2", "", nil); status != http.StatusNotFound {
+		t.Errorf("expected 404 for an instance not checked, got %d", status)
+	}
+
+	if len(hooked) != 1 || hooked[0].ID != job.ID {
+		t.Errorf("expected the run hook to get the run, got %+v", hooked)
+	}
+
+	resp, err := http.Get(ts.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if !strings.Contains(string(body), "firefly_scans_total 1") {
+		t.Errorf("expected the scan in the metrics, got:\n%s", body)
+	}
+}
+
+func TestServer_InvalidRequests(t *testing.T) {
+	s, ts := newTestServer(t, &fakeScanner{})
+
+	outside := filepath.Join(t.TempDir(), "secret.tfstate")
+	if err := os.WriteFile(outside, []byte("{}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(s.stateDir, "link.tfstate")); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, body := range map[string]string{
+		"not JSON":          `state`,
+		"unknown field":     `{"state": "terraform.tfstate", "region": "eu-west-1"}`,
+		"no state":          `{}`,
+		"missing state":     `{"state": "other.tfstate"}`,
+		"outside state dir": `{"state": "../terraform.tfstate"}`,
+		"absolute state":    `{"state": "/etc/passwd"}`,
+		"unknown attribute": `{"state": "terraform.tfstate", "attributes": ["Nope"]}`,
+		"symlink outside":   `{"state": "link.tfstate"}`,
+		"unknown type":      `{"state": "terraform.tfstate", "resource_types": ["aws_s3_bucket"]}`,
+	} {
+		var resp errorResponse
+		if status := doJSON(t, http.MethodPost, ts.URL+"/v1/scans", body, &resp); status != http.StatusBadRequest || resp.Error == "" {
+			t.Errorf("%s: expected 400 with an error, got %d %+v", name, status, resp)
+		}
+	}
+
+	if err := os.Symlink("terraform.tfstate", filepath.Join(s.stateDir, "current.tfstate")); err != nil {
+		t.Fatal(err)
+	}
+	if status := doJSON(t, http.MethodPost, ts.URL+"/v1/scans", `{"state": "current.tfstate", "resource_types": ["aws_ebs_volume"]}`, nil); status != http.StatusAccepted {
+		t.Errorf("expected 202 for a symlink within the state directory, got %d", status)
+	}
+
+	if status := doJSON(t, http.MethodGet, ts.URL+"/v1/scans/nope", "", nil); status != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown scan, got %d", status)
+	}
+}
+
+func TestServer_FailedAndTimedOutScans(t *testing.T) {
+	_, ts := newTestServer(t, &fakeScanner{err: errors.New("failed to parse state")})
+
+	var job Job
+	doJSON(t, http.MethodPost, ts.URL+"/v1/scans", `{"state": "terraform.tfstate"}`, &job)
+	job = waitForJob(t, ts.URL, job.ID)
+	if job.Status != StatusFailed || job.Error != "failed to parse state" {
+		t.Errorf("expected a failed scan, got %+v", job)
+	}
+	if status := doJSON(t, http.MethodGet, ts.URL+"/v1/scans/"+job.ID+"/report", "", nil); status != http.StatusConflict {
+		t.Errorf("expected 409 for the report of a failed scan, got %d", status)
+	}
+
+	_, ts = newTestServer(t, &fakeScanner{block: true}, WithScanTimeout(20*time.Millisecond))
+	doJSON(t, http.MethodPost, ts.URL+"/v1/scans", `{"state": "terraform.tfstate"}`, &job)
+	job = waitForJob(t, ts.URL, job.ID)
+	if job.Status != StatusFailed || !strings.Contains(job.Error, "timed out") {
+		t.Errorf("expected a timed out scan, got %+v", job)
+	}
+}
+
+func TestServer_ForgetsOldJobs(t *testing.T) {
+	s, ts := newTestServer(t, &fakeScanner{}, WithMaxJobs(2))
+
+	var ids []string
+	for range 3 {
+		var job Job
+		doJSON(t, http.MethodPost, ts.URL+"/v1/scans", `{"state": "terraform.tfstate"}`, &job)
+		waitForJob(t, ts.URL, job.ID)
+		ids = append(ids, job.ID)
+	}
+
+	if status := doJSON(t, http.MethodGet, ts.URL+"/v1/scans/"+ids[0], "", nil); status != http.StatusNotFound {
+		t.Errorf("expected the oldest scan to be forgotten, got %d", status)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.jobs) != 2 {
+		t.Errorf("expected 2 jobs kept, got %d", len(s.jobs))
+	}
+}
@@ -0,0 +1,135 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"firefly-ec2-drift-detector/models"
+)
+
+// AutoScalingProvider is implemented by state providers that can fetch Auto
+// Scaling groups with their members.
+type AutoScalingProvider interface {
+	GetAutoScalingGroupStates(ctx context.Context, names []string) (map[string]*models.AutoScalingGroupState, error)
+}
+
+// AutoScalingParser is implemented by parsers that can read
+// aws_autoscaling_group and aws_launch_template resources.
+type AutoScalingParser interface {
+	ParseAutoScalingGroups(path string) (map[string]*models.AutoScalingGroupState, error)
+}
+
+type autoScalingHandler struct {
+	provider   AutoScalingProvider
+	parser     AutoScalingParser
+	comparator models.ResourceComparator
+}
+
+// NewAutoScalingHandler returns the aws_autoscaling_group handler. Members are
+// not in state, so each is checked against the group's launch template.
+func NewAutoScalingHandler(provider AutoScalingProvider, parser AutoScalingParser, comparator models.ResourceComparator) ResourceHandler {
+	return &autoScalingHandler{
+		provider:   provider,
+		parser:     parser,
+		comparator: comparator,
+	}
+}
+
+func (h *autoScalingHandler) Type() string {
+	return models.ResourceTypeAutoScalingGroup
+}
+
+func (h *autoScalingHandler) Attributes() *models.AttributeRegistry {
+	return nil
+}
+
+func (h *autoScalingHandler) LoadExpected(_ context.Context, path string) (map[string]models.Resource, error) {
+	groups, err := h.parser.ParseAutoScalingGroups(path)
+	if err != nil {
+		return nil, err
+	}
+
+	resources := make(map[string]models.Resource, len(groups))
+	for id, group := range groups {
+		resources[id] = group
+	}
+	return resources, nil
+}
+
+// FetchActual looks groups up by name, which is also their ID in state.
+func (h *autoScalingHandler) FetchActual(ctx context.Context, ids []string, expected map[string]models.Resource, _ []string) (map[string]models.Resource, map[string]error) {
+	var (
+		names    []string
+		idByName = make(map[string]string, len(ids))
+		errs     = make(map[string]error)
+	)
+
+	for _, id := range ids {
+		name := expected[id].(*models.AutoScalingGroupState).Name
+		if name == "" {
+			errs[id] = fmt.Errorf("auto scaling group %s has no name to look up", id)
+			continue
+		}
+		names = append(names, name)
+		idByName[name] = id
+	}
+
+	if len(names) == 0 {
+		return nil, errs
+	}
+
+	groups, err := h.provider.GetAutoScalingGroupStates(ctx, names)
+	if err != nil {
+		for _, name := range names {
+			errs[idByName[name]] = fmt.Errorf("failed to fetch auto scaling group %s: %w", name, err)
+		}
+		return nil, errs
+	}
+
+	resources := make(map[string]models.Resource, len(groups))
+	for name, group := range groups {
+		if id, ok := idByName[name]; ok {
+			resources[id] = group
+		}
+	}
+	return resources, errs
+}
+
+// Compare checks the group and the template version of its members, then
+// compares each member against the launch template from state. Member drift
+// is reported on the group, under Instances["<id>"].
+func (h *autoScalingHandler) Compare(_ context.Context, expected, actual models.Resource, _ []string) (*models.DriftReport, error) {
+	expectedGroup := expected.(*models.AutoScalingGroupState)
+	actualGroup := actual.(*models.AutoScalingGroupState)
+
+	report := models.CompareAutoScalingGroup(expectedGroup, actualGroup)
+
+	template := expectedGroup.Template
+	if template == nil {
+		return report, nil
+	}
+
+	attrs := template.InstanceAttributes()
+	for _, member := range actualGroup.Instances {
+		if member.State == nil {
+			continue
+		}
+		if !slices.Contains(report.CheckedAttrs, models.MembersAttribute) {
+			report.CheckedAttrs = append(report.CheckedAttrs, models.MembersAttribute)
+		}
+
+		memberReport, err := h.comparator.CompareResource(models.ResourceTypeInstance, models.InstanceAttributes,
+			template.InstanceState(member.InstanceID), member.State, attrs)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, drift := range memberReport.Drifts {
+			drift.AttributeName = models.MemberAttribute(member.InstanceID, drift.AttributeName)
+			report.AddAttributeDrift(drift)
+		}
+	}
+
+	return report, nil
+}
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"firefly-ec2-drift-detector/models"
+)
+
+type fakeAutoScalingParser struct {
+	fakeParser
+	groups map[string]*models.AutoScalingGroupState
+}
+
+func (f *fakeAutoScalingParser) ParseAutoScalingGroups(_ string) (map[string]*models.AutoScalingGroupState, error) {
+	return f.groups, nil
+}
+
+type fakeAutoScalingProvider struct {
+	fakeProvider
+	groups map[string]*models.AutoScalingGroupState
+}
+
+func (f *fakeAutoScalingProvider) GetAutoScalingGroupStates(_ context.Context, names []string) (map[string]*models.AutoScalingGroupState, error) {
+	result := make(map[string]*models.AutoScalingGroupState)
+	for _, name := range names {
+		if group, ok := f.groups[name]; ok {
+			result[name] = group
+		}
+	}
+	return result, nil
+}
+
+func TestDetectResources_AutoScalingGroupMembers(t *testing.T) {
+	spec := func(version string) models.LaunchTemplateSpec {
+		return models.LaunchTemplateSpec{ID: "lt-1", Name: "web", Version: version}
+	}
+
+	parser := &fakeAutoScalingParser{
+		groups: map[string]*models.AutoScalingGroupState{
+			"web-asg": {
+				Name:           "web-asg",
+				LaunchTemplate: spec("$Latest"),
+				Template: &models.LaunchTemplateState{
+					ID:            "lt-1",
+					Name:          "web",
+					LatestVersion: 2,
+					InstanceType:  "t3.micro",
+					ImageID:       "ami-1",
+				},
+			},
+			"hcl:batch": {LaunchTemplate: spec("$Latest")},
+		},
+	}
+	provider := &fakeAutoScalingProvider{
+		groups: map[string]*models.AutoScalingGroupState{
+			"web-asg": {
+				Name:           "web-asg",
+				LaunchTemplate: spec("$Latest"),
+				Template:       &models.LaunchTemplateState{ID: "lt-1", Name: "web", LatestVersion: 2},
+				Instances: []models.AutoScalingInstance{
+					{
+						InstanceID:     "i-1",
+						LaunchTemplate: spec("2"),
+						State:          &models.InstanceState{InstanceID: "i-1", InstanceType: "t3.large", ImageID: "ami-1", SubnetID: "subnet-1"},
+					},
+					{
+						InstanceID:     "i-2",
+						LaunchTemplate: spec("1"),
+						State:          &models.InstanceState{InstanceID: "i-2", InstanceType: "t3.micro", ImageID: "ami-1"},
+					},
+				},
+			},
+		},
+	}
+
+	comparator := models.NewAttributeComparator(newTestLogger())
+	svc := NewDriftService(provider, parser, comparator, newTestLogger())
+
+	reports, err := svc.DetectResources(context.Background(), "state.tfstate", DetectRequest{
+		Types: []string{models.ResourceTypeAutoScalingGroup},
+	})
+	if err == nil {
+		t.Error("expected an error for the group without a name")
+	}
+
+	if len(reports) != 1 {
+		t.Fatalf("expected 1 report, got %d", len(reports))
+	}
+
+	got := make(map[string]models.AttributeDrift)
+	for _, drift := range reports[0].Drifts {
+		got[drift.AttributeName] = drift
+	}
+
+	// Only the attributes the template sets are compared, so SubnetID is not
+	// reported.
+	if len(got) != 2 {
+		t.Fatalf("expected 2 drifts, got %+v", reports[0].Drifts)
+	}
+	if drift := got[`Instances["i-1"].InstanceType`]; drift.ActualValue != "t3.large" {
+		t.Errorf("expected i-1 instance type drift, got %+v", drift)
+	}
+	if drift := got[`Instances["i-2"].LaunchTemplate.Version`]; drift.ActualValue != 1 {
+		t.Errorf("expected i-2 to run an outdated version, got %+v", drift)
+	}
+}
@@ -0,0 +1,91 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"firefly-ec2-drift-detector/models"
+)
+
+type (
+	// ResourceHandler adds drift detection for one Terraform resource type.
+	// DriftService drives every handler the same way: load the expected
+	// resources, fetch the live ones, then compare each pair.
+	ResourceHandler interface {
+		// Type is the Terraform resource type, e.g. "aws_instance".
+		Type() string
+
+		// Attributes lists the attributes Compare accepts, or nil when the
+		// handler always compares a fixed set.
+		Attributes() *models.AttributeRegistry
+
+		// LoadExpected reads the resources of this type from a state file,
+		// .tf file or directory, keyed by resource ID.
+		LoadExpected(ctx context.Context, path string) (map[string]models.Resource, error)
+
+		// FetchActual fetches the live state of ids, keyed by the same IDs.
+		// Resources that could not be fetched are reported in the error map;
+		// resources that do not exist are simply absent from both. It may
+		// also resolve configuration-only references in expected against
+		// what it fetched. attrs are the paths that will be compared, so
+		// that details nothing compares need not be fetched.
+		FetchActual(ctx context.Context, ids []string, expected map[string]models.Resource, attrs []string) (map[string]models.Resource, map[string]error)
+
+		// Compare reports drift between an expected and a live resource for
+		// the given canonical attribute paths. It fails only for a path the
+		// handler does not know.
+		Compare(ctx context.Context, expected, actual models.Resource, attrs []string) (*models.DriftReport, error)
+	}
+
+	// StreamingHandler is implemented by handlers that can hand over each
+	// live resource as soon as it is fetched, so that it is compared and
+	// reported without waiting for the rest of its type.
+	StreamingHandler interface {
+		ResourceHandler
+
+		// FetchEach fetches ids like FetchActual, calling fn once per ID
+		// with the live resource or the error fetching it. Both are nil
+		// when the resource does not exist. fn may be called concurrently.
+		FetchEach(ctx context.Context, ids []string, expected map[string]models.Resource, attrs []string, fn func(id string, actual models.Resource, err error))
+	}
+
+	// HandlerRegistry holds the handlers a DriftService can run, in
+	// registration order.
+	HandlerRegistry struct {
+		handlers map[string]ResourceHandler
+		order    []string
+	}
+)
+
+func NewHandlerRegistry(handlers ...ResourceHandler) *HandlerRegistry {
+	r := &HandlerRegistry{
+		handlers: make(map[string]ResourceHandler, len(handlers)),
+	}
+
+	for _, h := range handlers {
+		r.Register(h)
+	}
+
+	return r
+}
+
+// Register adds a handler, replacing any handler for the same type.
+func (r *HandlerRegistry) Register(h ResourceHandler) {
+	if _, exists := r.handlers[h.Type()]; !exists {
+		r.order = append(r.order, h.Type())
+	}
+	r.handlers[h.Type()] = h
+}
+
+func (r *HandlerRegistry) Get(resourceType string) (ResourceHandler, error) {
+	h, ok := r.handlers[resourceType]
+	if !ok {
+		return nil, fmt.Errorf("unsupported resource type %q (supported: %v)", resourceType, r.order)
+	}
+	return h, nil
+}
+
+// Types returns the registered resource types in registration order.
+func (r *HandlerRegistry) Types() []string {
+	return append([]string(nil), r.order...)
+}
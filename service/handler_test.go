@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"firefly-ec2-drift-detector/models"
+)
+
+type fakeResource struct {
+	id    string
+	value string
+}
+
+func (r *fakeResource) ResourceID() string {
+	return r.id
+}
+
+type fakeHandler struct {
+	expected map[string]models.Resource
+	actual   map[string]models.Resource
+	errs     map[string]error
+}
+
+func (h *fakeHandler) Type() string {
+	return "aws_fake"
+}
+
+func (h *fakeHandler) Attributes() *models.AttributeRegistry {
+	return nil
+}
+
+func (h *fakeHandler) LoadExpected(_ context.Context, _ string) (map[string]models.Resource, error) {
+	return h.expected, nil
+}
+
+func (h *fakeHandler) FetchActual(_ context.Context, _ []string, _ map[string]models.Resource, _ []string) (map[string]models.Resource, map[string]error) {
+	return h.actual, h.errs
+}
+
+func (h *fakeHandler) Compare(_ context.Context, expected, actual models.Resource, attrs []string) (*models.DriftReport, error) {
+	report := &models.DriftReport{InstanceID: actual.ResourceID(), ResourceType: h.Type()}
+	if exp, act := expected.(*fakeResource).value, actual.(*fakeResource).value; exp != act {
+		report.AddDrift("Value", exp, act, models.DriftTypeValueMismatch)
+	}
+	return report, nil
+}
+
+func TestHandlerRegistry(t *testing.T) {
+	registry := NewHandlerRegistry(NewInstanceHandler(nil, nil, nil, newTestLogger()))
+	registry.Register(&fakeHandler{})
+
+	if got := registry.Types(); !reflect.DeepEqual(got, []string{models.ResourceTypeInstance, "aws_fake"}) {
+		t.Errorf("Types() = %v", got)
+	}
+
+	if _, err := registry.Get("aws_unknown"); err == nil {
+		t.Error("expected an error for an unregistered type")
+	}
+}
+
+func TestDetectResources_MixedTypes(t *testing.T) {
+	parser := &fakeParser{
+		states: map[string]*models.InstanceState{
+			"i-1": {InstanceID: "i-1"},
+		},
+	}
+	provider := &fakeProvider{
+		states: map[string]*models.InstanceState{
+			"i-1": {InstanceID: "i-1"},
+		},
+	}
+
+	handler := &fakeHandler{
+		expected: map[string]models.Resource{
+			"f-1": &fakeResource{id: "f-1", value: "a"},
+			"f-2": &fakeResource{id: "f-2", value: "a"},
+			"f-3": &fakeResource{id: "f-3", value: "a"},
+		},
+		actual: map[string]models.Resource{
+			"f-1": &fakeResource{id: "f-1", value: "a"},
+			"f-2": &fakeResource{id: "f-2", value: "b"},
+		},
+		errs: map[string]error{
+			"f-3": errors.New("boom"),
+		},
+	}
+
+	svc := NewDriftService(provider, parser, &fakeComparator{}, newTestLogger())
+	svc.RegisterHandler(handler)
+
+	reports, err := svc.DetectResources(context.Background(), "state.tfstate", DetectRequest{
+		Types: []string{models.ResourceTypeInstance, "aws_fake"},
+	})
+	if err == nil || err.Error() != "boom" {
+		t.Fatalf("expected the fetch failure to be reported, got %v", err)
+	}
+
+	if len(reports) != 3 {
+		t.Fatalf("expected 3 reports, got %d", len(reports))
+	}
+
+	wantIDs := []string{"i-1", "f-1", "f-2"}
+	for i, report := range reports {
+		if report.InstanceID != wantIDs[i] {
+			t.Errorf("reports[%d] = %s, want %s", i, report.InstanceID, wantIDs[i])
+		}
+	}
+
+	if !reports[2].HasDrift || reports[1].HasDrift {
+		t.Error("expected drift on f-2 only")
+	}
+}
+
+func TestDetectResources_ValidatesBeforeFetching(t *testing.T) {
+	svc := NewDriftService(&fakeProvider{}, &fakeParser{}, &fakeComparator{}, newTestLogger())
+
+	_, err := svc.DetectResources(context.Background(), "state.tfstate", DetectRequest{
+		Types: []string{"aws_unknown"},
+	})
+	if err == nil {
+		t.Error("expected an error for an unsupported resource type")
+	}
+
+	_, err = svc.DetectResources(context.Background(), "state.tfstate", DetectRequest{
+		Attributes: map[string][]string{models.ResourceTypeInstance: {"NotAField"}},
+	})
+	if err == nil {
+		t.Error("expected an error for an unknown instance attribute")
+	}
+}
@@ -0,0 +1,173 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"go.uber.org/zap"
+
+	awspkg "firefly-ec2-drift-detector/aws"
+	flog "firefly-ec2-drift-detector/logger"
+	"firefly-ec2-drift-detector/models"
+)
+
+// batchThreshold is the instance count above which instances are fetched with
+// batched DescribeInstances calls instead of one retried call each.
+const batchThreshold = 10
+
+type instanceHandler struct {
+	provider   StateProvider
+	parser     StateParser
+	comparator models.DriftDetector
+	logger     *flog.Logger
+}
+
+// NewInstanceHandler returns the aws_instance handler.
+func NewInstanceHandler(provider StateProvider, parser StateParser, comparator models.DriftDetector, logger *flog.Logger) ResourceHandler {
+	return &instanceHandler{
+		provider:   provider,
+		parser:     parser,
+		comparator: comparator,
+		logger:     logger,
+	}
+}
+
+func (h *instanceHandler) Type() string {
+	return models.ResourceTypeInstance
+}
+
+func (h *instanceHandler) Attributes() *models.AttributeRegistry {
+	return models.InstanceAttributes
+}
+
+func (h *instanceHandler) LoadExpected(ctx context.Context, path string) (map[string]models.Resource, error) {
+	states, err := h.parser.ParseStateFile(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+
+	resources := make(map[string]models.Resource, len(states))
+	for id, state := range states {
+		resources[id] = state
+	}
+	return resources, nil
+}
+
+func (h *instanceHandler) FetchActual(ctx context.Context, ids []string, _ map[string]models.Resource, attrs []string) (map[string]models.Resource, map[string]error) {
+	opts := fetchOptions(attrs)
+	if len(ids) > batchThreshold {
+		return h.fetchBatch(ctx, ids, opts)
+	}
+
+	var (
+		mu        sync.Mutex
+		resources = make(map[string]models.Resource, len(ids))
+		errs      = make(map[string]error)
+	)
+
+	h.fetchConcurrent(ctx, ids, opts, func(id string, state models.Resource, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if err != nil {
+			errs[id] = err
+			return
+		}
+		resources[id] = state
+	})
+
+	return resources, errs
+}
+
+// FetchEach implements StreamingHandler. Instances fetched concurrently are
+// handed over one by one; a batch is handed over once its call returns.
+func (h *instanceHandler) FetchEach(ctx context.Context, ids []string, _ map[string]models.Resource, attrs []string, fn func(id string, actual models.Resource, err error)) {
+	opts := fetchOptions(attrs)
+	if len(ids) > batchThreshold {
+		resources, errs := h.fetchBatch(ctx, ids, opts)
+		for _, id := range ids {
+			fn(id, resources[id], errs[id])
+		}
+		return
+	}
+
+	h.fetchConcurrent(ctx, ids, opts, fn)
+}
+
+// fetchOptions asks for root volume details only when a RootBlockDevice
+// attribute is compared, since they cost a DescribeVolumes call.
+func fetchOptions(attrs []string) []awspkg.FetchOption {
+	for _, attr := range attrs {
+		if path, err := models.ParseAttributePath(attr); err == nil && path.Root() == "RootBlockDevice" {
+			return []awspkg.FetchOption{awspkg.WithRootVolumes()}
+		}
+	}
+	return nil
+}
+
+func (h *instanceHandler) fetchBatch(ctx context.Context, ids []string, opts []awspkg.FetchOption) (map[string]models.Resource, map[string]error) {
+	h.logger.Info("using batch mode for large instance count",
+		zap.Int("instance_count", len(ids)),
+	)
+
+	states, err := h.provider.GetInstanceStatesBatch(ctx, ids, opts...)
+	if err != nil {
+		h.logger.Warn("batch fetch encountered errors",
+			zap.Error(err),
+			zap.Int("successful_fetches", len(states)),
+		)
+	}
+
+	resources := make(map[string]models.Resource, len(states))
+	for id, state := range states {
+		resources[id] = state
+	}
+
+	// A failed batch returns none of its instances, which are then not
+	// checked rather than reported as missing from AWS.
+	errs := make(map[string]error)
+	if err != nil {
+		for _, id := range ids {
+			if _, ok := resources[id]; !ok {
+				errs[id] = fmt.Errorf("failed to fetch instance %s: %w", id, err)
+			}
+		}
+	}
+	return resources, errs
+}
+
+// fetchConcurrent fetches each instance in a goroutine of its own, calling fn
+// from that goroutine as soon as it is fetched.
+func (h *instanceHandler) fetchConcurrent(ctx context.Context, ids []string, opts []awspkg.FetchOption, fn func(id string, actual models.Resource, err error)) {
+	h.logger.Info("checking instances concurrently",
+		zap.Int("instance_count", len(ids)),
+	)
+
+	var wg sync.WaitGroup
+	for _, instanceID := range ids {
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+
+			state, err := h.provider.GetInstanceState(ctx, id, opts...)
+			if err != nil {
+				if awspkg.IsAuthError(err) {
+					h.logger.Error("authentication error - check AWS credentials",
+						zap.String("instance_id", id),
+						zap.Error(err),
+					)
+				}
+				fn(id, nil, err)
+				return
+			}
+			fn(id, state, nil)
+		}(instanceID)
+	}
+
+	wg.Wait()
+}
+
+func (h *instanceHandler) Compare(ctx context.Context, expected, actual models.Resource, attrs []string) (*models.DriftReport, error) {
+	return h.comparator.CompareAttributes(ctx, expected.(*models.InstanceState), actual.(*models.InstanceState), attrs)
+}
@@ -0,0 +1,185 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"firefly-ec2-drift-detector/models"
+)
+
+// SecurityGroupProvider is implemented by state providers that can fetch
+// security groups. Groups are looked up by ID, or by name for groups declared
+// in HCL that have no ID yet.
+type SecurityGroupProvider interface {
+	GetSecurityGroupStates(ctx context.Context, groupIDs, groupNames []string) (map[string]*models.SecurityGroupState, error)
+}
+
+// SecurityGroupParser is implemented by parsers that can read security groups
+// and their rules from Terraform state or configuration.
+type SecurityGroupParser interface {
+	ParseSecurityGroups(path string) (map[string]*models.SecurityGroupState, error)
+}
+
+type securityGroupHandler struct {
+	provider SecurityGroupProvider
+	parser   SecurityGroupParser
+}
+
+// NewSecurityGroupHandler returns the aws_security_group handler, which
+// compares ingress and egress rules.
+func NewSecurityGroupHandler(provider SecurityGroupProvider, parser SecurityGroupParser) ResourceHandler {
+	return &securityGroupHandler{
+		provider: provider,
+		parser:   parser,
+	}
+}
+
+func (h *securityGroupHandler) Type() string {
+	return models.ResourceTypeSecurityGroup
+}
+
+func (h *securityGroupHandler) Attributes() *models.AttributeRegistry {
+	return nil
+}
+
+func (h *securityGroupHandler) LoadExpected(_ context.Context, path string) (map[string]models.Resource, error) {
+	groups, err := h.parser.ParseSecurityGroups(path)
+	if err != nil {
+		return nil, err
+	}
+
+	resources := make(map[string]models.Resource, len(groups))
+	for id, group := range groups {
+		resources[id] = group
+	}
+	return resources, nil
+}
+
+func (h *securityGroupHandler) FetchActual(ctx context.Context, ids []string, expected map[string]models.Resource, _ []string) (map[string]models.Resource, map[string]error) {
+	var (
+		groupIDs, names []string
+		errs            = make(map[string]error)
+	)
+
+	for _, id := range ids {
+		switch {
+		case !isConfigOnlyID(id):
+			groupIDs = append(groupIDs, id)
+		case expected[id].(*models.SecurityGroupState).Name != "":
+			names = append(names, expected[id].(*models.SecurityGroupState).Name)
+		default:
+			errs[id] = fmt.Errorf("security group %s has no static name to look up", id)
+		}
+	}
+
+	if len(groupIDs) == 0 && len(names) == 0 {
+		return nil, errs
+	}
+
+	live, err := h.provider.GetSecurityGroupStates(ctx, groupIDs, names)
+	if err != nil {
+		for _, id := range ids {
+			errs[id] = fmt.Errorf("failed to fetch security group %s: %w", id, err)
+		}
+		return nil, errs
+	}
+
+	liveIDs, ambiguous := resolveLiveGroupIDs(expected, live)
+	for id, err := range ambiguous {
+		errs[id] = err
+	}
+
+	resources := make(map[string]models.Resource, len(ids))
+	for _, id := range ids {
+		if group, ok := live[liveIDs[id]]; ok {
+			resources[id] = group
+			expected[id] = resolveRuleSources(expected[id].(*models.SecurityGroupState), group.GroupID, liveIDs)
+		}
+	}
+
+	return resources, errs
+}
+
+func (h *securityGroupHandler) Compare(_ context.Context, expected, actual models.Resource, _ []string) (*models.DriftReport, error) {
+	return models.CompareSecurityGroupRules(expected.(*models.SecurityGroupState), actual.(*models.SecurityGroupState)), nil
+}
+
+func isConfigOnlyID(id string) bool {
+	return strings.HasPrefix(id, "hcl:")
+}
+
+// groupKey identifies a security group by name within its VPC, since names
+// are only unique per VPC.
+type groupKey struct {
+	name, vpcID string
+}
+
+// resolveLiveGroupIDs maps every expected group ID to the ID of the live
+// group, matching configuration-only groups by name within their VPC. When
+// the configuration does not give a literal vpc_id, a name shared by groups in
+// several VPCs cannot be matched and is reported as an error instead.
+func resolveLiveGroupIDs(expected map[string]models.Resource, live map[string]*models.SecurityGroupState) (map[string]string, map[string]error) {
+	var (
+		byKey  = make(map[groupKey]string, len(live))
+		byName = make(map[string][]string, len(live))
+	)
+	for id, group := range live {
+		byKey[groupKey{group.Name, group.VpcID}] = id
+		byName[group.Name] = append(byName[group.Name], id)
+	}
+
+	liveIDs := make(map[string]string, len(expected))
+	errs := make(map[string]error)
+	for id, resource := range expected {
+		liveIDs[id] = id
+		if !isConfigOnlyID(id) {
+			continue
+		}
+
+		group := resource.(*models.SecurityGroupState)
+		if group.VpcID != "" && !isConfigOnlyID(group.VpcID) {
+			if liveID, ok := byKey[groupKey{group.Name, group.VpcID}]; ok {
+				liveIDs[id] = liveID
+			}
+			continue
+		}
+
+		switch matches := byName[group.Name]; len(matches) {
+		case 0:
+		case 1:
+			liveIDs[id] = matches[0]
+		default:
+			slices.Sort(matches)
+			errs[id] = fmt.Errorf("security group name %q is used in more than one VPC (%s); set a literal vpc_id to choose one",
+				group.Name, strings.Join(matches, ", "))
+		}
+	}
+
+	return liveIDs, errs
+}
+
+// resolveRuleSources returns a copy of group whose rule sources that
+// reference configuration-only groups are rewritten to their live IDs, and
+// references to the group itself to "self". group is left as it is, since
+// parsed state can be reused by later scans.
+func resolveRuleSources(group *models.SecurityGroupState, groupID string, liveIDs map[string]string) *models.SecurityGroupState {
+	resolved := *group
+	resolved.Ingress = resolveSources(group.Ingress, groupID, liveIDs)
+	resolved.Egress = resolveSources(group.Egress, groupID, liveIDs)
+	return &resolved
+}
+
+func resolveSources(rules []models.SecurityGroupRule, groupID string, liveIDs map[string]string) []models.SecurityGroupRule {
+	resolved := slices.Clone(rules)
+	for i := range resolved {
+		if liveID, ok := liveIDs[resolved[i].Source]; ok && isConfigOnlyID(resolved[i].Source) {
+			resolved[i].Source = liveID
+		}
+		if resolved[i].Source == groupID {
+			resolved[i].Source = models.SelfSource
+		}
+	}
+	return resolved
+}
@@ -0,0 +1,233 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"firefly-ec2-drift-detector/models"
+)
+
+type fakeSecurityGroupParser struct {
+	fakeParser
+	groups map[string]*models.SecurityGroupState
+}
+
+func (f *fakeSecurityGroupParser) ParseSecurityGroups(_ string) (map[string]*models.SecurityGroupState, error) {
+	return f.groups, nil
+}
+
+type fakeSecurityGroupProvider struct {
+	fakeProvider
+	groups           map[string]*models.SecurityGroupState
+	gotIDs, gotNames []string
+}
+
+func (f *fakeSecurityGroupProvider) GetSecurityGroupStates(_ context.Context, groupIDs, groupNames []string) (map[string]*models.SecurityGroupState, error) {
+	f.gotIDs, f.gotNames = groupIDs, groupNames
+
+	result := make(map[string]*models.SecurityGroupState)
+	for id, group := range f.groups {
+		for _, want := range groupIDs {
+			if want == id {
+				result[id] = group
+			}
+		}
+		for _, want := range groupNames {
+			if want == group.Name {
+				result[id] = group
+			}
+		}
+	}
+	return result, nil
+}
+
+func TestDetectSecurityGroupDrift(t *testing.T) {
+	parser := &fakeSecurityGroupParser{
+		groups: map[string]*models.SecurityGroupState{
+			"sg-web": {
+				GroupID: "sg-web",
+				Ingress: []models.SecurityGroupRule{
+					models.NewSecurityGroupRule("tcp", 443, 443, "0.0.0.0/0", "https"),
+				},
+			},
+			"hcl:db": {
+				GroupID: "hcl:db",
+				Name:    "db",
+				Ingress: []models.SecurityGroupRule{
+					models.NewSecurityGroupRule("tcp", 5432, 5432, "sg-web", ""),
+					models.NewSecurityGroupRule("tcp", 5432, 5432, "hcl:db", "replication"),
+				},
+			},
+		},
+	}
+
+	provider := &fakeSecurityGroupProvider{
+		groups: map[string]*models.SecurityGroupState{
+			"sg-web": {
+				GroupID: "sg-web",
+				Name:    "web",
+				Ingress: []models.SecurityGroupRule{
+					models.NewSecurityGroupRule("tcp", 443, 443, "0.0.0.0/0", "https"),
+					models.NewSecurityGroupRule("tcp", 22, 22, "0.0.0.0/0", ""),
+				},
+			},
+			"sg-db": {
+				GroupID: "sg-db",
+				Name:    "db",
+				Ingress: []models.SecurityGroupRule{
+					models.NewSecurityGroupRule("tcp", 5432, 5432, "sg-web", ""),
+					models.NewSecurityGroupRule("tcp", 5432, 5432, models.SelfSource, "replication"),
+				},
+			},
+		},
+	}
+
+	svc := NewDriftService(provider, parser, &fakeComparator{}, newTestLogger())
+
+	reports, err := svc.DetectResources(context.Background(), "state.tfstate", DetectRequest{
+		Types: []string{models.ResourceTypeSecurityGroup},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(provider.gotIDs) != 1 || provider.gotIDs[0] != "sg-web" {
+		t.Errorf("expected lookup by ID for sg-web, got %v", provider.gotIDs)
+	}
+	if len(provider.gotNames) != 1 || provider.gotNames[0] != "db" {
+		t.Errorf("expected lookup by name for hcl:db, got %v", provider.gotNames)
+	}
+
+	if len(reports) != 2 {
+		t.Fatalf("expected 2 reports, got %d", len(reports))
+	}
+
+	byID := make(map[string]*models.DriftReport)
+	for _, r := range reports {
+		byID[r.InstanceID] = r
+	}
+
+	if r := byID["sg-db"]; r == nil || r.HasDrift {
+		t.Errorf("expected configuration-only group to match without drift, got %+v", r)
+	}
+
+	web := byID["sg-web"]
+	if web == nil || !web.HasDrift || web.ResourceType != models.ResourceTypeSecurityGroup {
+		t.Fatalf("expected drift on sg-web, got %+v", web)
+	}
+	if added := web.Drifts[0].Added; len(added) != 1 || added[0] != "tcp 22 0.0.0.0/0" {
+		t.Errorf("expected the ssh rule to be reported as added, got %v", added)
+	}
+}
+
+func TestDetectSecurityGroupDrift_Unsupported(t *testing.T) {
+	svc := NewDriftService(&fakeProvider{}, &fakeParser{}, &fakeComparator{}, newTestLogger())
+
+	_, err := svc.DetectResources(context.Background(), "state.tfstate", DetectRequest{
+		Types: []string{models.ResourceTypeSecurityGroup},
+	})
+	if err == nil {
+		t.Error("expected an error when the provider cannot fetch security groups")
+	}
+}
+
+func TestDetectSecurityGroupDrift_KeepsParsedRules(t *testing.T) {
+	parser := &fakeSecurityGroupParser{
+		groups: map[string]*models.SecurityGroupState{
+			"hcl:db": {
+				GroupID: "hcl:db",
+				Name:    "db",
+				Ingress: []models.SecurityGroupRule{
+					models.NewSecurityGroupRule("tcp", 5432, 5432, "hcl:db", "replication"),
+				},
+			},
+		},
+	}
+	provider := &fakeSecurityGroupProvider{
+		groups: map[string]*models.SecurityGroupState{
+			"sg-db": {
+				GroupID: "sg-db",
+				Name:    "db",
+				Ingress: []models.SecurityGroupRule{
+					models.NewSecurityGroupRule("tcp", 5432, 5432, models.SelfSource, "replication"),
+				},
+			},
+		},
+	}
+
+	svc := NewDriftService(provider, parser, &fakeComparator{}, newTestLogger())
+
+	// The second scan reuses the state parsed by the first.
+	for i := range 2 {
+		reports, err := svc.DetectResources(context.Background(), "state.tfstate", DetectRequest{
+			Types:        []string{models.ResourceTypeSecurityGroup},
+			StateVersion: "v1",
+		})
+		if err != nil {
+			t.Fatalf("scan %d: unexpected error: %v", i+1, err)
+		}
+		if len(reports) != 1 || reports[0].HasDrift {
+			t.Fatalf("scan %d: expected no drift, got %+v", i+1, reports)
+		}
+	}
+
+	if source := parser.groups["hcl:db"].Ingress[0].Source; source != "hcl:db" {
+		t.Errorf("expected the parsed rule to keep its source, got %q", source)
+	}
+}
+
+func TestDetectSecurityGroupDrift_MatchesNameWithinVPC(t *testing.T) {
+	parser := &fakeSecurityGroupParser{
+		groups: map[string]*models.SecurityGroupState{
+			"hcl:db": {
+				GroupID: "hcl:db",
+				Name:    "db",
+				VpcID:   "vpc-2",
+				Ingress: []models.SecurityGroupRule{
+					models.NewSecurityGroupRule("tcp", 5432, 5432, "10.0.0.0/16", ""),
+				},
+			},
+			"hcl:cache": {
+				GroupID: "hcl:cache",
+				Name:    "cache",
+				VpcID:   "hcl:main",
+			},
+		},
+	}
+	provider := &fakeSecurityGroupProvider{
+		groups: map[string]*models.SecurityGroupState{
+			"sg-db1": {GroupID: "sg-db1", Name: "db", VpcID: "vpc-1"},
+			"sg-db2": {
+				GroupID: "sg-db2",
+				Name:    "db",
+				VpcID:   "vpc-2",
+				Ingress: []models.SecurityGroupRule{
+					models.NewSecurityGroupRule("tcp", 5432, 5432, "10.0.0.0/16", ""),
+				},
+			},
+			"sg-cache1": {GroupID: "sg-cache1", Name: "cache", VpcID: "vpc-1"},
+			"sg-cache2": {GroupID: "sg-cache2", Name: "cache", VpcID: "vpc-2"},
+		},
+	}
+
+	svc := NewDriftService(provider, parser, &fakeComparator{}, newTestLogger())
+
+	reports, err := svc.DetectResources(context.Background(), "state.tfstate", DetectRequest{
+		Types: []string{models.ResourceTypeSecurityGroup},
+	})
+
+	if len(reports) != 1 || reports[0].InstanceID != "sg-db2" || reports[0].HasDrift {
+		t.Fatalf("expected hcl:db to match sg-db2 in vpc-2 without drift, got %+v", reports)
+	}
+
+	var partial *PartialFailureError
+	if !errors.As(err, &partial) || len(partial.Failures) != 1 {
+		t.Fatalf("expected one failure for the ambiguous name, got %v", err)
+	}
+	if failure := partial.Failures[0]; failure.ResourceID != "hcl:cache" ||
+		!strings.Contains(failure.Error(), "sg-cache1, sg-cache2") {
+		t.Errorf("expected hcl:cache to fail naming both groups, got %v", failure)
+	}
+}
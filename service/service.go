@@ -4,9 +4,14 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sort"
 	"sync"
 	"time"
 
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 
 	awspkg "firefly-ec2-drift-detector/aws"
@@ -14,224 +19,537 @@ import (
 	"firefly-ec2-drift-detector/models"
 )
 
+var tracer = otel.Tracer("firefly-ec2-drift-detector/service")
+
 type StateProvider interface {
-	GetInstanceState(ctx context.Context, instanceID string) (*models.InstanceState, error)
-	GetInstanceStatesBatch(ctx context.Context, instanceIDs []string) (map[string]*models.InstanceState, error)
+	GetInstanceState(ctx context.Context, instanceID string, opts ...awspkg.FetchOption) (*models.InstanceState, error)
+	GetInstanceStatesBatch(ctx context.Context, instanceIDs []string, opts ...awspkg.FetchOption) (map[string]*models.InstanceState, error)
 }
 
 type StateParser interface {
-	ParseStateFile(filepath string) (map[string]*models.InstanceState, error)
+	ParseStateFile(ctx context.Context, filepath string) (map[string]*models.InstanceState, error)
 }
 
 type DriftService struct {
-	awsProvider StateProvider
-	tfParser    StateParser
-	comparator  models.DriftDetector
-	logger      *flog.Logger
+	handlers *HandlerRegistry
+	severity *models.SeverityPolicy
+	baseline *models.Baseline
+	states   *stateCache
+	now      func() time.Time
+	logger   *flog.Logger
+}
+
+// DetectRequest selects what DetectResources checks.
+type DetectRequest struct {
+	// Types lists the resource types to check; empty checks every
+	// registered type.
+	Types []string
+
+	// IDs restricts a type to specific resource IDs. Types without an entry
+	// check every resource of that type in the state.
+	IDs map[string][]string
+
+	// Attributes selects the attributes compared for a type. Types without
+	// an entry compare all of their attributes.
+	Attributes map[string][]string
+
+	// StateVersion identifies the contents of the state path, such as its
+	// modification time. When set, resources already loaded from the same
+	// path and version are reused rather than parsed again.
+	StateVersion string
+
+	// OnReport, when set, is called with each report as soon as it is
+	// complete, before DetectResources returns. Calls are not concurrent.
+	OnReport func(report *models.DriftReport)
+}
+
+// ResourceError records a resource that could not be checked. ResourceID is
+// empty when the whole resource type failed, for example on a parse error.
+type ResourceError struct {
+	ResourceType string
+	ResourceID   string
+	Err          error
+}
+
+func (e *ResourceError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ResourceError) Unwrap() error {
+	return e.Err
+}
+
+// PartialFailureError is returned alongside the reports of a run in which
+// some resources could not be checked.
+type PartialFailureError struct {
+	Failures []*ResourceError
+	msg      string
+}
+
+func (e *PartialFailureError) Error() string {
+	return e.msg
+}
+
+func (e *PartialFailureError) Unwrap() []error {
+	errs := make([]error, len(e.Failures))
+	for i, f := range e.Failures {
+		errs[i] = f
+	}
+	return errs
+}
+
+// Failures returns the resources err reports as not checked, if any.
+func Failures(err error) []*ResourceError {
+	var partial *PartialFailureError
+	if errors.As(err, &partial) {
+		return partial.Failures
+	}
+	return nil
 }
 
+// NewDriftService creates a service with the aws_instance handler, plus a
+// handler for every other type the provider, parser and comparator support.
+// More types can be added with RegisterHandler.
 func NewDriftService(provider StateProvider, parser StateParser, comparator models.DriftDetector, logger *flog.Logger) *DriftService {
+	handlers := NewHandlerRegistry(NewInstanceHandler(provider, parser, comparator, logger))
+
+	sgProvider, providerOK := provider.(SecurityGroupProvider)
+	sgParser, parserOK := parser.(SecurityGroupParser)
+	if providerOK && parserOK {
+		handlers.Register(NewSecurityGroupHandler(sgProvider, sgParser))
+	}
+
+	volumeProvider, providerOK := provider.(VolumeProvider)
+	volumeParser, parserOK := parser.(VolumeParser)
+	resourceComparator, comparatorOK := comparator.(models.ResourceComparator)
+	if providerOK && parserOK && comparatorOK {
+		handlers.Register(NewVolumeHandler(volumeProvider, volumeParser, resourceComparator))
+	}
+
+	asgProvider, providerOK := provider.(AutoScalingProvider)
+	asgParser, parserOK := parser.(AutoScalingParser)
+	if providerOK && parserOK && comparatorOK {
+		handlers.Register(NewAutoScalingHandler(asgProvider, asgParser, resourceComparator))
+	}
+
 	return &DriftService{
-		awsProvider: provider,
-		tfParser:    parser,
-		comparator:  comparator,
-		logger:      logger,
+		handlers: handlers,
+		severity: models.DefaultSeverityPolicy(),
+		states:   newStateCache(),
+		now:      time.Now,
+		logger:   logger,
 	}
 }
 
-func (s *DriftService) DetectDrift(ctx context.Context, tfStatePath string, instanceIDs []string, attrs []string) ([]*models.DriftReport, error) {
+// SetSeverityPolicy replaces the policy that rates each drift. Passing nil
+// leaves drifts unrated.
+func (s *DriftService) SetSeverityPolicy(policy *models.SeverityPolicy) {
+	s.severity = policy
+}
+
+// SetBaseline sets the accepted drifts that are reported as suppressed
+// rather than as drift. Passing nil suppresses nothing.
+func (s *DriftService) SetBaseline(baseline *models.Baseline) {
+	s.baseline = baseline
+}
+
+// RegisterHandler adds support for a resource type.
+func (s *DriftService) RegisterHandler(h ResourceHandler) {
+	s.handlers.Register(h)
+}
+
+// ResourceTypes returns the resource types the service can check.
+func (s *DriftService) ResourceTypes() []string {
+	return s.handlers.Types()
+}
+
+// DetectResources checks any mix of registered resource types. Resources that
+// could not be checked are summarised in the returned error alongside the
+// reports for those that could.
+func (s *DriftService) DetectResources(ctx context.Context, tfStatePath string, req DetectRequest) ([]*models.DriftReport, error) {
+	types := req.Types
+	if len(types) == 0 {
+		types = s.handlers.Types()
+	}
+
+	ctx, span := tracer.Start(ctx, "DriftService.DetectResources", trace.WithAttributes(
+		attribute.String("terraform.state.path", tfStatePath),
+		attribute.StringSlice("resource.types", types),
+	))
+	defer span.End()
+
 	s.logger.Info("starting drift detection",
 		zap.String("terraform_state", tfStatePath),
-		zap.Strings("instance_ids", instanceIDs),
-		zap.Strings("attributes", attrs),
+		zap.Strings("resource_types", types),
 	)
 
-	startTime := time.Now()
-
-	expectedStates, err := s.tfParser.ParseStateFile(tfStatePath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse terraform state: %w", err)
-	}
+	// Resolve every handler and attribute list before any AWS calls.
+	handlers := make([]ResourceHandler, 0, len(types))
+	attrsByType := make(map[string][]string, len(types))
+	for _, resourceType := range types {
+		h, err := s.handlers.Get(resourceType)
+		if err != nil {
+			recordError(span, err)
+			return nil, err
+		}
 
-	if len(instanceIDs) == 0 {
-		for id := range expectedStates {
-			instanceIDs = append(instanceIDs, id)
+		attrs, err := resolveHandlerAttributes(h, req.Attributes[resourceType])
+		if err != nil {
+			recordError(span, err)
+			return nil, err
 		}
-		s.logger.Info("checking all instances from state file",
-			zap.Int("instance_count", len(instanceIDs)),
-		)
+
+		handlers = append(handlers, h)
+		attrsByType[resourceType] = attrs
 	}
 
+	startTime := time.Now()
+
 	var (
-		reports      []*models.DriftReport
-		detectionErr error
+		reports  []*models.DriftReport
+		failures []*ResourceError
 	)
 
-	if len(instanceIDs) > 10 {
-		s.logger.Info("using batch mode for large instance count",
-			zap.Int("instance_count", len(instanceIDs)),
-		)
-		reports, detectionErr = s.detectDriftBatch(ctx, expectedStates, instanceIDs, attrs)
-	} else {
-		reports, detectionErr = s.detectDriftConcurrent(ctx, expectedStates, instanceIDs, attrs)
+	for _, h := range handlers {
+		typeReports, typeFailures, err := s.detectType(ctx, h, tfStatePath, req.StateVersion, req.IDs[h.Type()], attrsByType[h.Type()], req.OnReport)
+		if err != nil {
+			failures = append(failures, &ResourceError{ResourceType: h.Type(), Err: err})
+			continue
+		}
+		reports = append(reports, typeReports...)
+		failures = append(failures, typeFailures...)
 	}
 
-	duration := time.Since(startTime)
-
-	if detectionErr != nil {
-		s.logger.Error("drift detection encountered errors",
-			zap.Duration("duration", duration),
-			zap.Error(detectionErr),
-		)
+	span.SetAttributes(
+		attribute.Int("resource.checked_count", len(reports)),
+		attribute.Int("resource.failed_count", len(failures)),
+	)
+	err := s.finish(reports, failures, time.Since(startTime))
+	if err != nil {
+		recordError(span, err)
 	}
+	return reports, err
+}
 
-	driftCount := 0
-	for _, report := range reports {
-		if report.HasDrift {
-			driftCount++
-		}
+func resolveHandlerAttributes(h ResourceHandler, attrs []string) ([]string, error) {
+	registry := h.Attributes()
+	if registry == nil {
+		return nil, nil
 	}
 
-	s.logger.Info("drift detection completed",
-		zap.Duration("duration", duration),
-		zap.Int("total_instances", len(reports)),
-		zap.Int("instances_with_drift", driftCount),
-	)
+	if len(attrs) == 0 {
+		attrs = []string{models.AllAttributes}
+	}
 
-	return reports, detectionErr
+	resolved, err := registry.Resolve(attrs)
+	if err != nil {
+		return nil, fmt.Errorf("invalid attributes for %s: %w", h.Type(), err)
+	}
+	return resolved, nil
 }
 
-func (s *DriftService) detectDriftBatch(ctx context.Context, expectedStates map[string]*models.InstanceState, instanceIDs []string, attrs []string) ([]*models.DriftReport, error) {
-	s.logger.Info("fetching instances in batch mode",
-		zap.Int("instance_count", len(instanceIDs)),
+func (s *DriftService) DetectDrift(ctx context.Context, tfStatePath string, instanceIDs []string, attrs []string) ([]*models.DriftReport, error) {
+	s.logger.Info("starting drift detection",
+		zap.String("terraform_state", tfStatePath),
+		zap.Strings("instance_ids", instanceIDs),
+		zap.Strings("attributes", attrs),
 	)
 
-	actualStates, err := s.awsProvider.GetInstanceStatesBatch(ctx, instanceIDs)
+	attrs, err := models.ResolveAttributes(attrs)
 	if err != nil {
-		s.logger.Warn("batch fetch encountered errors",
-			zap.Error(err),
-			zap.Int("successful_fetches", len(actualStates)),
+		return nil, fmt.Errorf("invalid attributes: %w", err)
+	}
+
+	h, err := s.handlers.Get(models.ResourceTypeInstance)
+	if err != nil {
+		return nil, err
+	}
+
+	startTime := time.Now()
+
+	reports, failures, err := s.detectType(ctx, h, tfStatePath, "", instanceIDs, attrs, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return reports, s.finish(reports, failures, time.Since(startTime))
+}
+
+// detectType runs one handler: load, fetch, compare. Per-resource failures
+// are returned separately from err, which means nothing could be checked.
+// stateVersion, if set, lets unchanged state be reused; see
+// DetectRequest.StateVersion. onReport, if set, receives each report as it is
+// compared.
+func (s *DriftService) detectType(ctx context.Context, h ResourceHandler, tfStatePath, stateVersion string, ids []string, attrs []string, onReport func(*models.DriftReport)) ([]*models.DriftReport, []*ResourceError, error) {
+	ctx, span := tracer.Start(ctx, "DriftService.detectType",
+		trace.WithAttributes(attribute.String("resource.type", h.Type())))
+	defer span.End()
+
+	expected, cached, err := s.loadExpected(ctx, h, tfStatePath, stateVersion)
+	if err != nil {
+		err = fmt.Errorf("failed to parse terraform state for %s: %w", h.Type(), err)
+		recordError(span, err)
+		return nil, nil, err
+	}
+	if cached {
+		s.logger.Info("terraform state unchanged, reusing parsed resources",
+			zap.String("resource_type", h.Type()),
+			zap.String("terraform_state", tfStatePath),
+		)
+	}
+
+	if len(ids) == 0 {
+		for id := range expected {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+
+		s.logger.Info("checking all resources from state file",
+			zap.String("resource_type", h.Type()),
+			zap.Int("resource_count", len(ids)),
 		)
 	}
 
-	reports := make([]*models.DriftReport, 0, len(instanceIDs))
-	var errorMessages []string
+	var (
+		failures []*ResourceError
+		fetchIDs = make([]string, 0, len(ids))
+	)
 
-	for _, instanceID := range instanceIDs {
-		expected, existsInExpected := expectedStates[instanceID]
-		if !existsInExpected {
-			s.logger.Warn("instance not in terraform state",
-				zap.String("instance_id", instanceID),
+	for _, id := range ids {
+		if _, ok := expected[id]; !ok {
+			s.logger.Warn("resource not in terraform state",
+				zap.String("resource_type", h.Type()),
+				zap.String("resource_id", id),
 			)
-			errorMessages = append(errorMessages, fmt.Sprintf("instance %s not in terraform state", instanceID))
+			failures = append(failures, &ResourceError{
+				ResourceType: h.Type(),
+				ResourceID:   id,
+				Err:          fmt.Errorf("%s %s not in terraform state", h.Type(), id),
+			})
 			continue
 		}
+		fetchIDs = append(fetchIDs, id)
+	}
+
+	if len(fetchIDs) == 0 {
+		return nil, failures, nil
+	}
+
+	var (
+		mu       sync.Mutex
+		compared = make(map[string]*models.DriftReport, len(fetchIDs))
+		failed   = make(map[string]*ResourceError)
+	)
 
-		actual, existsInActual := actualStates[instanceID]
-		if !existsInActual {
-			s.logger.Warn("instance not fetched from AWS",
-				zap.String("instance_id", instanceID),
+	// check compares one fetched resource, or records why it could not be.
+	check := func(id string, live models.Resource, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch {
+		case err != nil:
+			failed[id] = &ResourceError{ResourceType: h.Type(), ResourceID: id, Err: err}
+		case live == nil:
+			s.logger.Warn("resource not found in AWS",
+				zap.String("resource_type", h.Type()),
+				zap.String("resource_id", id),
 			)
-			errorMessages = append(errorMessages, fmt.Sprintf("instance %s not found in AWS", instanceID))
-			continue
+			failed[id] = &ResourceError{
+				ResourceType: h.Type(),
+				ResourceID:   id,
+				Err:          fmt.Errorf("%s %s not found in AWS", h.Type(), id),
+			}
+		default:
+			report, err := s.compare(ctx, h, expected[id], live, attrs)
+			if err != nil {
+				failed[id] = &ResourceError{ResourceType: h.Type(), ResourceID: id, Err: err}
+				return
+			}
+			compared[id] = report
+			if onReport != nil {
+				onReport(report)
+			}
 		}
+	}
 
-		report := s.comparator.CompareAttributes(expected, actual, attrs)
-		reports = append(reports, report)
+	if streaming, ok := h.(StreamingHandler); ok {
+		s.fetchEach(ctx, streaming, fetchIDs, expected, attrs, check)
+	} else {
+		actual, fetchErrs := s.fetchActual(ctx, h, fetchIDs, expected, attrs)
+		for _, id := range fetchIDs {
+			check(id, actual[id], fetchErrs[id])
+		}
 	}
 
-	if len(errorMessages) > 0 {
-		summary := fmt.Sprintf("%d instance(s) failed", len(errorMessages))
-		return reports, errors.New(summary)
+	reports := make([]*models.DriftReport, 0, len(fetchIDs))
+	for _, id := range fetchIDs {
+		if failure, ok := failed[id]; ok {
+			failures = append(failures, failure)
+			continue
+		}
+		if report, ok := compared[id]; ok {
+			reports = append(reports, report)
+		}
 	}
 
-	return reports, nil
+	return reports, failures, nil
 }
 
-func (s *DriftService) detectDriftConcurrent(ctx context.Context, expectedStates map[string]*models.InstanceState, instanceIDs []string, attrs []string) ([]*models.DriftReport, error) {
-	s.logger.Info("checking instances concurrently",
-		zap.Int("instance_count", len(instanceIDs)),
-	)
+// compare compares one resource and applies the severity policy and baseline.
+func (s *DriftService) compare(ctx context.Context, h ResourceHandler, expected, live models.Resource, attrs []string) (*models.DriftReport, error) {
+	report, err := h.Compare(ctx, expected, live, attrs)
+	if err != nil {
+		return nil, err
+	}
+	s.severity.Apply(report, resourceTags(live, expected))
+	s.baseline.Apply(report, s.now())
+	if len(report.Suppressed) > 0 {
+		s.logger.Info("drift suppressed by baseline",
+			zap.String("resource_type", h.Type()),
+			zap.String("resource_id", report.InstanceID),
+			zap.Int("suppressed_count", len(report.Suppressed)),
+		)
+	}
+	if declared, ok := expected.(models.Declared); ok {
+		report.Source = declared.SourceLocation()
+	}
+	if addressed, ok := expected.(models.Addressed); ok {
+		report.Address = addressed.ResourceAddress()
+	}
+	return report, nil
+}
+
+// loadExpected loads the resources in state under a span of their own.
+func (s *DriftService) loadExpected(ctx context.Context, h ResourceHandler, tfStatePath, stateVersion string) (map[string]models.Resource, bool, error) {
+	ctx, span := tracer.Start(ctx, "ResourceHandler.LoadExpected",
+		trace.WithAttributes(attribute.String("resource.type", h.Type())))
+	defer span.End()
 
-	type result struct {
-		report *models.DriftReport
-		err    error
+	expected, cached, err := s.states.load(ctx, h, tfStatePath, stateVersion)
+	if err != nil {
+		recordError(span, err)
+		return nil, false, err
 	}
 
-	results := make(chan result, len(instanceIDs))
-	var wg sync.WaitGroup
+	span.SetAttributes(
+		attribute.Bool("terraform.state.cached", cached),
+		attribute.Int("resource.count", len(expected)),
+	)
+	return expected, cached, nil
+}
 
-	for _, instanceID := range instanceIDs {
-		wg.Add(1)
-		go func(id string) {
-			defer wg.Done()
+// fetchActual fetches the live resources under a span of their own.
+func (s *DriftService) fetchActual(ctx context.Context, h ResourceHandler, ids []string, expected map[string]models.Resource, attrs []string) (map[string]models.Resource, map[string]error) {
+	ctx, span := tracer.Start(ctx, "ResourceHandler.FetchActual", trace.WithAttributes(
+		attribute.String("resource.type", h.Type()),
+		attribute.Int("resource.count", len(ids)),
+	))
+	defer span.End()
+
+	actual, errs := h.FetchActual(ctx, ids, expected, attrs)
+	span.SetAttributes(
+		attribute.Int("resource.found_count", len(actual)),
+		attribute.Int("resource.failed_count", len(errs)),
+	)
+	return actual, errs
+}
 
-			expected, exists := expectedStates[id]
-			if !exists {
-				s.logger.Warn("instance not in terraform state",
-					zap.String("instance_id", id),
-				)
-				results <- result{
-					err: fmt.Errorf("instance %s not in terraform state", id),
-				}
-				return
-			}
+// fetchEach fetches the live resources of a streaming handler under a span
+// of their own, calling fn as each one is fetched.
+func (s *DriftService) fetchEach(ctx context.Context, h StreamingHandler, ids []string, expected map[string]models.Resource, attrs []string, fn func(id string, actual models.Resource, err error)) {
+	fetchCtx, span := tracer.Start(ctx, "ResourceHandler.FetchActual", trace.WithAttributes(
+		attribute.String("resource.type", h.Type()),
+		attribute.Int("resource.count", len(ids)),
+	))
+	defer span.End()
 
-			actual, err := s.awsProvider.GetInstanceState(ctx, id)
-			if err != nil {
-				if awspkg.IsAuthError(err) {
-					s.logger.Error("authentication error - check AWS credentials",
-						zap.String("instance_id", id),
-						zap.Error(err),
-					)
-				}
-				results <- result{err: err}
-				return
-			}
+	var (
+		mu            sync.Mutex
+		found, failed int
+	)
+	h.FetchEach(fetchCtx, ids, expected, attrs, func(id string, actual models.Resource, err error) {
+		mu.Lock()
+		switch {
+		case err != nil:
+			failed++
+		case actual != nil:
+			found++
+		}
+		mu.Unlock()
 
-			report := s.comparator.CompareAttributes(expected, actual, attrs)
-			results <- result{report: report}
-		}(instanceID)
-	}
+		fn(id, actual, err)
+	})
 
-	go func() {
-		wg.Wait()
-		close(results)
-	}()
+	span.SetAttributes(
+		attribute.Int("resource.found_count", found),
+		attribute.Int("resource.failed_count", failed),
+	)
+}
 
-	reports := make([]*models.DriftReport, 0, len(instanceIDs))
-	var errorMessages []string
-	var authErrors int
+// recordError marks span as failed with err.
+func recordError(span trace.Span, err error) {
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
 
-	for res := range results {
-		if res.err != nil {
-			if awspkg.IsAuthError(res.err) {
-				authErrors++
-			}
-			errorMessages = append(errorMessages, res.err.Error())
-		} else {
-			reports = append(reports, res.report)
+// resourceTags returns the tags severity rules are matched against: the live
+// tags when the resource has any, otherwise those in state.
+func resourceTags(resources ...models.Resource) map[string]string {
+	for _, r := range resources {
+		if tagged, ok := r.(models.TaggedResource); ok && len(tagged.ResourceTags()) > 0 {
+			return tagged.ResourceTags()
 		}
 	}
+	return nil
+}
 
-	if len(errorMessages) > 0 {
-		s.logger.Warn("some instances could not be checked",
-			zap.Int("error_count", len(errorMessages)),
+func (s *DriftService) finish(reports []*models.DriftReport, failures []*ResourceError, duration time.Duration) error {
+	err := summarizeFailures(failures)
+	if err != nil {
+		s.logger.Error("drift detection encountered errors",
+			zap.Duration("duration", duration),
+			zap.Int("error_count", len(failures)),
 			zap.Int("success_count", len(reports)),
-			zap.Int("auth_errors", authErrors),
+			zap.Error(err),
 		)
+	}
 
-		summary := fmt.Sprintf("%d instance(s) failed", len(errorMessages))
-		if len(errorMessages) == 1 {
-			summary = errorMessages[0]
-		} else if authErrors > 0 {
-			summary = fmt.Sprintf("%d instance(s) failed (%d authentication errors)", len(errorMessages), authErrors)
+	driftCount := 0
+	for _, report := range reports {
+		if report.HasDrift {
+			driftCount++
 		}
+	}
+
+	s.logger.Info("drift detection completed",
+		zap.Duration("duration", duration),
+		zap.Int("total_resources", len(reports)),
+		zap.Int("resources_with_drift", driftCount),
+	)
 
-		return reports, errors.New(summary)
+	return err
+}
+
+func summarizeFailures(failures []*ResourceError) error {
+	switch len(failures) {
+	case 0:
+		return nil
+	case 1:
+		return &PartialFailureError{Failures: failures, msg: failures[0].Error()}
+	}
+
+	authErrors := 0
+	for _, err := range failures {
+		if awspkg.IsAuthError(err) {
+			authErrors++
+		}
 	}
 
-	return reports, nil
+	msg := fmt.Sprintf("%d resource(s) failed", len(failures))
+	if authErrors > 0 {
+		msg = fmt.Sprintf("%d resource(s) failed (%d authentication errors)", len(failures), authErrors)
+	}
+	return &PartialFailureError{Failures: failures, msg: msg}
 }
 
 func (s *DriftService) DetectSingleDrift(ctx context.Context, tfStatePath, instanceID string, attrs []string) (*models.DriftReport, error) {
@@ -240,23 +558,23 @@ func (s *DriftService) DetectSingleDrift(ctx context.Context, tfStatePath, insta
 		zap.Strings("attributes", attrs),
 	)
 
-	expectedStates, err := s.tfParser.ParseStateFile(tfStatePath)
+	attrs, err := models.ResolveAttributes(attrs)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse terraform state: %w", err)
+		return nil, fmt.Errorf("invalid attributes: %w", err)
 	}
 
-	expected, exists := expectedStates[instanceID]
-	if !exists {
-		s.logger.Error("instance not found in terraform state",
-			zap.String("instance_id", instanceID),
-		)
-		return nil, fmt.Errorf("instance %s not found in terraform state", instanceID)
+	h, err := s.handlers.Get(models.ResourceTypeInstance)
+	if err != nil {
+		return nil, err
 	}
 
-	actual, err := s.awsProvider.GetInstanceState(ctx, instanceID)
+	reports, failures, err := s.detectType(ctx, h, tfStatePath, "", []string{instanceID}, attrs, nil)
 	if err != nil {
 		return nil, err
 	}
+	if len(failures) > 0 {
+		return nil, failures[0]
+	}
 
-	return s.comparator.CompareAttributes(expected, actual, attrs), nil
+	return reports[0], nil
 }
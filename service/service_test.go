@@ -2,9 +2,18 @@ package service
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	awspkg "firefly-ec2-drift-detector/aws"
 	flog "firefly-ec2-drift-detector/logger"
 	"firefly-ec2-drift-detector/models"
 )
@@ -12,9 +21,11 @@ import (
 type fakeParser struct {
 	states map[string]*models.InstanceState
 	err    error
+	calls  int
 }
 
-func (f *fakeParser) ParseStateFile(_ string) (map[string]*models.InstanceState, error) {
+func (f *fakeParser) ParseStateFile(_ context.Context, _ string) (map[string]*models.InstanceState, error) {
+	f.calls++
 	if f.err != nil {
 		return nil, f.err
 	}
@@ -28,7 +39,7 @@ type fakeProvider struct {
 	batchErr    error
 }
 
-func (f *fakeProvider) GetInstanceState(_ context.Context, id string) (*models.InstanceState, error) {
+func (f *fakeProvider) GetInstanceState(_ context.Context, id string, _ ...awspkg.FetchOption) (*models.InstanceState, error) {
 	if err, ok := f.errs[id]; ok {
 		return nil, err
 	}
@@ -38,7 +49,7 @@ func (f *fakeProvider) GetInstanceState(_ context.Context, id string) (*models.I
 	return nil, errors.New("instance not found")
 }
 
-func (f *fakeProvider) GetInstanceStatesBatch(_ context.Context, instanceIDs []string) (map[string]*models.InstanceState, error) {
+func (f *fakeProvider) GetInstanceStatesBatch(_ context.Context, instanceIDs []string, _ ...awspkg.FetchOption) (map[string]*models.InstanceState, error) {
 	if f.batchErr != nil {
 		return nil, f.batchErr
 	}
@@ -60,16 +71,16 @@ type fakeComparator struct {
 	report *models.DriftReport
 }
 
-func (f *fakeComparator) CompareAttributes(_, actual *models.InstanceState, _ []string) *models.DriftReport {
+func (f *fakeComparator) CompareAttributes(_ context.Context, _, actual *models.InstanceState, _ []string) (*models.DriftReport, error) {
 	if f.report != nil {
 		reportCopy := *f.report
 		reportCopy.InstanceID = actual.InstanceID
-		return &reportCopy
+		return &reportCopy, nil
 	}
 	return &models.DriftReport{
 		InstanceID: actual.InstanceID,
 		HasDrift:   false,
-	}
+	}, nil
 }
 
 func newTestLogger() *flog.Logger {
@@ -394,6 +405,32 @@ func TestDetectDrift_InstanceNotInExpectedState(t *testing.T) {
 	}
 }
 
+func TestDetectDrift_BatchFetchErrorIsNotMissingInstance(t *testing.T) {
+	states := make(map[string]*models.InstanceState)
+	for i := 0; i < 15; i++ {
+		id := "i-" + string(rune('a'+i))
+		states[id] = &models.InstanceState{InstanceID: id}
+	}
+
+	provider := &fakeProvider{batchErr: errors.New("batch fetch encountered 1 error(s)")}
+	svc := NewDriftService(provider, &fakeParser{states: states}, &fakeComparator{}, newTestLogger())
+
+	reports, err := svc.DetectDrift(context.Background(), "state.tf", nil, nil)
+	if len(reports) != 0 {
+		t.Fatalf("expected no reports, got %d", len(reports))
+	}
+
+	failures := Failures(err)
+	if len(failures) != 15 {
+		t.Fatalf("expected every instance to fail, got %d failures", len(failures))
+	}
+	for _, failure := range failures {
+		if !strings.Contains(failure.Error(), "failed to fetch instance") {
+			t.Errorf("expected a fetch failure, got %v", failure)
+		}
+	}
+}
+
 func TestDetectDrift_BatchModeTrigger(t *testing.T) {
 	ctx := context.Background()
 
@@ -430,3 +467,376 @@ func TestDetectDrift_BatchModeTrigger(t *testing.T) {
 		t.Fatalf("expected 11 reports, got %d", len(reports))
 	}
 }
+
+func TestDetectDrift_InvalidAttributePath(t *testing.T) {
+	parser := &fakeParser{
+		states: map[string]*models.InstanceState{
+			"i-1": {InstanceID: "i-1"},
+		},
+	}
+
+	svc := NewDriftService(&fakeProvider{}, parser, &fakeComparator{}, newTestLogger())
+
+	_, err := svc.DetectDrift(context.Background(), "state.tf", []string{"i-1"}, []string{"InstanceType", "NotAField"})
+	if err == nil {
+		t.Fatalf("expected error for unknown attribute")
+	}
+
+	_, err = svc.DetectSingleDrift(context.Background(), "state.tf", "i-1", []string{"Tags["})
+	if err == nil {
+		t.Fatalf("expected error for malformed attribute path")
+	}
+}
+
+func TestDetectDrift_SeverityPolicy(t *testing.T) {
+	parser := &fakeParser{
+		states: map[string]*models.InstanceState{
+			"i-1": {InstanceID: "i-1", InstanceType: "t3.micro"},
+		},
+	}
+	provider := &fakeProvider{
+		states: map[string]*models.InstanceState{
+			"i-1": {InstanceID: "i-1", InstanceType: "t3.large", Tags: map[string]string{"Environment": "prod"}},
+		},
+	}
+
+	svc := NewDriftService(provider, parser, models.NewAttributeComparator(newTestLogger()), newTestLogger())
+
+	reports, err := svc.DetectDrift(context.Background(), "state.tf", []string{"i-1"}, []string{"InstanceType"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := reports[0].Severity; got != models.SeverityMedium {
+		t.Errorf("expected the default policy to rate InstanceType medium, got %s", got)
+	}
+
+	svc.SetSeverityPolicy(&models.SeverityPolicy{
+		Default: models.SeverityLow,
+		Rules: []models.SeverityRule{
+			{Tags: map[string]string{"Environment": "prod"}, Severity: models.SeverityCritical},
+		},
+	})
+
+	reports, err = svc.DetectDrift(context.Background(), "state.tf", []string{"i-1"}, []string{"InstanceType"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := reports[0].Drifts[0].Severity; got != models.SeverityCritical {
+		t.Errorf("expected the live prod tag to escalate the drift, got %s", got)
+	}
+}
+
+func TestDetectDrift_ReportsSourceLocation(t *testing.T) {
+	source := &models.SourceLocation{File: "infra/main.tf", StartLine: 12, EndLine: 20}
+	parser := &fakeParser{
+		states: map[string]*models.InstanceState{
+			"i-1": {InstanceID: "i-1", InstanceType: "t3.micro", Source: source},
+		},
+	}
+	provider := &fakeProvider{
+		states: map[string]*models.InstanceState{
+			"i-1": {InstanceID: "i-1", InstanceType: "t3.large"},
+		},
+	}
+
+	svc := NewDriftService(provider, parser, models.NewAttributeComparator(newTestLogger()), newTestLogger())
+
+	reports, err := svc.DetectDrift(context.Background(), "infra", []string{"i-1"}, []string{"InstanceType"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := reports[0].Source; got == nil || *got != *source {
+		t.Errorf("expected the report to point at %+v, got %+v", source, got)
+	}
+}
+
+func TestDetectDrift_PartialFailure_ReportsFailedResources(t *testing.T) {
+	parser := &fakeParser{
+		states: map[string]*models.InstanceState{
+			"i-1": {InstanceID: "i-1"},
+			"i-2": {InstanceID: "i-2"},
+		},
+	}
+	provider := &fakeProvider{
+		states: map[string]*models.InstanceState{
+			"i-1": {InstanceID: "i-1"},
+		},
+		errs: map[string]error{
+			"i-2": errors.New("boom"),
+		},
+	}
+
+	svc := NewDriftService(provider, parser, &fakeComparator{report: &models.DriftReport{}}, newTestLogger())
+
+	_, err := svc.DetectDrift(context.Background(), "state.tf", []string{"i-1", "i-2", "i-3"}, nil)
+	if err == nil {
+		t.Fatal("expected error for partial failure")
+	}
+	if err.Error() != "2 resource(s) failed" {
+		t.Errorf("unexpected summary: %v", err)
+	}
+
+	failures := Failures(err)
+	if len(failures) != 2 {
+		t.Fatalf("expected 2 failed resources, got %d", len(failures))
+	}
+
+	got := map[string]string{}
+	for _, f := range failures {
+		if f.ResourceType != models.ResourceTypeInstance {
+			t.Errorf("unexpected resource type for %s: %s", f.ResourceID, f.ResourceType)
+		}
+		got[f.ResourceID] = f.Error()
+	}
+	if got["i-2"] != "boom" {
+		t.Errorf("expected the fetch error for i-2, got %q", got["i-2"])
+	}
+	if got["i-3"] != "aws_instance i-3 not in terraform state" {
+		t.Errorf("expected i-3 to be missing from state, got %q", got["i-3"])
+	}
+
+	if Failures(errors.New("other")) != nil {
+		t.Error("expected no failures from an unrelated error")
+	}
+}
+
+func TestDetectResources_OnReport(t *testing.T) {
+	parser := &fakeParser{
+		states: map[string]*models.InstanceState{
+			"i-1": {InstanceID: "i-1", InstanceType: "t3.micro", Address: "aws_instance.web"},
+			"i-2": {InstanceID: "i-2", InstanceType: "t3.micro", Address: "aws_instance.api"},
+		},
+	}
+	provider := &fakeProvider{
+		states: map[string]*models.InstanceState{
+			"i-1": {InstanceID: "i-1", InstanceType: "t3.large"},
+			"i-2": {InstanceID: "i-2", InstanceType: "t3.micro"},
+		},
+	}
+
+	svc := NewDriftService(provider, parser, models.NewAttributeComparator(newTestLogger()), newTestLogger())
+
+	var streamed []*models.DriftReport
+	reports, err := svc.DetectResources(context.Background(), "state.tf", DetectRequest{
+		Attributes: map[string][]string{models.ResourceTypeInstance: {"InstanceType"}},
+		OnReport: func(report *models.DriftReport) {
+			streamed = append(streamed, report)
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Reports stream as they are compared; the result is in ID order.
+	if len(streamed) != len(reports) {
+		t.Fatalf("expected every report to be streamed, got %d of %d", len(streamed), len(reports))
+	}
+	for _, report := range reports {
+		if !slices.Contains(streamed, report) {
+			t.Errorf("expected %s to be streamed", report.InstanceID)
+		}
+	}
+	if reports[0].InstanceID != "i-1" || reports[1].InstanceID != "i-2" {
+		t.Errorf("expected reports in ID order, got %s and %s", reports[0].InstanceID, reports[1].InstanceID)
+	}
+
+	if reports[0].Address != "aws_instance.web" || reports[1].Address != "aws_instance.api" {
+		t.Errorf("expected reports to carry Terraform addresses, got %q and %q", reports[0].Address, reports[1].Address)
+	}
+}
+
+// gatedProvider holds back i-slow until i-fast has been reported.
+type gatedProvider struct {
+	fakeProvider
+	release chan struct{}
+}
+
+func (p *gatedProvider) GetInstanceState(ctx context.Context, id string, opts ...awspkg.FetchOption) (*models.InstanceState, error) {
+	if id == "i-slow" {
+		select {
+		case <-p.release:
+		case <-time.After(2 * time.Second):
+			return nil, errors.New("i-fast was not reported before i-slow was fetched")
+		}
+	}
+	return p.fakeProvider.GetInstanceState(ctx, id, opts...)
+}
+
+func TestDetectResources_OnReportDoesNotWaitForOtherFetches(t *testing.T) {
+	parser := &fakeParser{
+		states: map[string]*models.InstanceState{
+			"i-fast": {InstanceID: "i-fast", InstanceType: "t3.micro"},
+			"i-slow": {InstanceID: "i-slow", InstanceType: "t3.micro"},
+		},
+	}
+	provider := &gatedProvider{
+		fakeProvider: fakeProvider{states: parser.states},
+		release:      make(chan struct{}),
+	}
+
+	svc := NewDriftService(provider, parser, models.NewAttributeComparator(newTestLogger()), newTestLogger())
+
+	var streamed []string
+	reports, err := svc.DetectResources(context.Background(), "state.tf", DetectRequest{
+		Types: []string{models.ResourceTypeInstance},
+		OnReport: func(report *models.DriftReport) {
+			streamed = append(streamed, report.InstanceID)
+			if report.InstanceID == "i-fast" {
+				close(provider.release)
+			}
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(reports) != 2 || !slices.Equal(streamed, []string{"i-fast", "i-slow"}) {
+		t.Errorf("expected i-fast to be reported before i-slow, got %v", streamed)
+	}
+}
+
+func TestDetectDrift_Baseline(t *testing.T) {
+	parser := &fakeParser{
+		states: map[string]*models.InstanceState{
+			"i-1": {InstanceID: "i-1", InstanceType: "t3.micro"},
+			"i-2": {InstanceID: "i-2", InstanceType: "t3.micro"},
+		},
+	}
+	provider := &fakeProvider{
+		states: map[string]*models.InstanceState{
+			"i-1": {InstanceID: "i-1", InstanceType: "t3.large"},
+			"i-2": {InstanceID: "i-2", InstanceType: "t3.large"},
+		},
+	}
+
+	svc := NewDriftService(provider, parser, models.NewAttributeComparator(newTestLogger()), newTestLogger())
+	svc.now = func() time.Time { return time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC) }
+	svc.SetBaseline(&models.Baseline{Suppressions: []models.BaselineEntry{
+		{ResourceID: "i-1", Attribute: "InstanceType", Expected: json.RawMessage(`"t3.micro"`), Actual: json.RawMessage(`"t3.large"`), Reason: "resize pending"},
+	}})
+
+	reports, err := svc.DetectDrift(context.Background(), "state.tf", []string{"i-1", "i-2"}, []string{"InstanceType"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if reports[0].HasDrift || len(reports[0].Suppressed) != 1 || reports[0].Suppressed[0].Reason != "resize pending" {
+		t.Errorf("expected i-1's drift to be suppressed, got %+v", reports[0])
+	}
+	if !reports[1].HasDrift || len(reports[1].Suppressed) != 0 {
+		t.Errorf("expected i-2's drift to be reported, got %+v", reports[1])
+	}
+}
+
+func TestDetectResources_StateVersionReusesParsedState(t *testing.T) {
+	parser := &fakeParser{
+		states: map[string]*models.InstanceState{
+			"i-1": {InstanceID: "i-1", InstanceType: "t3.micro"},
+		},
+	}
+	provider := &fakeProvider{
+		states: map[string]*models.InstanceState{
+			"i-1": {InstanceID: "i-1", InstanceType: "t3.large"},
+		},
+	}
+
+	svc := NewDriftService(provider, parser, models.NewAttributeComparator(newTestLogger()), newTestLogger())
+
+	for i, version := range []string{"v1", "v1", "v2", ""} {
+		req := DetectRequest{
+			Types:        []string{models.ResourceTypeInstance},
+			IDs:          map[string][]string{models.ResourceTypeInstance: {"i-1"}},
+			Attributes:   map[string][]string{models.ResourceTypeInstance: {"InstanceType"}},
+			StateVersion: version,
+		}
+		reports, err := svc.DetectResources(context.Background(), "state.tf", req)
+		if err != nil {
+			t.Fatalf("scan %d: unexpected error: %v", i+1, err)
+		}
+		if len(reports) != 1 || !reports[0].HasDrift {
+			t.Fatalf("scan %d: expected drift on every scan, got %+v", i+1, reports)
+		}
+	}
+
+	// v1 is parsed once, v2 once, and an empty version always.
+	if parser.calls != 3 {
+		t.Errorf("expected the state to be parsed 3 times, got %d", parser.calls)
+	}
+}
+
+func TestDetectResources_Traces(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	parser := &fakeParser{
+		states: map[string]*models.InstanceState{
+			"i-1": {InstanceID: "i-1", InstanceType: "t3.micro"},
+			"i-2": {InstanceID: "i-2", InstanceType: "t3.micro"},
+		},
+	}
+	provider := &fakeProvider{
+		states: map[string]*models.InstanceState{
+			"i-1": {InstanceID: "i-1", InstanceType: "t3.large"},
+			"i-2": {InstanceID: "i-2", InstanceType: "t3.micro"},
+		},
+	}
+
+	svc := NewDriftService(provider, parser, models.NewAttributeComparator(newTestLogger()), newTestLogger())
+	if _, err := svc.DetectResources(context.Background(), "state.tf", DetectRequest{
+		Types:      []string{models.ResourceTypeInstance},
+		Attributes: map[string][]string{models.ResourceTypeInstance: {"InstanceType"}},
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	byName := make(map[string][]sdktrace.ReadOnlySpan)
+	for _, span := range recorder.Ended() {
+		byName[span.Name()] = append(byName[span.Name()], span)
+	}
+
+	root := byName["DriftService.DetectResources"]
+	detect := byName["DriftService.detectType"]
+	if len(root) != 1 || len(detect) != 1 {
+		t.Fatalf("expected one scan and one resource type span, got %v", byName)
+	}
+	if detect[0].Parent().SpanID() != root[0].SpanContext().SpanID() {
+		t.Error("expected the resource type span under the scan span")
+	}
+
+	for name, want := range map[string]int{
+		"ResourceHandler.LoadExpected":          1,
+		"ResourceHandler.FetchActual":           1,
+		"AttributeComparator.CompareAttributes": 2,
+	} {
+		spans := byName[name]
+		if len(spans) != want {
+			t.Errorf("expected %d %s span(s), got %d", want, name, len(spans))
+			continue
+		}
+		for _, span := range spans {
+			if span.Parent().SpanID() != detect[0].SpanContext().SpanID() {
+				t.Errorf("expected %s under the resource type span", name)
+			}
+		}
+	}
+}
+
+func TestFetchOptions_RootVolumesOnlyWhenCompared(t *testing.T) {
+	tests := []struct {
+		attrs []string
+		want  int
+	}{
+		{attrs: []string{"InstanceType"}, want: 0},
+		{attrs: []string{"InstanceType", "MetadataOptions"}, want: 0},
+		{attrs: []string{"RootBlockDevice"}, want: 1},
+		{attrs: []string{"Tags", "RootBlockDevice.Encrypted"}, want: 1},
+	}
+
+	for _, tt := range tests {
+		if got := len(fetchOptions(tt.attrs)); got != tt.want {
+			t.Errorf("fetchOptions(%v) returned %d option(s), want %d", tt.attrs, got, tt.want)
+		}
+	}
+}
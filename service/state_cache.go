@@ -0,0 +1,61 @@
+package service
+
+import (
+	"context"
+	"maps"
+	"sync"
+
+	"firefly-ec2-drift-detector/models"
+)
+
+// stateCache keeps the resources each handler loaded from a state path, for
+// as long as callers report the same version of that path.
+type stateCache struct {
+	mu      sync.Mutex
+	entries map[stateCacheKey]cachedState
+}
+
+type stateCacheKey struct {
+	resourceType string
+	path         string
+}
+
+type cachedState struct {
+	version   string
+	resources map[string]models.Resource
+}
+
+func newStateCache() *stateCache {
+	return &stateCache{entries: make(map[stateCacheKey]cachedState)}
+}
+
+// load returns the resources of h at path, calling h.LoadExpected only when
+// version is empty or differs from the cached one. Each call gets a map of its
+// own, since handlers may replace resources in it; the resources themselves
+// are shared and must not be modified.
+func (c *stateCache) load(ctx context.Context, h ResourceHandler, path, version string) (map[string]models.Resource, bool, error) {
+	if version == "" {
+		resources, err := h.LoadExpected(ctx, path)
+		return resources, false, err
+	}
+
+	key := stateCacheKey{resourceType: h.Type(), path: path}
+
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	c.mu.Unlock()
+	if ok && entry.version == version {
+		return maps.Clone(entry.resources), true, nil
+	}
+
+	resources, err := h.LoadExpected(ctx, path)
+	if err != nil {
+		return nil, false, err
+	}
+
+	c.mu.Lock()
+	c.entries[key] = cachedState{version: version, resources: maps.Clone(resources)}
+	c.mu.Unlock()
+
+	return resources, false, nil
+}
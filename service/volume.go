@@ -0,0 +1,116 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"firefly-ec2-drift-detector/models"
+)
+
+// VolumeProvider is implemented by state providers that can fetch EBS
+// volumes.
+type VolumeProvider interface {
+	GetVolumeStates(ctx context.Context, volumeIDs []string) (map[string]*models.VolumeState, error)
+}
+
+// VolumeParser is implemented by parsers that can read aws_ebs_volume and
+// aws_volume_attachment resources.
+type VolumeParser interface {
+	ParseVolumes(path string) (map[string]*models.VolumeState, error)
+}
+
+type volumeHandler struct {
+	provider   VolumeProvider
+	parser     VolumeParser
+	comparator models.ResourceComparator
+}
+
+// NewVolumeHandler returns the aws_ebs_volume handler.
+func NewVolumeHandler(provider VolumeProvider, parser VolumeParser, comparator models.ResourceComparator) ResourceHandler {
+	return &volumeHandler{
+		provider:   provider,
+		parser:     parser,
+		comparator: comparator,
+	}
+}
+
+func (h *volumeHandler) Type() string {
+	return models.ResourceTypeVolume
+}
+
+func (h *volumeHandler) Attributes() *models.AttributeRegistry {
+	return models.VolumeAttributes
+}
+
+func (h *volumeHandler) LoadExpected(_ context.Context, path string) (map[string]models.Resource, error) {
+	volumes, err := h.parser.ParseVolumes(path)
+	if err != nil {
+		return nil, err
+	}
+
+	resources := make(map[string]models.Resource, len(volumes))
+	for id, volume := range volumes {
+		resources[id] = volume
+	}
+	return resources, nil
+}
+
+func (h *volumeHandler) FetchActual(ctx context.Context, ids []string, _ map[string]models.Resource, _ []string) (map[string]models.Resource, map[string]error) {
+	var (
+		volumeIDs []string
+		errs      = make(map[string]error)
+	)
+
+	for _, id := range ids {
+		if isConfigOnlyID(id) {
+			errs[id] = fmt.Errorf("volume %s is only declared in configuration and has no ID to look up", id)
+			continue
+		}
+		volumeIDs = append(volumeIDs, id)
+	}
+
+	if len(volumeIDs) == 0 {
+		return nil, errs
+	}
+
+	volumes, err := h.provider.GetVolumeStates(ctx, volumeIDs)
+	if err != nil {
+		for _, id := range volumeIDs {
+			errs[id] = fmt.Errorf("failed to fetch volume %s: %w", id, err)
+		}
+		return nil, errs
+	}
+
+	resources := make(map[string]models.Resource, len(volumes))
+	for id, volume := range volumes {
+		resources[id] = volume
+	}
+	return resources, errs
+}
+
+// Compare runs the generic attribute comparison, then explains attachment
+// drift in terms of the instance the volume should be attached to.
+func (h *volumeHandler) Compare(_ context.Context, expected, actual models.Resource, attrs []string) (*models.DriftReport, error) {
+	report, err := h.comparator.CompareResource(h.Type(), models.VolumeAttributes, expected, actual, attrs)
+	if err != nil {
+		return nil, err
+	}
+
+	driftType, details := models.DescribeAttachmentDrift(
+		expected.(*models.VolumeState).Attachment,
+		actual.(*models.VolumeState).Attachment,
+	)
+	if details == "" {
+		return report, nil
+	}
+
+	for i := range report.Drifts {
+		if strings.HasPrefix(report.Drifts[i].AttributeName, "Attachment") {
+			report.Drifts[i].DriftType = driftType
+			report.Drifts[i].Details = details
+		}
+	}
+
+	return report, nil
+}
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"firefly-ec2-drift-detector/models"
+)
+
+type fakeVolumeParser struct {
+	fakeParser
+	volumes map[string]*models.VolumeState
+}
+
+func (f *fakeVolumeParser) ParseVolumes(_ string) (map[string]*models.VolumeState, error) {
+	return f.volumes, nil
+}
+
+type fakeVolumeProvider struct {
+	fakeProvider
+	volumes map[string]*models.VolumeState
+}
+
+func (f *fakeVolumeProvider) GetVolumeStates(_ context.Context, volumeIDs []string) (map[string]*models.VolumeState, error) {
+	result := make(map[string]*models.VolumeState)
+	for _, id := range volumeIDs {
+		if volume, ok := f.volumes[id]; ok {
+			result[id] = volume
+		}
+	}
+	return result, nil
+}
+
+func TestDetectResources_VolumeAttachmentDrift(t *testing.T) {
+	parser := &fakeVolumeParser{
+		volumes: map[string]*models.VolumeState{
+			"vol-1": {VolumeID: "vol-1", Size: 10, Attachment: models.VolumeAttachment{InstanceID: "i-1", DeviceName: "/dev/sdf"}},
+			"vol-2": {VolumeID: "vol-2", Size: 10, Attachment: models.VolumeAttachment{InstanceID: "i-1", DeviceName: "/dev/sdg"}},
+		},
+	}
+	provider := &fakeVolumeProvider{
+		volumes: map[string]*models.VolumeState{
+			"vol-1": {VolumeID: "vol-1", Size: 10},
+			"vol-2": {VolumeID: "vol-2", Size: 10, Attachment: models.VolumeAttachment{InstanceID: "i-2", DeviceName: "/dev/sdg"}},
+		},
+	}
+
+	comparator := models.NewAttributeComparator(newTestLogger())
+	svc := NewDriftService(provider, parser, comparator, newTestLogger())
+
+	reports, err := svc.DetectResources(context.Background(), "state.tfstate", DetectRequest{
+		Types: []string{models.ResourceTypeVolume},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(reports) != 2 {
+		t.Fatalf("expected 2 reports, got %d", len(reports))
+	}
+
+	wantDetails := map[string]string{
+		"vol-1": "volume is detached from i-1",
+		"vol-2": "volume is attached to i-2 instead of i-1",
+	}
+
+	for _, report := range reports {
+		var found bool
+		for _, drift := range report.Drifts {
+			if drift.AttributeName == "Attachment" {
+				found = true
+				if drift.Details != wantDetails[report.InstanceID] {
+					t.Errorf("%s: details = %q, want %q", report.InstanceID, drift.Details, wantDetails[report.InstanceID])
+				}
+			}
+		}
+		if !found {
+			t.Errorf("%s: expected attachment drift, got %+v", report.InstanceID, report.Drifts)
+		}
+	}
+}
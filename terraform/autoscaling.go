@@ -0,0 +1,150 @@
+package terraform
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"go.uber.org/zap"
+
+	"firefly-ec2-drift-detector/models"
+)
+
+const resourceTypeLaunchTemplate = "aws_launch_template"
+
+// ParseAutoScalingGroups reads Auto Scaling groups from a state file, a .tf
+// file or a directory of .tf files, each with the launch template it
+// references when that template is declared alongside it.
+func (p *TerraformClient) ParseAutoScalingGroups(path string) (map[string]*models.AutoScalingGroupState, error) {
+	resources, err := p.ParseResources(path, models.ResourceTypeAutoScalingGroup, resourceTypeLaunchTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	var templates []*models.LaunchTemplateState
+	for _, res := range resources {
+		if res.Type != resourceTypeLaunchTemplate {
+			continue
+		}
+
+		var attrs LaunchTemplateAttributes
+		if err := json.Unmarshal(res.Attributes, &attrs); err != nil {
+			return nil, fmt.Errorf("failed to decode %s.%s: %w", res.Type, res.Name, err)
+		}
+		if attrs.ID == "" {
+			attrs.ID = "hcl:" + res.Name
+		}
+
+		templates = append(templates, mapLaunchTemplate(attrs))
+	}
+
+	groups := make(map[string]*models.AutoScalingGroupState)
+	for _, res := range resources {
+		if res.Type != models.ResourceTypeAutoScalingGroup {
+			continue
+		}
+
+		var attrs AutoScalingGroupAttributes
+		if err := json.Unmarshal(res.Attributes, &attrs); err != nil {
+			return nil, fmt.Errorf("failed to decode %s.%s: %w", res.Type, res.Name, err)
+		}
+		if attrs.ID == "" {
+			attrs.ID = "hcl:" + res.Name
+		}
+
+		group := &models.AutoScalingGroupState{
+			Name:            attrs.Name,
+			MinSize:         attrs.MinSize,
+			MaxSize:         attrs.MaxSize,
+			DesiredCapacity: attrs.DesiredCapacity,
+			LaunchTemplate:  launchTemplateSpec(attrs),
+			Source:          res.Source,
+			Address:         res.Address,
+		}
+		group.Template = findLaunchTemplate(templates, group.LaunchTemplate)
+
+		if group.Template == nil {
+			p.logger.Debug("auto scaling group references a launch template not in state",
+				zap.String("resource_name", res.Name),
+				zap.String("launch_template", group.LaunchTemplate.ID+group.LaunchTemplate.Name),
+			)
+		}
+
+		groups[attrs.ID] = group
+	}
+
+	p.logger.Info("successfully parsed auto scaling groups",
+		zap.String("path", path),
+		zap.Int("group_count", len(groups)),
+		zap.Int("launch_template_count", len(templates)),
+	)
+
+	return groups, nil
+}
+
+func launchTemplateSpec(attrs AutoScalingGroupAttributes) models.LaunchTemplateSpec {
+	if len(attrs.LaunchTemplate) > 0 {
+		lt := attrs.LaunchTemplate[0]
+		return models.LaunchTemplateSpec{ID: lt.ID, Name: lt.Name, Version: lt.Version}
+	}
+
+	for _, policy := range attrs.MixedInstancesPolicy {
+		for _, lt := range policy.LaunchTemplate {
+			for _, spec := range lt.LaunchTemplateSpecification {
+				return models.LaunchTemplateSpec{
+					ID:      spec.LaunchTemplateID,
+					Name:    spec.LaunchTemplateName,
+					Version: spec.Version,
+				}
+			}
+		}
+	}
+
+	return models.LaunchTemplateSpec{}
+}
+
+func mapLaunchTemplate(attrs LaunchTemplateAttributes) *models.LaunchTemplateState {
+	template := &models.LaunchTemplateState{
+		ID:             attrs.ID,
+		Name:           attrs.Name,
+		LatestVersion:  attrs.LatestVersion,
+		DefaultVersion: attrs.DefaultVersion,
+		ImageID:        attrs.ImageID,
+		InstanceType:   attrs.InstanceType,
+		KeyName:        attrs.KeyName,
+		SecurityGroups: attrs.VpcSecurityGroupIDs,
+	}
+
+	// Templates that configure network interfaces put security groups there.
+	if len(template.SecurityGroups) == 0 && len(attrs.NetworkInterfaces) > 0 {
+		template.SecurityGroups = attrs.NetworkInterfaces[0].SecurityGroups
+	}
+
+	if len(attrs.Monitoring) > 0 {
+		enabled := attrs.Monitoring[0].Enabled
+		template.Monitoring = &enabled
+	}
+
+	if len(attrs.MetadataOptions) > 0 {
+		mo := attrs.MetadataOptions[0]
+		template.MetadataOptions = models.MetadataOptions{
+			HttpEndpoint:            mo.HttpEndpoint,
+			HttpTokens:              mo.HttpTokens,
+			HttpPutResponseHopLimit: mo.HttpPutResponseHopLimit,
+			InstanceMetadataTags:    mo.InstanceMetadataTags,
+		}
+	}
+
+	return template
+}
+
+func findLaunchTemplate(templates []*models.LaunchTemplateState, spec models.LaunchTemplateSpec) *models.LaunchTemplateState {
+	for _, t := range templates {
+		if spec.ID != "" && t.ID == spec.ID {
+			return t
+		}
+		if spec.Name != "" && t.Name == spec.Name {
+			return t
+		}
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package terraform
+
+import "testing"
+
+func TestParseAutoScalingGroups_StateFile(t *testing.T) {
+	tfState := `{
+		"version": 4,
+		"resources": [
+			{
+				"mode": "managed",
+				"type": "aws_launch_template",
+				"name": "web",
+				"instances": [{
+					"attributes": {
+						"id": "lt-1",
+						"name": "web",
+						"latest_version": 4,
+						"default_version": 1,
+						"image_id": "ami-1",
+						"instance_type": "t3.micro",
+						"vpc_security_group_ids": [],
+						"monitoring": [{"enabled": true}],
+						"network_interfaces": [{"security_groups": ["sg-1"]}],
+						"metadata_options": [{"http_tokens": "required"}]
+					}
+				}]
+			},
+			{
+				"mode": "managed",
+				"type": "aws_autoscaling_group",
+				"name": "web",
+				"instances": [{
+					"attributes": {
+						"id": "web-asg",
+						"name": "web-asg",
+						"min_size": 1,
+						"max_size": 3,
+						"desired_capacity": 2,
+						"launch_template": [{"id": "lt-1", "name": "web", "version": "$Latest"}]
+					}
+				}]
+			},
+			{
+				"mode": "managed",
+				"type": "aws_autoscaling_group",
+				"name": "batch",
+				"instances": [{
+					"attributes": {
+						"id": "batch-asg",
+						"name": "batch-asg",
+						"mixed_instances_policy": [{
+							"launch_template": [{
+								"launch_template_specification": [{"launch_template_id": "lt-9", "version": "2"}]
+							}]
+						}]
+					}
+				}]
+			}
+		]
+	}`
+
+	client := NewTerraformClient(newTestLogger())
+
+	groups, err := client.ParseAutoScalingGroups(writeTempFile(t, tfState))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(groups) != 2 {
+		t.Fatalf("expected 2 groups, got %d", len(groups))
+	}
+
+	web := groups["web-asg"]
+	if web.MaxSize != 3 || web.LaunchTemplate.Version != "$Latest" {
+		t.Errorf("unexpected group: %+v", web)
+	}
+
+	template := web.Template
+	if template == nil {
+		t.Fatal("expected web-asg to reference the launch template")
+	}
+	if template.LatestVersion != 4 || template.InstanceType != "t3.micro" || template.MetadataOptions.HttpTokens != "required" {
+		t.Errorf("unexpected template: %+v", template)
+	}
+	if len(template.SecurityGroups) != 1 || template.SecurityGroups[0] != "sg-1" {
+		t.Errorf("expected network interface security groups, got %v", template.SecurityGroups)
+	}
+	if template.Monitoring == nil || !*template.Monitoring {
+		t.Error("expected monitoring to be enabled")
+	}
+
+	batch := groups["batch-asg"]
+	if batch.LaunchTemplate.ID != "lt-9" || batch.LaunchTemplate.Version != "2" || batch.Template != nil {
+		t.Errorf("unexpected mixed instances group: %+v", batch)
+	}
+}
+
+func TestParseAutoScalingGroups_HCL(t *testing.T) {
+	hcl := `
+resource "aws_launch_template" "web" {
+  name          = "web"
+  image_id      = "ami-1"
+  instance_type = "t3.micro"
+}
+
+resource "aws_autoscaling_group" "web" {
+  name     = "web-asg"
+  min_size = 1
+  max_size = 3
+
+  launch_template {
+    id      = aws_launch_template.web.id
+    version = aws_launch_template.web.latest_version
+  }
+}
+`
+
+	client := NewTerraformClient(newTestLogger())
+
+	groups, err := client.ParseAutoScalingGroups(writeTempHCLFile(t, hcl))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	web := groups["hcl:web"]
+	if web == nil || web.Name != "web-asg" {
+		t.Fatalf("unexpected group: %+v", web)
+	}
+	if web.LaunchTemplate.ID != "hcl:web" || web.LaunchTemplate.Version != "hcl:web.latest_version" {
+		t.Errorf("unexpected launch template reference: %+v", web.LaunchTemplate)
+	}
+	if web.Template == nil || web.Template.ImageID != "ami-1" {
+		t.Errorf("expected the declared template, got %+v", web.Template)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/hashicorp/hcl/v2"
 	"github.com/hashicorp/hcl/v2/hclparse"
 	"github.com/hashicorp/hcl/v2/hclsyntax"
 	"github.com/zclconf/go-cty/cty"
@@ -25,30 +26,69 @@ func NewHCLParser(logger *flog.Logger) *HCLParser {
 	}
 }
 
+// hclModule collects everything parsed from one or more .tf files so that
+// provider settings can be applied to resources declared in other files.
+type hclModule struct {
+	instances         map[string]*models.InstanceState
+	defaultTags       map[string]map[string]string // provider reference -> default_tags
+	instanceProviders map[string]string            // instance ID -> provider reference
+}
+
+func newHCLModule() *hclModule {
+	return &hclModule{
+		instances:         make(map[string]*models.InstanceState),
+		defaultTags:       make(map[string]map[string]string),
+		instanceProviders: make(map[string]string),
+	}
+}
+
 func (p *HCLParser) ParseHCLFile(filepath string) (map[string]*models.InstanceState, error) {
+	module := newHCLModule()
+
+	if err := p.parseFileInto(filepath, module); err != nil {
+		return nil, err
+	}
+
+	p.applyDefaultTags(module)
+
+	p.logger.Info("successfully parsed HCL file",
+		zap.String("filepath", filepath),
+		zap.Int("instance_count", len(module.instances)),
+	)
+
+	return module.instances, nil
+}
+
+func (p *HCLParser) parseFileInto(filepath string, module *hclModule) error {
 	p.logger.Info("parsing HCL terraform file",
 		zap.String("filepath", filepath),
 	)
 
 	content, err := os.ReadFile(filepath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read HCL file: %w", err)
+		return fmt.Errorf("failed to read HCL file: %w", err)
 	}
 
 	parser := hclparse.NewParser()
 	file, diags := parser.ParseHCL(content, filepath)
 	if diags.HasErrors() {
-		return nil, fmt.Errorf("failed to parse HCL: %s", diags.Error())
+		return fmt.Errorf("failed to parse HCL: %s", diags.Error())
 	}
 
-	instances := make(map[string]*models.InstanceState)
-
 	body, ok := file.Body.(*hclsyntax.Body)
 	if !ok {
-		return nil, fmt.Errorf("unexpected body type")
+		return fmt.Errorf("unexpected body type")
 	}
 
 	for _, block := range body.Blocks {
+		if block.Type == "provider" && len(block.Labels) == 1 && block.Labels[0] == "aws" {
+			ref, tags := p.parseProviderBlock(block)
+			if tags != nil {
+				module.defaultTags[ref] = tags
+			}
+			continue
+		}
+
 		if block.Type != "resource" {
 			continue
 		}
@@ -77,15 +117,13 @@ func (p *HCLParser) ParseHCLFile(filepath string) (map[string]*models.InstanceSt
 			continue
 		}
 
-		instances[instanceState.InstanceID] = instanceState
+		module.instances[instanceState.InstanceID] = instanceState
+		if ref := p.resourceProviderRef(block); ref != "" {
+			module.instanceProviders[instanceState.InstanceID] = ref
+		}
 	}
 
-	p.logger.Info("successfully parsed HCL file",
-		zap.String("filepath", filepath),
-		zap.Int("instance_count", len(instances)),
-	)
-
-	return instances, nil
+	return nil
 }
 
 func (p *HCLParser) ParseHCLDirectory(dirPath string) (map[string]*models.InstanceState, error) {
@@ -93,7 +131,7 @@ func (p *HCLParser) ParseHCLDirectory(dirPath string) (map[string]*models.Instan
 		zap.String("directory", dirPath),
 	)
 
-	instances := make(map[string]*models.InstanceState)
+	module := newHCLModule()
 
 	err := filepath.Walk(dirPath, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
@@ -112,17 +150,11 @@ func (p *HCLParser) ParseHCLDirectory(dirPath string) (map[string]*models.Instan
 			zap.String("file", path),
 		)
 
-		fileInstances, err := p.ParseHCLFile(path)
-		if err != nil {
+		if err := p.parseFileInto(path, module); err != nil {
 			p.logger.Warn("failed to parse file",
 				zap.String("file", path),
 				zap.Error(err),
 			)
-			return nil
-		}
-
-		for id, state := range fileInstances {
-			instances[id] = state
 		}
 
 		return nil
@@ -132,26 +164,105 @@ func (p *HCLParser) ParseHCLDirectory(dirPath string) (map[string]*models.Instan
 		return nil, fmt.Errorf("failed to walk directory: %w", err)
 	}
 
+	p.applyDefaultTags(module)
+
 	p.logger.Info("successfully parsed HCL directory",
 		zap.String("directory", dirPath),
-		zap.Int("instance_count", len(instances)),
+		zap.Int("instance_count", len(module.instances)),
 	)
 
-	return instances, nil
+	return module.instances, nil
+}
+
+// parseProviderBlock returns the provider reference ("aws" or "aws.<alias>")
+// and its default_tags, or nil tags when the provider sets none.
+func (p *HCLParser) parseProviderBlock(block *hclsyntax.Block) (string, map[string]string) {
+	ref := "aws"
+	if alias := p.stringValue(p.evaluateBlockAttributes(block)["alias"]); alias != "" {
+		ref = "aws." + alias
+	}
+
+	for _, nested := range block.Body.Blocks {
+		if nested.Type != "default_tags" {
+			continue
+		}
+
+		tags, ok := p.evaluateBlockAttributes(nested)["tags"]
+		if !ok || !(tags.Type().IsMapType() || tags.Type().IsObjectType()) {
+			continue
+		}
+
+		p.logger.Debug("found provider default tags",
+			zap.String("provider", ref),
+			zap.Int("tag_count", tags.LengthInt()),
+		)
+
+		return ref, p.extractStringMap(tags)
+	}
+
+	return ref, nil
+}
+
+// resourceProviderRef returns the provider a resource is bound to through its
+// provider meta-argument (e.g. provider = aws.west), or "" for the default.
+func (p *HCLParser) resourceProviderRef(block *hclsyntax.Block) string {
+	attr, ok := block.Body.Attributes["provider"]
+	if !ok {
+		return ""
+	}
+
+	traversal, diags := hcl.AbsTraversalForExpr(attr.Expr)
+	if diags.HasErrors() || len(traversal) == 0 {
+		return ""
+	}
+
+	ref := traversal.RootName()
+	for _, step := range traversal[1:] {
+		if attrStep, ok := step.(hcl.TraverseAttr); ok {
+			ref += "." + attrStep.Name
+		}
+	}
+
+	return ref
+}
+
+// applyDefaultTags computes the effective tag set (the equivalent of
+// tags_all in state) for every instance whose provider sets default_tags.
+// Resource tags take precedence over provider defaults.
+func (p *HCLParser) applyDefaultTags(module *hclModule) {
+	for id, state := range module.instances {
+		ref, ok := module.instanceProviders[id]
+		if !ok {
+			ref = "aws"
+		}
+
+		defaults, ok := module.defaultTags[ref]
+		if !ok {
+			continue
+		}
+
+		tagsAll := make(map[string]string, len(defaults)+len(state.Tags))
+		for k, v := range defaults {
+			tagsAll[k] = v
+		}
+		for k, v := range state.Tags {
+			tagsAll[k] = v
+		}
+		state.TagsAll = tagsAll
+	}
 }
 
 func (p *HCLParser) parseInstanceBlock(block *hclsyntax.Block, resourceName string) (*models.InstanceState, error) {
 	state := &models.InstanceState{
 		InstanceID: fmt.Sprintf("hcl:%s", resourceName),
 		Tags:       make(map[string]string),
+		Source:     blockLocation(block),
+		Address:    models.ResourceTypeInstance + "." + resourceName,
 	}
 
-	attrs, diags := block.Body.JustAttributes()
-	if diags.HasErrors() {
-		return nil, fmt.Errorf("failed to get attributes: %s", diags.Error())
-	}
-
-	for name, attr := range attrs {
+	// Body.Attributes is used rather than JustAttributes, which rejects
+	// bodies containing nested blocks such as root_block_device.
+	for name, attr := range block.Body.Attributes {
 		value, diags := attr.Expr.Value(nil)
 		if diags.HasErrors() {
 			p.logger.Debug("failed to evaluate attribute",
@@ -211,18 +322,128 @@ func (p *HCLParser) parseInstanceBlock(block *hclsyntax.Block, resourceName stri
 		}
 	}
 
+	var rootBlockDevice, metadataOptions map[string]cty.Value
 	for _, nestedBlock := range block.Body.Blocks {
-		if nestedBlock.Type == "tags" {
+		switch nestedBlock.Type {
+		case "tags":
 			tags, err := p.parseTagsBlock(nestedBlock)
 			if err == nil {
 				state.Tags = tags
 			}
+
+		case "root_block_device":
+			rootBlockDevice = p.evaluateBlockAttributes(nestedBlock)
+			state.RootBlockDevice = p.parseRootBlockDevice(rootBlockDevice)
+
+		case "metadata_options":
+			metadataOptions = p.evaluateBlockAttributes(nestedBlock)
+			state.MetadataOptions = p.parseMetadataOptions(metadataOptions)
 		}
 	}
 
+	// Fields the configuration leaves out, or that cannot be evaluated
+	// without variables, are not compared.
+	state.MarkUnset("RootBlockDevice", writtenInBlock(rootBlockDevice))
+	state.MarkUnset("MetadataOptions", writtenInBlock(metadataOptions))
+
 	return state, nil
 }
 
+// writtenInBlock reports whether a field is set in the evaluated attributes
+// of a nested block.
+func writtenInBlock(values map[string]cty.Value) func(string) bool {
+	return func(name string) bool {
+		value, ok := values[name]
+		return ok && !value.IsNull()
+	}
+}
+
+func (p *HCLParser) parseRootBlockDevice(values map[string]cty.Value) models.BlockDevice {
+	var device models.BlockDevice
+
+	for name, value := range values {
+		switch name {
+		case "device_name":
+			device.DeviceName = p.stringValue(value)
+		case "volume_size":
+			device.VolumeSize = p.intValue(value)
+		case "volume_type":
+			device.VolumeType = p.stringValue(value)
+		case "iops":
+			device.Iops = p.intValue(value)
+		case "throughput":
+			device.Throughput = p.intValue(value)
+		case "encrypted":
+			device.Encrypted = p.boolValue(value)
+		case "kms_key_id":
+			device.KmsKeyID = p.stringValue(value)
+		case "delete_on_termination":
+			device.DeleteOnTermination = p.boolValue(value)
+		}
+	}
+
+	return device
+}
+
+func (p *HCLParser) parseMetadataOptions(values map[string]cty.Value) models.MetadataOptions {
+	var opts models.MetadataOptions
+
+	for name, value := range values {
+		switch name {
+		case "http_endpoint":
+			opts.HttpEndpoint = p.stringValue(value)
+		case "http_tokens":
+			opts.HttpTokens = p.stringValue(value)
+		case "http_put_response_hop_limit":
+			opts.HttpPutResponseHopLimit = p.intValue(value)
+		case "instance_metadata_tags":
+			opts.InstanceMetadataTags = p.stringValue(value)
+		}
+	}
+
+	return opts
+}
+
+func (p *HCLParser) evaluateBlockAttributes(block *hclsyntax.Block) map[string]cty.Value {
+	values := make(map[string]cty.Value, len(block.Body.Attributes))
+	for name, attr := range block.Body.Attributes {
+		value, diags := attr.Expr.Value(nil)
+		if diags.HasErrors() {
+			p.logger.Debug("failed to evaluate attribute",
+				zap.String("block", block.Type),
+				zap.String("attribute", name),
+				zap.String("error", diags.Error()),
+			)
+			continue
+		}
+		values[name] = value
+	}
+
+	return values
+}
+
+func (p *HCLParser) stringValue(value cty.Value) string {
+	if value.IsNull() || !value.IsKnown() || value.Type() != cty.String {
+		return ""
+	}
+	return value.AsString()
+}
+
+func (p *HCLParser) intValue(value cty.Value) int {
+	if value.IsNull() || !value.IsKnown() || value.Type() != cty.Number {
+		return 0
+	}
+	n, _ := value.AsBigFloat().Int64()
+	return int(n)
+}
+
+func (p *HCLParser) boolValue(value cty.Value) bool {
+	if value.IsNull() || !value.IsKnown() || value.Type() != cty.Bool {
+		return false
+	}
+	return value.True()
+}
+
 func (p *HCLParser) parseTagsBlock(block *hclsyntax.Block) (map[string]string, error) {
 	tags := make(map[string]string)
 
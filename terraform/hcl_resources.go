@@ -0,0 +1,212 @@
+package terraform
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+	"go.uber.org/zap"
+
+	"firefly-ec2-drift-detector/models"
+)
+
+// ParseResources reads resources of the given types from a .tf file or a
+// directory of .tf files and renders their bodies in the same JSON layout as
+// state attributes. Nested blocks become lists of objects, and expressions
+// that reference another resource (aws_security_group.web.id) become
+// "hcl:<name>", the ID used for resources that only exist in configuration.
+func (p *HCLParser) ParseResources(path string, types ...string) ([]RawResource, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to access path: %w", err)
+	}
+
+	wanted := make(map[string]bool, len(types))
+	for _, t := range types {
+		wanted[t] = true
+	}
+
+	if !info.IsDir() {
+		return p.parseResourcesFile(path, wanted)
+	}
+
+	var resources []RawResource
+	err = filepath.Walk(path, func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || !strings.HasSuffix(file, ".tf") {
+			return nil
+		}
+
+		parsed, err := p.parseResourcesFile(file, wanted)
+		if err != nil {
+			p.logger.Warn("failed to parse file",
+				zap.String("file", file),
+				zap.Error(err),
+			)
+			return nil
+		}
+		resources = append(resources, parsed...)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk directory: %w", err)
+	}
+
+	return resources, nil
+}
+
+func (p *HCLParser) parseResourcesFile(path string, wanted map[string]bool) ([]RawResource, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read HCL file: %w", err)
+	}
+
+	file, diags := hclparse.NewParser().ParseHCL(content, path)
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("failed to parse HCL: %s", diags.Error())
+	}
+
+	body, ok := file.Body.(*hclsyntax.Body)
+	if !ok {
+		return nil, fmt.Errorf("unexpected body type")
+	}
+
+	var resources []RawResource
+	for _, block := range body.Blocks {
+		if block.Type != "resource" || len(block.Labels) < 2 || !wanted[block.Labels[0]] {
+			continue
+		}
+
+		attrs, err := json.Marshal(p.blockToMap(block))
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode %s.%s: %w", block.Labels[0], block.Labels[1], err)
+		}
+
+		resources = append(resources, RawResource{
+			Type:       block.Labels[0],
+			Name:       block.Labels[1],
+			Address:    block.Labels[0] + "." + block.Labels[1],
+			Attributes: attrs,
+			Source:     blockLocation(block),
+		})
+	}
+
+	return resources, nil
+}
+
+// blockLocation returns the lines a block spans.
+func blockLocation(block *hclsyntax.Block) *models.SourceLocation {
+	r := block.Range()
+	return &models.SourceLocation{
+		File:      r.Filename,
+		StartLine: r.Start.Line,
+		EndLine:   r.End.Line,
+	}
+}
+
+func (p *HCLParser) blockToMap(block *hclsyntax.Block) map[string]interface{} {
+	result := make(map[string]interface{}, len(block.Body.Attributes))
+
+	for name, attr := range block.Body.Attributes {
+		if value, ok := p.exprToInterface(attr.Expr); ok {
+			result[name] = value
+		}
+	}
+
+	for _, nested := range block.Body.Blocks {
+		list, _ := result[nested.Type].([]interface{})
+		result[nested.Type] = append(list, p.blockToMap(nested))
+	}
+
+	return result
+}
+
+// exprToInterface evaluates a literal expression into plain Go values,
+// falling back to element-wise evaluation for tuples and objects so that a
+// single reference does not discard its siblings.
+func (p *HCLParser) exprToInterface(expr hclsyntax.Expression) (interface{}, bool) {
+	if value, diags := expr.Value(nil); !diags.HasErrors() {
+		if !value.IsWhollyKnown() || value.IsNull() {
+			return nil, false
+		}
+		data, err := ctyjson.Marshal(value, value.Type())
+		if err != nil {
+			return nil, false
+		}
+		var result interface{}
+		if err := json.Unmarshal(data, &result); err != nil {
+			return nil, false
+		}
+		return result, true
+	}
+
+	switch e := expr.(type) {
+	case *hclsyntax.TupleConsExpr:
+		items := make([]interface{}, 0, len(e.Exprs))
+		for _, item := range e.Exprs {
+			if value, ok := p.exprToInterface(item); ok {
+				items = append(items, value)
+			}
+		}
+		return items, true
+
+	case *hclsyntax.ObjectConsExpr:
+		obj := make(map[string]interface{}, len(e.Items))
+		for _, item := range e.Items {
+			key, diags := item.KeyExpr.Value(nil)
+			if diags.HasErrors() || !key.IsKnown() || key.IsNull() || key.Type() != cty.String {
+				continue
+			}
+			if value, ok := p.exprToInterface(item.ValueExpr); ok {
+				obj[key.AsString()] = value
+			}
+		}
+		return obj, true
+	}
+
+	if ref := resourceReference(expr); ref != "" {
+		return ref, true
+	}
+
+	p.logger.Debug("skipping expression that cannot be evaluated statically",
+		zap.String("range", expr.Range().String()),
+	)
+
+	return nil, false
+}
+
+// resourceReference renders <type>.<name>.id traversals as "hcl:<name>", and
+// references to any other attribute as "hcl:<name>.<attr>".
+func resourceReference(expr hclsyntax.Expression) string {
+	traversal, diags := hcl.AbsTraversalForExpr(expr)
+	if diags.HasErrors() || len(traversal) < 2 {
+		return ""
+	}
+
+	switch traversal.RootName() {
+	case "var", "local", "data", "module", "each", "count", "path", "self":
+		return ""
+	}
+
+	name, ok := traversal[1].(hcl.TraverseAttr)
+	if !ok {
+		return ""
+	}
+
+	if len(traversal) > 2 {
+		if attr, ok := traversal[2].(hcl.TraverseAttr); ok && attr.Name != "id" {
+			return "hcl:" + name.Name + "." + attr.Name
+		}
+	}
+
+	return "hcl:" + name.Name
+}
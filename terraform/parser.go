@@ -1,19 +1,25 @@
 package terraform
 
+import "encoding/json"
+
 type StateFile struct {
 	Version   int        `json:"version"`
 	Resources []Resource `json:"resources"`
 }
 
 type Resource struct {
+	Module    string     `json:"module"` // empty in the root module
 	Type      string     `json:"type"`
 	Name      string     `json:"name"`
 	Mode      string     `json:"mode"`
 	Instances []Instance `json:"instances"`
 }
 
+// Instance holds raw attributes, decoded according to the resource type
+// (Attributes for aws_instance, SecurityGroupAttributes, ...).
 type Instance struct {
-	Attributes Attributes `json:"attributes"`
+	IndexKey   json.RawMessage `json:"index_key"` // count index or for_each key
+	Attributes json.RawMessage `json:"attributes"`
 }
 
 type Attributes struct {
@@ -22,8 +28,173 @@ type Attributes struct {
 	AvailabilityZone    string            `json:"availability_zone"`
 	VpcSecurityGroupIds []string          `json:"vpc_security_group_ids"`
 	Tags                map[string]string `json:"tags"`
+	TagsAll             map[string]string `json:"tags_all"`
 	SubnetID            string            `json:"subnet_id"`
 	AMI                 string            `json:"ami"`
 	KeyName             string            `json:"key_name"`
 	Monitoring          bool              `json:"monitoring"`
+	RootBlockDevice     []BlockDevice     `json:"root_block_device"`
+	MetadataOptions     []MetadataOptions `json:"metadata_options"`
+}
+
+// ObjectFields holds the keys written in the single-block attributes of an
+// aws_instance, so that fields a state file leaves out, or sets to null, are
+// told apart from fields set to false or 0.
+type ObjectFields struct {
+	RootBlockDevice []map[string]json.RawMessage `json:"root_block_device"`
+	MetadataOptions []map[string]json.RawMessage `json:"metadata_options"`
+}
+
+// writtenIn reports whether the first block in blocks sets a field.
+func writtenIn(blocks []map[string]json.RawMessage) func(string) bool {
+	return func(name string) bool {
+		if len(blocks) == 0 {
+			return false
+		}
+		value, ok := blocks[0][name]
+		return ok && string(value) != "null"
+	}
+}
+
+type BlockDevice struct {
+	DeviceName          string `json:"device_name"`
+	VolumeID            string `json:"volume_id"`
+	VolumeSize          int    `json:"volume_size"`
+	VolumeType          string `json:"volume_type"`
+	Iops                int    `json:"iops"`
+	Throughput          int    `json:"throughput"`
+	Encrypted           bool   `json:"encrypted"`
+	KmsKeyID            string `json:"kms_key_id"`
+	DeleteOnTermination bool   `json:"delete_on_termination"`
+}
+
+type MetadataOptions struct {
+	HttpEndpoint            string `json:"http_endpoint"`
+	HttpTokens              string `json:"http_tokens"`
+	HttpPutResponseHopLimit int    `json:"http_put_response_hop_limit"`
+	InstanceMetadataTags    string `json:"instance_metadata_tags"`
+}
+
+// SecurityGroupAttributes is the state of an aws_security_group. Ingress and
+// egress hold the inline rules, which Terraform treats as authoritative.
+type SecurityGroupAttributes struct {
+	ID          string              `json:"id"`
+	Name        string              `json:"name"`
+	Description string              `json:"description"`
+	VpcID       string              `json:"vpc_id"`
+	Tags        map[string]string   `json:"tags"`
+	TagsAll     map[string]string   `json:"tags_all"`
+	Ingress     []SecurityGroupRule `json:"ingress"`
+	Egress      []SecurityGroupRule `json:"egress"`
+}
+
+type SecurityGroupRule struct {
+	Protocol       string   `json:"protocol"`
+	FromPort       int      `json:"from_port"`
+	ToPort         int      `json:"to_port"`
+	CidrBlocks     []string `json:"cidr_blocks"`
+	Ipv6CidrBlocks []string `json:"ipv6_cidr_blocks"`
+	PrefixListIDs  []string `json:"prefix_list_ids"`
+	SecurityGroups []string `json:"security_groups"`
+	Self           bool     `json:"self"`
+	Description    string   `json:"description"`
+}
+
+// SecurityGroupRuleAttributes is the state of a standalone
+// aws_security_group_rule.
+type SecurityGroupRuleAttributes struct {
+	SecurityGroupID       string   `json:"security_group_id"`
+	Type                  string   `json:"type"`
+	Protocol              string   `json:"protocol"`
+	FromPort              int      `json:"from_port"`
+	ToPort                int      `json:"to_port"`
+	CidrBlocks            []string `json:"cidr_blocks"`
+	Ipv6CidrBlocks        []string `json:"ipv6_cidr_blocks"`
+	PrefixListIDs         []string `json:"prefix_list_ids"`
+	SourceSecurityGroupID string   `json:"source_security_group_id"`
+	Self                  bool     `json:"self"`
+	Description           string   `json:"description"`
+}
+
+// VpcSecurityGroupRuleAttributes is the state of an
+// aws_vpc_security_group_ingress_rule or aws_vpc_security_group_egress_rule.
+type VpcSecurityGroupRuleAttributes struct {
+	SecurityGroupID           string `json:"security_group_id"`
+	IpProtocol                string `json:"ip_protocol"`
+	FromPort                  int    `json:"from_port"`
+	ToPort                    int    `json:"to_port"`
+	CidrIpv4                  string `json:"cidr_ipv4"`
+	CidrIpv6                  string `json:"cidr_ipv6"`
+	PrefixListID              string `json:"prefix_list_id"`
+	ReferencedSecurityGroupID string `json:"referenced_security_group_id"`
+	Description               string `json:"description"`
+}
+
+// EBSVolumeAttributes is the state of an aws_ebs_volume.
+type EBSVolumeAttributes struct {
+	ID               string            `json:"id"`
+	AvailabilityZone string            `json:"availability_zone"`
+	Size             int               `json:"size"`
+	Type             string            `json:"type"`
+	Iops             int               `json:"iops"`
+	Throughput       int               `json:"throughput"`
+	Encrypted        bool              `json:"encrypted"`
+	KmsKeyID         string            `json:"kms_key_id"`
+	SnapshotID       string            `json:"snapshot_id"`
+	Tags             map[string]string `json:"tags"`
+	TagsAll          map[string]string `json:"tags_all"`
+}
+
+// VolumeAttachmentAttributes is the state of an aws_volume_attachment.
+type VolumeAttachmentAttributes struct {
+	VolumeID   string `json:"volume_id"`
+	InstanceID string `json:"instance_id"`
+	DeviceName string `json:"device_name"`
+}
+
+// AutoScalingGroupAttributes is the state of an aws_autoscaling_group. The
+// launch template is either set directly or through a mixed instances policy.
+type AutoScalingGroupAttributes struct {
+	ID                   string                        `json:"id"`
+	Name                 string                        `json:"name"`
+	MinSize              int                           `json:"min_size"`
+	MaxSize              int                           `json:"max_size"`
+	DesiredCapacity      int                           `json:"desired_capacity"`
+	LaunchTemplate       []LaunchTemplateSpecification `json:"launch_template"`
+	MixedInstancesPolicy []MixedInstancesPolicy        `json:"mixed_instances_policy"`
+}
+
+type LaunchTemplateSpecification struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type MixedInstancesPolicy struct {
+	LaunchTemplate []struct {
+		LaunchTemplateSpecification []struct {
+			LaunchTemplateID   string `json:"launch_template_id"`
+			LaunchTemplateName string `json:"launch_template_name"`
+			Version            string `json:"version"`
+		} `json:"launch_template_specification"`
+	} `json:"launch_template"`
+}
+
+// LaunchTemplateAttributes is the state of an aws_launch_template.
+type LaunchTemplateAttributes struct {
+	ID                  string   `json:"id"`
+	Name                string   `json:"name"`
+	LatestVersion       int      `json:"latest_version"`
+	DefaultVersion      int      `json:"default_version"`
+	ImageID             string   `json:"image_id"`
+	InstanceType        string   `json:"instance_type"`
+	KeyName             string   `json:"key_name"`
+	VpcSecurityGroupIDs []string `json:"vpc_security_group_ids"`
+	Monitoring          []struct {
+		Enabled bool `json:"enabled"`
+	} `json:"monitoring"`
+	MetadataOptions   []MetadataOptions `json:"metadata_options"`
+	NetworkInterfaces []struct {
+		SecurityGroups []string `json:"security_groups"`
+	} `json:"network_interfaces"`
 }
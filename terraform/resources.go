@@ -0,0 +1,91 @@
+package terraform
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"firefly-ec2-drift-detector/models"
+)
+
+// RawResource is a managed resource whose attributes are decoded according to
+// its type. Resources read from HCL have the same attribute layout as state,
+// with references to other resources rendered as "hcl:<name>".
+type RawResource struct {
+	Type       string
+	Name       string
+	Address    string // such as module.app.aws_instance.web[0]
+	Attributes json.RawMessage
+	Source     *models.SourceLocation // nil for resources read from state
+}
+
+// ParseResources returns the managed resources of the given types from a
+// state file, a .tf file or a directory of .tf files. Data sources are
+// skipped.
+func (p *TerraformClient) ParseResources(path string, types ...string) ([]RawResource, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to access path: %w", err)
+	}
+
+	if info.IsDir() || strings.ToLower(filepath.Ext(path)) == ".tf" {
+		return p.hclParser.ParseResources(path, types...)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state file: %w", err)
+	}
+
+	var state StateFile
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse state file: %w", err)
+	}
+
+	wanted := make(map[string]bool, len(types))
+	for _, t := range types {
+		wanted[t] = true
+	}
+
+	var resources []RawResource
+	for _, resource := range state.Resources {
+		if resource.Mode == "data" || !wanted[resource.Type] {
+			continue
+		}
+		for _, inst := range resource.Instances {
+			resources = append(resources, RawResource{
+				Type:       resource.Type,
+				Name:       resource.Name,
+				Address:    resourceAddress(resource.Module, resource.Type, resource.Name, inst.IndexKey),
+				Attributes: inst.Attributes,
+			})
+		}
+	}
+
+	return resources, nil
+}
+
+// resourceAddress builds the address Terraform uses for a resource instance:
+// the module path, the type and name, and the count index or for_each key.
+func resourceAddress(module, resourceType, name string, indexKey json.RawMessage) string {
+	address := resourceType + "." + name
+	if module != "" {
+		address = module + "." + address
+	}
+
+	var key interface{}
+	if len(indexKey) == 0 || json.Unmarshal(indexKey, &key) != nil {
+		return address
+	}
+
+	switch k := key.(type) {
+	case float64:
+		return fmt.Sprintf("%s[%d]", address, int(k))
+	case string:
+		return fmt.Sprintf("%s[%q]", address, k)
+	default:
+		return address
+	}
+}
@@ -0,0 +1,190 @@
+package terraform
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"go.uber.org/zap"
+
+	"firefly-ec2-drift-detector/models"
+)
+
+const (
+	resourceTypeSecurityGroup           = "aws_security_group"
+	resourceTypeSecurityGroupRule       = "aws_security_group_rule"
+	resourceTypeVpcSecurityGroupIngress = "aws_vpc_security_group_ingress_rule"
+	resourceTypeVpcSecurityGroupEgress  = "aws_vpc_security_group_egress_rule"
+)
+
+var securityGroupResourceTypes = []string{
+	resourceTypeSecurityGroup,
+	resourceTypeSecurityGroupRule,
+	resourceTypeVpcSecurityGroupIngress,
+	resourceTypeVpcSecurityGroupEgress,
+}
+
+// ParseSecurityGroups reads security groups and their rules from a state
+// file, a .tf file or a directory of .tf files. Rules declared as standalone
+// resources are merged into the group they reference.
+func (p *TerraformClient) ParseSecurityGroups(path string) (map[string]*models.SecurityGroupState, error) {
+	resources, err := p.ParseResources(path, securityGroupResourceTypes...)
+	if err != nil {
+		return nil, err
+	}
+
+	groups, err := buildSecurityGroups(resources)
+	if err != nil {
+		return nil, err
+	}
+
+	p.logger.Info("successfully parsed security groups",
+		zap.String("path", path),
+		zap.Int("group_count", len(groups)),
+	)
+
+	return groups, nil
+}
+
+func buildSecurityGroups(resources []RawResource) (map[string]*models.SecurityGroupState, error) {
+	groups := make(map[string]*models.SecurityGroupState)
+
+	group := func(id string) *models.SecurityGroupState {
+		g, ok := groups[id]
+		if !ok {
+			g = &models.SecurityGroupState{GroupID: id, Partial: true}
+			groups[id] = g
+		}
+		return g
+	}
+
+	// Groups first, so standalone rules attach to a non-partial group
+	// regardless of the order resources appear in.
+	for _, res := range resources {
+		if res.Type != resourceTypeSecurityGroup {
+			continue
+		}
+
+		var attrs SecurityGroupAttributes
+		if err := json.Unmarshal(res.Attributes, &attrs); err != nil {
+			return nil, fmt.Errorf("failed to decode %s.%s: %w", res.Type, res.Name, err)
+		}
+		if attrs.ID == "" {
+			attrs.ID = "hcl:" + res.Name
+		}
+
+		g := group(attrs.ID)
+		g.Partial = false
+		g.Source = res.Source
+		g.Address = res.Address
+		g.Name = attrs.Name
+		g.Description = attrs.Description
+		g.VpcID = attrs.VpcID
+		g.Tags = attrs.Tags
+		if attrs.TagsAll != nil {
+			g.Tags = attrs.TagsAll
+		}
+
+		for _, rule := range attrs.Ingress {
+			g.Ingress = append(g.Ingress, expandInlineRule(rule)...)
+		}
+		for _, rule := range attrs.Egress {
+			g.Egress = append(g.Egress, expandInlineRule(rule)...)
+		}
+	}
+
+	for _, res := range resources {
+		switch res.Type {
+		case resourceTypeSecurityGroupRule:
+			var attrs SecurityGroupRuleAttributes
+			if err := json.Unmarshal(res.Attributes, &attrs); err != nil {
+				return nil, fmt.Errorf("failed to decode %s.%s: %w", res.Type, res.Name, err)
+			}
+			if attrs.SecurityGroupID == "" {
+				continue
+			}
+
+			var groupIDs []string
+			if attrs.SourceSecurityGroupID != "" {
+				groupIDs = []string{attrs.SourceSecurityGroupID}
+			}
+			rules := expandRule(attrs.Protocol, attrs.FromPort, attrs.ToPort, attrs.Description,
+				attrs.CidrBlocks, attrs.Ipv6CidrBlocks, attrs.PrefixListIDs, groupIDs, attrs.Self)
+
+			g := group(attrs.SecurityGroupID)
+			if attrs.Type == "egress" {
+				g.Egress = append(g.Egress, rules...)
+			} else {
+				g.Ingress = append(g.Ingress, rules...)
+			}
+
+		case resourceTypeVpcSecurityGroupIngress, resourceTypeVpcSecurityGroupEgress:
+			var attrs VpcSecurityGroupRuleAttributes
+			if err := json.Unmarshal(res.Attributes, &attrs); err != nil {
+				return nil, fmt.Errorf("failed to decode %s.%s: %w", res.Type, res.Name, err)
+			}
+			if attrs.SecurityGroupID == "" {
+				continue
+			}
+
+			source := firstNonEmpty(attrs.CidrIpv4, attrs.CidrIpv6, attrs.PrefixListID, attrs.ReferencedSecurityGroupID)
+			rule := models.NewSecurityGroupRule(attrs.IpProtocol, attrs.FromPort, attrs.ToPort, source, attrs.Description)
+
+			g := group(attrs.SecurityGroupID)
+			if res.Type == resourceTypeVpcSecurityGroupEgress {
+				g.Egress = append(g.Egress, rule)
+			} else {
+				g.Ingress = append(g.Ingress, rule)
+			}
+		}
+	}
+
+	for _, g := range groups {
+		markSelfRules(g)
+	}
+
+	return groups, nil
+}
+
+func expandInlineRule(rule SecurityGroupRule) []models.SecurityGroupRule {
+	return expandRule(rule.Protocol, rule.FromPort, rule.ToPort, rule.Description,
+		rule.CidrBlocks, rule.Ipv6CidrBlocks, rule.PrefixListIDs, rule.SecurityGroups, rule.Self)
+}
+
+// expandRule splits a Terraform rule with several sources into one rule per
+// source, matching how AWS reports rules.
+func expandRule(protocol string, fromPort, toPort int, description string, cidrs, ipv6Cidrs, prefixLists, groupIDs []string, self bool) []models.SecurityGroupRule {
+	var rules []models.SecurityGroupRule
+
+	for _, sources := range [][]string{cidrs, ipv6Cidrs, prefixLists, groupIDs} {
+		for _, source := range sources {
+			rules = append(rules, models.NewSecurityGroupRule(protocol, fromPort, toPort, source, description))
+		}
+	}
+
+	if self {
+		rules = append(rules, models.NewSecurityGroupRule(protocol, fromPort, toPort, models.SelfSource, description))
+	}
+
+	return rules
+}
+
+// markSelfRules rewrites rules whose source is the group itself to "self", so
+// they match however the reference was written.
+func markSelfRules(g *models.SecurityGroupState) {
+	for _, rules := range [][]models.SecurityGroupRule{g.Ingress, g.Egress} {
+		for i := range rules {
+			if rules[i].Source == g.GroupID {
+				rules[i].Source = models.SelfSource
+			}
+		}
+	}
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,186 @@
+package terraform
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"firefly-ec2-drift-detector/models"
+)
+
+func ruleKeys(rules []models.SecurityGroupRule) []string {
+	keys := make([]string, 0, len(rules))
+	for _, rule := range rules {
+		keys = append(keys, rule.Key())
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func TestParseSecurityGroups_StateFile(t *testing.T) {
+	tfState := `{
+		"version": 4,
+		"resources": [
+			{
+				"mode": "managed",
+				"type": "aws_security_group",
+				"name": "web",
+				"instances": [{
+					"attributes": {
+						"id": "sg-web",
+						"name": "web",
+						"vpc_id": "vpc-1",
+						"ingress": [{
+							"protocol": "tcp",
+							"from_port": 443,
+							"to_port": 443,
+							"cidr_blocks": ["0.0.0.0/0", "10.0.0.0/8"],
+							"ipv6_cidr_blocks": [],
+							"prefix_list_ids": [],
+							"security_groups": ["sg-web"],
+							"self": false,
+							"description": "https"
+						}],
+						"egress": [{
+							"protocol": "-1",
+							"from_port": 0,
+							"to_port": 0,
+							"cidr_blocks": ["0.0.0.0/0"],
+							"self": false
+						}]
+					}
+				}]
+			},
+			{
+				"mode": "managed",
+				"type": "aws_security_group_rule",
+				"name": "ssh",
+				"instances": [{
+					"attributes": {
+						"security_group_id": "sg-web",
+						"type": "ingress",
+						"protocol": "6",
+						"from_port": 22,
+						"to_port": 22,
+						"cidr_blocks": ["10.0.0.0/8"]
+					}
+				}]
+			},
+			{
+				"mode": "managed",
+				"type": "aws_vpc_security_group_ingress_rule",
+				"name": "db",
+				"instances": [{
+					"attributes": {
+						"security_group_id": "sg-external",
+						"ip_protocol": "tcp",
+						"from_port": 5432,
+						"to_port": 5432,
+						"referenced_security_group_id": "sg-web"
+					}
+				}]
+			},
+			{
+				"mode": "data",
+				"type": "aws_security_group",
+				"name": "default",
+				"instances": [{"attributes": {"id": "sg-default"}}]
+			}
+		]
+	}`
+
+	client := NewTerraformClient(newTestLogger())
+
+	groups, err := client.ParseSecurityGroups(writeTempFile(t, tfState))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(groups) != 2 {
+		t.Fatalf("expected 2 groups (data sources skipped), got %d", len(groups))
+	}
+
+	web := groups["sg-web"]
+	if web == nil || web.Partial || web.VpcID != "vpc-1" {
+		t.Fatalf("unexpected web group: %+v", web)
+	}
+
+	wantIngress := []string{"tcp 22 10.0.0.0/8", "tcp 443 0.0.0.0/0", "tcp 443 10.0.0.0/8", "tcp 443 self"}
+	if got := ruleKeys(web.Ingress); !reflect.DeepEqual(got, wantIngress) {
+		t.Errorf("ingress = %v, want %v", got, wantIngress)
+	}
+
+	if got := ruleKeys(web.Egress); !reflect.DeepEqual(got, []string{"-1 all 0.0.0.0/0"}) {
+		t.Errorf("egress = %v", got)
+	}
+
+	external := groups["sg-external"]
+	if external == nil || !external.Partial {
+		t.Fatalf("expected sg-external to be partially managed, got %+v", external)
+	}
+	if got := ruleKeys(external.Ingress); !reflect.DeepEqual(got, []string{"tcp 5432 sg-web"}) {
+		t.Errorf("external ingress = %v", got)
+	}
+}
+
+func TestParseSecurityGroups_HCL(t *testing.T) {
+	hcl := `
+resource "aws_security_group" "web" {
+  name   = "web"
+  vpc_id = var.vpc_id
+
+  ingress {
+    protocol        = "tcp"
+    from_port       = 443
+    to_port         = 443
+    cidr_blocks     = ["0.0.0.0/0"]
+    security_groups = [aws_security_group.lb.id]
+    description     = "https"
+  }
+
+  ingress {
+    protocol  = "tcp"
+    from_port = 8080
+    to_port   = 8080
+    self      = true
+  }
+
+  egress {
+    protocol    = "-1"
+    from_port   = 0
+    to_port     = 0
+    cidr_blocks = ["0.0.0.0/0"]
+  }
+}
+
+resource "aws_vpc_security_group_egress_rule" "dns" {
+  security_group_id = aws_security_group.web.id
+  ip_protocol       = "udp"
+  from_port         = 53
+  to_port           = 53
+  cidr_ipv4         = "10.0.0.2/32"
+}
+`
+
+	client := NewTerraformClient(newTestLogger())
+
+	groups, err := client.ParseSecurityGroups(writeTempHCLFile(t, hcl))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	web := groups["hcl:web"]
+	if web == nil || web.Name != "web" || web.Partial {
+		t.Fatalf("unexpected web group: %+v", web)
+	}
+
+	wantIngress := []string{"tcp 443 0.0.0.0/0", "tcp 443 hcl:lb", "tcp 8080 self"}
+	if got := ruleKeys(web.Ingress); !reflect.DeepEqual(got, wantIngress) {
+		t.Errorf("ingress = %v, want %v", got, wantIngress)
+	}
+
+	wantEgress := []string{"-1 all 0.0.0.0/0", "udp 53 10.0.0.2/32"}
+	if got := ruleKeys(web.Egress); !reflect.DeepEqual(got, wantEgress) {
+		t.Errorf("egress = %v, want %v", got, wantEgress)
+	}
+}
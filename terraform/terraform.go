@@ -1,18 +1,25 @@
 package terraform
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 
 	flog "firefly-ec2-drift-detector/logger"
 	"firefly-ec2-drift-detector/models"
 )
 
+var tracer = otel.Tracer("firefly-ec2-drift-detector/terraform")
+
 type TerraformClient struct {
 	_         struct{}
 	logger    *flog.Logger
@@ -26,7 +33,25 @@ func NewTerraformClient(logger *flog.Logger) *TerraformClient {
 	}
 }
 
-func (p *TerraformClient) ParseStateFile(path string) (map[string]*models.InstanceState, error) {
+// ParseStateFile parses a state file or .tf directory, traced as a child span
+// of ctx.
+func (p *TerraformClient) ParseStateFile(ctx context.Context, path string) (map[string]*models.InstanceState, error) {
+	_, span := tracer.Start(ctx, "TerraformClient.ParseStateFile",
+		trace.WithAttributes(attribute.String("terraform.state.path", path)))
+	defer span.End()
+
+	instances, err := p.parseStateFile(path)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	span.SetAttributes(attribute.Int("terraform.instance_count", len(instances)))
+	return instances, nil
+}
+
+func (p *TerraformClient) parseStateFile(path string) (map[string]*models.InstanceState, error) {
 	info, err := os.Stat(path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to access path: %w", err)
@@ -59,35 +84,34 @@ func (p *TerraformClient) parseJSONStateFile(filepath string) (map[string]*model
 		zap.String("filepath", filepath),
 	)
 
-	data, err := os.ReadFile(filepath)
+	resources, err := p.ParseResources(filepath, models.ResourceTypeInstance)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read state file: %w", err)
-	}
-
-	var state StateFile
-	if err := json.Unmarshal(data, &state); err != nil {
-		return nil, fmt.Errorf("failed to parse state file: %w", err)
+		return nil, err
 	}
 
-	p.logger.Debug("terraform state loaded",
-		zap.Int("version", state.Version),
-		zap.Int("resource_count", len(state.Resources)),
-	)
-
 	instances := make(map[string]*models.InstanceState)
-	for _, resource := range state.Resources {
-		if resource.Type == "aws_instance" {
-			for _, inst := range resource.Instances {
-				instanceState := p.mapToInstanceState(inst.Attributes)
-				instances[instanceState.InstanceID] = instanceState
-
-				p.logger.Debug("parsed instance from state",
-					zap.String("instance_id", instanceState.InstanceID),
-					zap.String("resource_name", resource.Name),
-					zap.String("instance_type", instanceState.InstanceType),
-				)
-			}
+	for _, resource := range resources {
+		var attrs Attributes
+		if err := json.Unmarshal(resource.Attributes, &attrs); err != nil {
+			return nil, fmt.Errorf("failed to decode %s.%s: %w", resource.Type, resource.Name, err)
 		}
+
+		var fields ObjectFields
+		if err := json.Unmarshal(resource.Attributes, &fields); err != nil {
+			return nil, fmt.Errorf("failed to decode %s.%s: %w", resource.Type, resource.Name, err)
+		}
+
+		instanceState := p.mapToInstanceState(attrs)
+		instanceState.Address = resource.Address
+		instanceState.MarkUnset("RootBlockDevice", writtenIn(fields.RootBlockDevice))
+		instanceState.MarkUnset("MetadataOptions", writtenIn(fields.MetadataOptions))
+		instances[instanceState.InstanceID] = instanceState
+
+		p.logger.Debug("parsed instance from state",
+			zap.String("instance_id", instanceState.InstanceID),
+			zap.String("resource_name", resource.Name),
+			zap.String("instance_type", instanceState.InstanceType),
+		)
 	}
 
 	p.logger.Info("successfully parsed terraform state",
@@ -99,15 +123,43 @@ func (p *TerraformClient) parseJSONStateFile(filepath string) (map[string]*model
 }
 
 func (p *TerraformClient) mapToInstanceState(attrs Attributes) *models.InstanceState {
-	return &models.InstanceState{
+	state := &models.InstanceState{
 		InstanceID:       attrs.ID,
 		InstanceType:     attrs.InstanceType,
 		AvailabilityZone: attrs.AvailabilityZone,
 		SecurityGroups:   attrs.VpcSecurityGroupIds,
 		Tags:             attrs.Tags,
+		TagsAll:          attrs.TagsAll,
 		SubnetID:         attrs.SubnetID,
 		ImageID:          attrs.AMI,
 		KeyName:          attrs.KeyName,
 		Monitoring:       attrs.Monitoring,
 	}
+
+	if len(attrs.RootBlockDevice) > 0 {
+		root := attrs.RootBlockDevice[0]
+		state.RootBlockDevice = models.BlockDevice{
+			DeviceName:          root.DeviceName,
+			VolumeID:            root.VolumeID,
+			VolumeSize:          root.VolumeSize,
+			VolumeType:          root.VolumeType,
+			Iops:                root.Iops,
+			Throughput:          root.Throughput,
+			Encrypted:           root.Encrypted,
+			KmsKeyID:            root.KmsKeyID,
+			DeleteOnTermination: root.DeleteOnTermination,
+		}
+	}
+
+	if len(attrs.MetadataOptions) > 0 {
+		opts := attrs.MetadataOptions[0]
+		state.MetadataOptions = models.MetadataOptions{
+			HttpEndpoint:            opts.HttpEndpoint,
+			HttpTokens:              opts.HttpTokens,
+			HttpPutResponseHopLimit: opts.HttpPutResponseHopLimit,
+			InstanceMetadataTags:    opts.InstanceMetadataTags,
+		}
+	}
+
+	return state
 }
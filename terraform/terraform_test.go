@@ -1,7 +1,9 @@
 package terraform
 
 import (
+	"context"
 	flog "firefly-ec2-drift-detector/logger"
+	"firefly-ec2-drift-detector/models"
 	"os"
 	"path/filepath"
 	"testing"
@@ -68,7 +70,7 @@ func TestParseStateFile_Success(t *testing.T) {
 
 	client := NewTerraformClient(newTestLogger())
 
-	instances, err := client.ParseStateFile(path)
+	instances, err := client.ParseStateFile(context.Background(), path)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -108,7 +110,7 @@ func TestParseStateFile_IgnoresNonEC2Resources(t *testing.T) {
 
 	client := NewTerraformClient(newTestLogger())
 
-	instances, err := client.ParseStateFile(path)
+	instances, err := client.ParseStateFile(context.Background(), path)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -138,7 +140,7 @@ func TestParseStateFile_MultipleInstances(t *testing.T) {
 
 	client := NewTerraformClient(newTestLogger())
 
-	instances, err := client.ParseStateFile(path)
+	instances, err := client.ParseStateFile(context.Background(), path)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -151,7 +153,7 @@ func TestParseStateFile_MultipleInstances(t *testing.T) {
 func TestParseStateFile_FileNotFound(t *testing.T) {
 	client := NewTerraformClient(newTestLogger())
 
-	_, err := client.ParseStateFile("does-not-exist.tfstate")
+	_, err := client.ParseStateFile(context.Background(), "does-not-exist.tfstate")
 	if err == nil {
 		t.Fatalf("expected error")
 	}
@@ -162,7 +164,7 @@ func TestParseStateFile_InvalidJSON(t *testing.T) {
 
 	client := NewTerraformClient(newTestLogger())
 
-	_, err := client.ParseStateFile(path)
+	_, err := client.ParseStateFile(context.Background(), path)
 	if err == nil {
 		t.Fatalf("expected error")
 	}
@@ -204,7 +206,7 @@ resource "aws_instance" "web" {
 
 	client := NewTerraformClient(newTestLogger())
 
-	instances, err := client.ParseStateFile(path)
+	instances, err := client.ParseStateFile(context.Background(), path)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -231,6 +233,119 @@ resource "aws_instance" "web" {
 	}
 }
 
+func TestParseStateFile_HCLSourceLocation(t *testing.T) {
+	hcl := `
+resource "aws_security_group" "web" {
+  name = "web"
+}
+
+resource "aws_instance" "web" {
+  instance_type = "t3.micro"
+
+  tags = {
+    Name = "web-server"
+  }
+}
+`
+
+	path := writeTempHCLFile(t, hcl)
+
+	client := NewTerraformClient(newTestLogger())
+
+	instances, err := client.ParseStateFile(context.Background(), path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	inst := instances["hcl:web"]
+	if inst == nil || inst.Source == nil {
+		t.Fatalf("expected hcl:web with a source location, got %+v", inst)
+	}
+
+	if inst.Source.File != path {
+		t.Errorf("unexpected source file: %s", inst.Source.File)
+	}
+
+	if inst.Source.StartLine != 6 || inst.Source.EndLine != 12 {
+		t.Errorf("expected lines 6-12, got %d-%d", inst.Source.StartLine, inst.Source.EndLine)
+	}
+
+	if inst.Address != "aws_instance.web" {
+		t.Errorf("unexpected address: %s", inst.Address)
+	}
+}
+
+func TestParseStateFile_JSONHasNoSourceLocation(t *testing.T) {
+	path := writeTempFile(t, `{
+  "version": 4,
+  "resources": [
+    {
+      "mode": "managed",
+      "type": "aws_instance",
+      "name": "web",
+      "instances": [{"attributes": {"id": "i-123", "instance_type": "t3.micro"}}]
+    }
+  ]
+}`)
+
+	client := NewTerraformClient(newTestLogger())
+
+	instances, err := client.ParseStateFile(context.Background(), path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if inst := instances["i-123"]; inst == nil || inst.Source != nil {
+		t.Errorf("expected i-123 without a source location, got %+v", inst)
+	}
+}
+
+func TestParseStateFile_ResourceAddress(t *testing.T) {
+	path := writeTempFile(t, `{
+  "version": 4,
+  "resources": [
+    {
+      "mode": "managed",
+      "type": "aws_instance",
+      "name": "web",
+      "instances": [{"attributes": {"id": "i-root"}}]
+    },
+    {
+      "module": "module.app",
+      "mode": "managed",
+      "type": "aws_instance",
+      "name": "worker",
+      "instances": [
+        {"index_key": 0, "attributes": {"id": "i-count"}},
+        {"index_key": "blue", "attributes": {"id": "i-each"}}
+      ]
+    }
+  ]
+}`)
+
+	client := NewTerraformClient(newTestLogger())
+
+	instances, err := client.ParseStateFile(context.Background(), path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := map[string]string{
+		"i-root":  "aws_instance.web",
+		"i-count": "module.app.aws_instance.worker[0]",
+		"i-each":  `module.app.aws_instance.worker["blue"]`,
+	}
+	for id, address := range want {
+		inst := instances[id]
+		if inst == nil {
+			t.Fatalf("instance %s not found", id)
+		}
+		if inst.Address != address {
+			t.Errorf("%s: expected address %s, got %s", id, address, inst.Address)
+		}
+	}
+}
+
 func TestParseStateFile_HCLDirectory(t *testing.T) {
 	dir := t.TempDir()
 
@@ -258,7 +373,7 @@ resource "aws_instance" "web2" {
 
 	client := NewTerraformClient(newTestLogger())
 
-	instances, err := client.ParseStateFile(dir)
+	instances, err := client.ParseStateFile(context.Background(), dir)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -323,7 +438,7 @@ resource "aws_instance" "test" {
 
 			client := NewTerraformClient(newTestLogger())
 
-			instances, err := client.ParseStateFile(path)
+			instances, err := client.ParseStateFile(context.Background(), path)
 			if err != nil {
 				t.Fatalf("unexpected error: %v", err)
 			}
@@ -358,7 +473,7 @@ func TestBackwardCompatibility_WithJSONStateFile(t *testing.T) {
 
 	client := NewTerraformClient(newTestLogger())
 
-	instances, err := client.ParseStateFile(path)
+	instances, err := client.ParseStateFile(context.Background(), path)
 	if err != nil {
 		t.Fatalf("backward compatibility broken: %v", err)
 	}
@@ -371,3 +486,424 @@ func TestBackwardCompatibility_WithJSONStateFile(t *testing.T) {
 		t.Fatal("expected instance not found")
 	}
 }
+
+func TestParseStateFile_NestedBlocks(t *testing.T) {
+	tfState := `
+{
+  "version": 4,
+  "resources": [
+    {
+      "type": "aws_instance",
+      "name": "web",
+      "instances": [
+        {
+          "attributes": {
+            "id": "i-123",
+            "root_block_device": [
+              {
+                "device_name": "/dev/xvda",
+                "volume_id": "vol-1",
+                "volume_size": 30,
+                "volume_type": "gp3",
+                "iops": 3000,
+                "encrypted": true,
+                "delete_on_termination": true
+              }
+            ],
+            "metadata_options": [
+              {
+                "http_endpoint": "enabled",
+                "http_tokens": "required",
+                "http_put_response_hop_limit": 2
+              }
+            ]
+          }
+        }
+      ]
+    }
+  ]
+}`
+
+	path := writeTempFile(t, tfState)
+
+	client := NewTerraformClient(newTestLogger())
+
+	instances, err := client.ParseStateFile(context.Background(), path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	inst := instances["i-123"]
+	if inst == nil {
+		t.Fatalf("instance i-123 not found")
+	}
+
+	if inst.RootBlockDevice.VolumeSize != 30 || inst.RootBlockDevice.VolumeType != "gp3" {
+		t.Errorf("unexpected root block device: %+v", inst.RootBlockDevice)
+	}
+
+	if !inst.RootBlockDevice.Encrypted {
+		t.Errorf("expected root volume to be encrypted")
+	}
+
+	if inst.MetadataOptions.HttpTokens != "required" || inst.MetadataOptions.HttpPutResponseHopLimit != 2 {
+		t.Errorf("unexpected metadata options: %+v", inst.MetadataOptions)
+	}
+}
+
+func TestParseStateFile_HCLNestedBlocks(t *testing.T) {
+	hcl := `
+resource "aws_instance" "web" {
+  instance_type = "t3.micro"
+
+  root_block_device {
+    volume_size = 50
+    volume_type = "gp3"
+    encrypted   = true
+  }
+
+  metadata_options {
+    http_tokens                 = "required"
+    http_put_response_hop_limit = 1
+  }
+}
+`
+
+	path := writeTempHCLFile(t, hcl)
+
+	client := NewTerraformClient(newTestLogger())
+
+	instances, err := client.ParseStateFile(context.Background(), path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	inst := instances["hcl:web"]
+	if inst == nil {
+		t.Fatalf("instance hcl:web not found")
+	}
+
+	if inst.RootBlockDevice.VolumeSize != 50 || inst.RootBlockDevice.VolumeType != "gp3" || !inst.RootBlockDevice.Encrypted {
+		t.Errorf("unexpected root block device: %+v", inst.RootBlockDevice)
+	}
+
+	if inst.MetadataOptions.HttpTokens != "required" || inst.MetadataOptions.HttpPutResponseHopLimit != 1 {
+		t.Errorf("unexpected metadata options: %+v", inst.MetadataOptions)
+	}
+}
+
+func TestParseStateFile_HCLWithoutBlocksHasNoObjectDrift(t *testing.T) {
+	hcl := `
+resource "aws_instance" "web" {
+  instance_type = "t3.micro"
+}
+`
+
+	path := writeTempHCLFile(t, hcl)
+
+	client := NewTerraformClient(newTestLogger())
+
+	instances, err := client.ParseStateFile(context.Background(), path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	attrs, err := models.ResolveAttributes([]string{models.AllAttributes})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actual := &models.InstanceState{
+		InstanceID:   "i-1",
+		InstanceType: "t3.micro",
+		RootBlockDevice: models.BlockDevice{
+			DeviceName: "/dev/xvda",
+			VolumeID:   "vol-1",
+			VolumeSize: 8,
+			VolumeType: "gp3",
+		},
+		MetadataOptions: models.MetadataOptions{
+			HttpEndpoint:            "enabled",
+			HttpTokens:              "required",
+			HttpPutResponseHopLimit: 2,
+		},
+	}
+
+	report, err := models.NewAttributeComparator(newTestLogger()).CompareAttributes(context.Background(), instances["hcl:web"], actual, attrs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, drift := range report.Drifts {
+		if drift.AttributeName == "RootBlockDevice" || drift.AttributeName == "MetadataOptions" {
+			t.Errorf("expected no drift for an undeclared block, got %s: %v vs %v", drift.AttributeName, drift.ExpectedValue, drift.ActualValue)
+		}
+	}
+}
+
+func TestParseStateFile_HCLComparesFalseAndZeroFields(t *testing.T) {
+	hcl := `
+resource "aws_instance" "web" {
+  instance_type = "t3.micro"
+
+  root_block_device {
+    volume_size           = 8
+    encrypted             = false
+    delete_on_termination = false
+  }
+
+  metadata_options {
+    http_tokens = "required"
+  }
+}
+`
+
+	path := writeTempHCLFile(t, hcl)
+
+	client := NewTerraformClient(newTestLogger())
+
+	instances, err := client.ParseStateFile(context.Background(), path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	actual := &models.InstanceState{
+		InstanceID:   "i-1",
+		InstanceType: "t3.micro",
+		RootBlockDevice: models.BlockDevice{
+			DeviceName:          "/dev/xvda",
+			VolumeSize:          8,
+			VolumeType:          "gp3",
+			Encrypted:           true,
+			DeleteOnTermination: false,
+		},
+		MetadataOptions: models.MetadataOptions{
+			HttpEndpoint:            "enabled",
+			HttpTokens:              "required",
+			HttpPutResponseHopLimit: 2,
+		},
+	}
+
+	attrs := []string{"RootBlockDevice", "RootBlockDevice.Encrypted", "RootBlockDevice.VolumeType", "MetadataOptions"}
+	report, err := models.NewAttributeComparator(newTestLogger()).CompareAttributes(context.Background(), instances["hcl:web"], actual, attrs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	drifted := make(map[string]bool)
+	for _, drift := range report.Drifts {
+		drifted[drift.AttributeName] = true
+	}
+	for _, attr := range []string{"RootBlockDevice", "RootBlockDevice.Encrypted"} {
+		if !drifted[attr] {
+			t.Errorf("expected drift for %s, which is set to false", attr)
+		}
+	}
+	for _, attr := range []string{"RootBlockDevice.VolumeType", "MetadataOptions"} {
+		if drifted[attr] {
+			t.Errorf("expected no drift for %s, which is only set in AWS", attr)
+		}
+	}
+}
+
+func TestParseStateFile_ComparesFalseAndZeroFields(t *testing.T) {
+	tfState := `
+{
+  "version": 4,
+  "resources": [
+    {
+      "type": "aws_instance",
+      "name": "web",
+      "instances": [
+        {
+          "attributes": {
+            "id": "i-123",
+            "root_block_device": [{"volume_size": 8, "encrypted": false, "kms_key_id": null}],
+            "metadata_options": [{"http_tokens": "required", "http_put_response_hop_limit": 0}]
+          }
+        }
+      ]
+    }
+  ]
+}
+`
+
+	path := writeTempFile(t, tfState)
+
+	client := NewTerraformClient(newTestLogger())
+
+	instances, err := client.ParseStateFile(context.Background(), path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := instances["i-123"]
+	for _, path := range []string{"RootBlockDevice.VolumeType", "RootBlockDevice.KmsKeyID", "MetadataOptions.HttpEndpoint"} {
+		if !expected.IsUnset(path) {
+			t.Errorf("expected %s to be unset", path)
+		}
+	}
+	for _, path := range []string{"RootBlockDevice.Encrypted", "MetadataOptions.HttpPutResponseHopLimit"} {
+		if expected.IsUnset(path) {
+			t.Errorf("expected %s to be set", path)
+		}
+	}
+
+	actual := &models.InstanceState{
+		InstanceID: "i-123",
+		RootBlockDevice: models.BlockDevice{
+			VolumeSize: 8,
+			VolumeType: "gp3",
+			Encrypted:  true,
+			KmsKeyID:   "arn:aws:kms:us-east-1:123456789012:key/abc",
+		},
+		MetadataOptions: models.MetadataOptions{
+			HttpEndpoint:            "enabled",
+			HttpTokens:              "required",
+			HttpPutResponseHopLimit: 1,
+		},
+	}
+
+	attrs := []string{"RootBlockDevice.Encrypted", "RootBlockDevice.KmsKeyID", "MetadataOptions"}
+	report, err := models.NewAttributeComparator(newTestLogger()).CompareAttributes(context.Background(), expected, actual, attrs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	drifted := make(map[string]bool)
+	for _, drift := range report.Drifts {
+		drifted[drift.AttributeName] = true
+	}
+	if !drifted["RootBlockDevice.Encrypted"] || !drifted["MetadataOptions"] {
+		t.Errorf("expected drift for fields set to false and 0, got %+v", report.Drifts)
+	}
+	if drifted["RootBlockDevice.KmsKeyID"] {
+		t.Error("expected no drift for a field that is null in state")
+	}
+}
+
+func TestParseStateFile_TagsAll(t *testing.T) {
+	tfState := `
+{
+  "version": 4,
+  "resources": [
+    {
+      "type": "aws_instance",
+      "name": "web",
+      "instances": [
+        {
+          "attributes": {
+            "id": "i-123",
+            "tags": {"Name": "web"},
+            "tags_all": {"Name": "web", "ManagedBy": "terraform"}
+          }
+        }
+      ]
+    }
+  ]
+}`
+
+	path := writeTempFile(t, tfState)
+
+	client := NewTerraformClient(newTestLogger())
+
+	instances, err := client.ParseStateFile(context.Background(), path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	inst := instances["i-123"]
+	if inst == nil {
+		t.Fatalf("instance i-123 not found")
+	}
+
+	if len(inst.Tags) != 1 {
+		t.Errorf("expected 1 resource tag, got %d", len(inst.Tags))
+	}
+
+	if inst.EffectiveTags()["ManagedBy"] != "terraform" {
+		t.Errorf("expected effective tags to include default tag, got %v", inst.EffectiveTags())
+	}
+}
+
+func TestParseStateFile_HCLProviderDefaultTags(t *testing.T) {
+	dir := t.TempDir()
+
+	providers := `
+provider "aws" {
+  region = "us-east-1"
+
+  default_tags {
+    tags = {
+      ManagedBy = "terraform"
+      Env       = "default"
+    }
+  }
+}
+
+provider "aws" {
+  alias  = "west"
+  region = "us-west-2"
+
+  default_tags {
+    tags = {
+      Region = "west"
+    }
+  }
+}
+`
+
+	instances := `
+resource "aws_instance" "web" {
+  instance_type = "t3.micro"
+
+  tags = {
+    Name = "web"
+    Env  = "prod"
+  }
+}
+
+resource "aws_instance" "west" {
+  provider      = aws.west
+  instance_type = "t3.micro"
+}
+`
+
+	if err := os.WriteFile(filepath.Join(dir, "providers.tf"), []byte(providers), 0644); err != nil {
+		t.Fatalf("failed to write providers.tf: %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "main.tf"), []byte(instances), 0644); err != nil {
+		t.Fatalf("failed to write main.tf: %v", err)
+	}
+
+	client := NewTerraformClient(newTestLogger())
+
+	parsed, err := client.ParseStateFile(context.Background(), dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	web := parsed["hcl:web"]
+	if web == nil {
+		t.Fatalf("instance hcl:web not found")
+	}
+
+	expectedWeb := map[string]string{"Name": "web", "Env": "prod", "ManagedBy": "terraform"}
+	if len(web.TagsAll) != len(expectedWeb) {
+		t.Fatalf("expected tags_all %v, got %v", expectedWeb, web.TagsAll)
+	}
+	for k, v := range expectedWeb {
+		if web.TagsAll[k] != v {
+			t.Errorf("tag %s: expected %q, got %q", k, v, web.TagsAll[k])
+		}
+	}
+
+	west := parsed["hcl:west"]
+	if west == nil {
+		t.Fatalf("instance hcl:west not found")
+	}
+
+	if len(west.TagsAll) != 1 || west.TagsAll["Region"] != "west" {
+		t.Errorf("expected aliased provider default tags, got %v", west.TagsAll)
+	}
+}
@@ -0,0 +1,85 @@
+package terraform
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"go.uber.org/zap"
+
+	"firefly-ec2-drift-detector/models"
+)
+
+const resourceTypeVolumeAttachment = "aws_volume_attachment"
+
+// ParseVolumes reads EBS volumes from a state file, a .tf file or a directory
+// of .tf files, with the attachment declared for each volume.
+func (p *TerraformClient) ParseVolumes(path string) (map[string]*models.VolumeState, error) {
+	resources, err := p.ParseResources(path, models.ResourceTypeVolume, resourceTypeVolumeAttachment)
+	if err != nil {
+		return nil, err
+	}
+
+	volumes := make(map[string]*models.VolumeState)
+
+	for _, res := range resources {
+		if res.Type != models.ResourceTypeVolume {
+			continue
+		}
+
+		var attrs EBSVolumeAttributes
+		if err := json.Unmarshal(res.Attributes, &attrs); err != nil {
+			return nil, fmt.Errorf("failed to decode %s.%s: %w", res.Type, res.Name, err)
+		}
+		if attrs.ID == "" {
+			attrs.ID = "hcl:" + res.Name
+		}
+
+		volumes[attrs.ID] = &models.VolumeState{
+			VolumeID:         attrs.ID,
+			AvailabilityZone: attrs.AvailabilityZone,
+			Size:             attrs.Size,
+			VolumeType:       attrs.Type,
+			Iops:             attrs.Iops,
+			Throughput:       attrs.Throughput,
+			Encrypted:        attrs.Encrypted,
+			KmsKeyID:         attrs.KmsKeyID,
+			SnapshotID:       attrs.SnapshotID,
+			Tags:             attrs.Tags,
+			TagsAll:          attrs.TagsAll,
+			Source:           res.Source,
+			Address:          res.Address,
+		}
+	}
+
+	for _, res := range resources {
+		if res.Type != resourceTypeVolumeAttachment {
+			continue
+		}
+
+		var attrs VolumeAttachmentAttributes
+		if err := json.Unmarshal(res.Attributes, &attrs); err != nil {
+			return nil, fmt.Errorf("failed to decode %s.%s: %w", res.Type, res.Name, err)
+		}
+
+		volume, ok := volumes[attrs.VolumeID]
+		if !ok {
+			p.logger.Debug("volume attachment references a volume not in state",
+				zap.String("resource_name", res.Name),
+				zap.String("volume_id", attrs.VolumeID),
+			)
+			continue
+		}
+
+		volume.Attachment = models.VolumeAttachment{
+			InstanceID: attrs.InstanceID,
+			DeviceName: attrs.DeviceName,
+		}
+	}
+
+	p.logger.Info("successfully parsed EBS volumes",
+		zap.String("path", path),
+		zap.Int("volume_count", len(volumes)),
+	)
+
+	return volumes, nil
+}
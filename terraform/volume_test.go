@@ -0,0 +1,98 @@
+package terraform
+
+import "testing"
+
+func TestParseVolumes_StateFile(t *testing.T) {
+	tfState := `{
+		"version": 4,
+		"resources": [
+			{
+				"mode": "managed",
+				"type": "aws_ebs_volume",
+				"name": "data",
+				"instances": [{
+					"attributes": {
+						"id": "vol-1",
+						"availability_zone": "us-east-1a",
+						"size": 100,
+						"type": "gp3",
+						"iops": 3000,
+						"encrypted": true,
+						"snapshot_id": "snap-1",
+						"tags": {"Name": "data"}
+					}
+				}]
+			},
+			{
+				"mode": "managed",
+				"type": "aws_volume_attachment",
+				"name": "data",
+				"instances": [{
+					"attributes": {
+						"volume_id": "vol-1",
+						"instance_id": "i-1",
+						"device_name": "/dev/sdf"
+					}
+				}]
+			},
+			{
+				"mode": "managed",
+				"type": "aws_volume_attachment",
+				"name": "orphan",
+				"instances": [{
+					"attributes": {"volume_id": "vol-unmanaged", "instance_id": "i-2", "device_name": "/dev/sdg"}
+				}]
+			}
+		]
+	}`
+
+	client := NewTerraformClient(newTestLogger())
+
+	volumes, err := client.ParseVolumes(writeTempFile(t, tfState))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(volumes) != 1 {
+		t.Fatalf("expected 1 volume, got %d", len(volumes))
+	}
+
+	volume := volumes["vol-1"]
+	if volume.Size != 100 || volume.VolumeType != "gp3" || !volume.Encrypted || volume.SnapshotID != "snap-1" {
+		t.Errorf("unexpected volume: %+v", volume)
+	}
+	if volume.Attachment.InstanceID != "i-1" || volume.Attachment.DeviceName != "/dev/sdf" {
+		t.Errorf("unexpected attachment: %+v", volume.Attachment)
+	}
+}
+
+func TestParseVolumes_HCL(t *testing.T) {
+	hcl := `
+resource "aws_ebs_volume" "data" {
+  availability_zone = "us-east-1a"
+  size              = 50
+  type              = "gp3"
+}
+
+resource "aws_volume_attachment" "data" {
+  volume_id   = aws_ebs_volume.data.id
+  instance_id = aws_instance.web.id
+  device_name = "/dev/sdf"
+}
+`
+
+	client := NewTerraformClient(newTestLogger())
+
+	volumes, err := client.ParseVolumes(writeTempHCLFile(t, hcl))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	volume := volumes["hcl:data"]
+	if volume == nil || volume.Size != 50 {
+		t.Fatalf("unexpected volume: %+v", volume)
+	}
+	if volume.Attachment.InstanceID != "hcl:web" {
+		t.Errorf("expected attachment to reference hcl:web, got %+v", volume.Attachment)
+	}
+}
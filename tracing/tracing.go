@@ -0,0 +1,98 @@
+// Package tracing sets up OpenTelemetry tracing of scans: the spans that the
+// terraform, aws, models and service packages start are exported over OTLP or
+// written to a local file.
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// Exporters.
+const (
+	ExporterNone = "none"
+	ExporterOTLP = "otlp"
+	ExporterFile = "file"
+)
+
+const serviceName = "firefly-ec2-drift-detector"
+
+// Config says where spans are exported.
+type Config struct {
+	// Exporter is ExporterNone, ExporterOTLP or ExporterFile.
+	Exporter string
+	// Endpoint is the OTLP/HTTP endpoint URL, such as
+	// http://localhost:4318. When empty, the standard OTEL_EXPORTER_OTLP_*
+	// environment variables apply.
+	Endpoint string
+	// FilePath is the file ExporterFile writes spans to, one JSON object
+	// per line. It is appended to.
+	FilePath string
+	// ServiceVersion is recorded on every span.
+	ServiceVersion string
+}
+
+// Setup installs a global tracer provider exporting spans as cfg says. The
+// returned function flushes spans not yet exported and must be called before
+// the process exits. With ExporterNone nothing is installed and spans are
+// dropped.
+func Setup(ctx context.Context, cfg Config) (func(context.Context) error, error) {
+	var (
+		exporter sdktrace.SpanExporter
+		closeFn  = func() error { return nil }
+	)
+
+	switch cfg.Exporter {
+	case "", ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterOTLP:
+		var opts []otlptracehttp.Option
+		if cfg.Endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpointURL(cfg.Endpoint))
+		}
+		otlp, err := otlptracehttp.New(ctx, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create OTLP trace exporter: %w", err)
+		}
+		exporter = otlp
+	case ExporterFile:
+		if cfg.FilePath == "" {
+			return nil, errors.New("a trace file path is required for the file exporter")
+		}
+		file, err := os.OpenFile(cfg.FilePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open trace file: %w", err)
+		}
+		stdout, err := stdouttrace.New(stdouttrace.WithWriter(file))
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to create file trace exporter: %w", err)
+		}
+		exporter, closeFn = stdout, file.Close
+	default:
+		return nil, fmt.Errorf("unsupported trace exporter: %s (use none, otlp or file)", cfg.Exporter)
+	}
+
+	res := resource.NewSchemaless(
+		attribute.String("service.name", serviceName),
+		attribute.String("service.version", cfg.ServiceVersion),
+	)
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+
+	return func(ctx context.Context) error {
+		return errors.Join(provider.Shutdown(ctx), closeFn())
+	}, nil
+}
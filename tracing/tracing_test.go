@@ -0,0 +1,56 @@
+package tracing
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+)
+
+func TestSetup_File(t *testing.T) {
+	previous := otel.GetTracerProvider()
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	path := filepath.Join(t.TempDir(), "traces.jsonl")
+	shutdown, err := Setup(context.Background(), Config{Exporter: ExporterFile, FilePath: path, ServiceVersion: "1.2.3"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_, span := otel.Tracer("test").Start(context.Background(), "TerraformClient.ParseStateFile")
+	span.End()
+
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatalf("unexpected shutdown error: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`"Name":"TerraformClient.ParseStateFile"`, `"Value":"firefly-ec2-drift-detector"`, `"Value":"1.2.3"`} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("expected %s in the trace file, got:\n%s", want, data)
+		}
+	}
+}
+
+func TestSetup_Errors(t *testing.T) {
+	for name, cfg := range map[string]Config{
+		"unknown exporter": {Exporter: "jaeger"},
+		"no file path":     {Exporter: ExporterFile},
+		"missing dir":      {Exporter: ExporterFile, FilePath: filepath.Join(t.TempDir(), "missing", "traces.jsonl")},
+	} {
+		if _, err := Setup(context.Background(), cfg); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+
+	shutdown, err := Setup(context.Background(), Config{Exporter: ExporterNone})
+	if err != nil || shutdown(context.Background()) != nil {
+		t.Errorf("expected no tracing to set up cleanly, got %v", err)
+	}
+}
@@ -0,0 +1,206 @@
+// Package watch runs drift detection on a schedule and reports what changed
+// since the previous scan.
+package watch
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"firefly-ec2-drift-detector/history"
+	flog "firefly-ec2-drift-detector/logger"
+	"firefly-ec2-drift-detector/output"
+)
+
+// Event types, one per kind of change between two scans.
+const (
+	EventNew      = "new"
+	EventResolved = "resolved"
+	EventChanged  = "changed"
+)
+
+type (
+	// ScanFunc runs one scan of the state at the given version; see
+	// service.DetectRequest.StateVersion. A run with failures is returned
+	// without an error; the error is for scans that checked nothing.
+	ScanFunc func(ctx context.Context, stateVersion string) (*output.Run, error)
+
+	// Scan is the outcome of one scheduled scan.
+	Scan struct {
+		Number       int // from 1
+		Run          *output.Run
+		StateChanged bool // state version differs from the previous scan
+		// Diff compares the scan with the previous one that succeeded. The
+		// first scan is compared with an empty run, so all of its drift is
+		// new.
+		Diff *history.RunDiff
+	}
+
+	// Event is one change between two scans.
+	Event struct {
+		Type  string    `json:"event"`
+		RunID string    `json:"run_id"`
+		Scan  int       `json:"scan"`
+		Time  time.Time `json:"time"`
+		history.Finding
+	}
+
+	// Watcher scans a state path every interval until its context is
+	// cancelled.
+	Watcher struct {
+		statePath string
+		interval  time.Duration
+		scan      ScanFunc
+		onScan    func(*Scan)
+		logger    *flog.Logger
+
+		count    int
+		version  string
+		previous *output.Document
+	}
+)
+
+// New returns a watcher calling scan for statePath every interval, and
+// onScan after each scan that succeeded.
+func New(statePath string, interval time.Duration, scan ScanFunc, onScan func(*Scan), logger *flog.Logger) *Watcher {
+	return &Watcher{
+		statePath: statePath,
+		interval:  interval,
+		scan:      scan,
+		onScan:    onScan,
+		logger:    logger,
+	}
+}
+
+// Run scans at once, then every interval, until ctx is cancelled, and returns
+// nil then. A failed scan is logged and tried again at the next interval.
+func (w *Watcher) Run(ctx context.Context) error {
+	if w.interval <= 0 {
+		return fmt.Errorf("invalid watch interval %s: must be positive", w.interval)
+	}
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := w.tick(ctx); err != nil && ctx.Err() == nil {
+			w.logger.Error("scan failed", zap.Int("scan", w.count), zap.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			w.logger.Info("watch stopped", zap.Int("scans", w.count))
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// tick runs one scan and reports it unless it failed or was cancelled.
+func (w *Watcher) tick(ctx context.Context) error {
+	w.count++
+
+	version, err := StateVersion(w.statePath)
+	if err != nil {
+		// Scanning without a version parses the state, which reports the
+		// problem if it persists.
+		w.logger.Warn("failed to read terraform state version", zap.String("path", w.statePath), zap.Error(err))
+	}
+
+	run, err := w.scan(ctx, version)
+	if err != nil {
+		return err
+	}
+	if ctx.Err() != nil {
+		// Reports of a cancelled scan are incomplete.
+		return ctx.Err()
+	}
+
+	scan := &Scan{
+		Number:       w.count,
+		Run:          run,
+		StateChanged: w.previous == nil || version == "" || version != w.version,
+	}
+
+	doc := output.NewDocument(run)
+	previous := w.previous
+	if previous == nil {
+		previous = &output.Document{}
+	}
+	scan.Diff = history.Diff(previous, doc)
+
+	w.previous = doc
+	w.version = version
+
+	w.onScan(scan)
+	return nil
+}
+
+// Events lists the new, resolved and changed drift of the scan.
+func (s *Scan) Events() []Event {
+	var events []Event
+	for _, change := range []struct {
+		event    string
+		findings []history.Finding
+	}{
+		{EventNew, s.Diff.New},
+		{EventResolved, s.Diff.Resolved},
+		{EventChanged, s.Diff.Changed},
+	} {
+		for _, finding := range change.findings {
+			events = append(events, Event{
+				Type:    change.event,
+				RunID:   s.Run.ID,
+				Scan:    s.Number,
+				Time:    s.Run.Finished,
+				Finding: finding,
+			})
+		}
+	}
+	return events
+}
+
+// StateVersion fingerprints a state path from file modification times and
+// sizes, without reading the files. A directory of .tf files changes version
+// when any of them does.
+func StateVersion(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	if !info.IsDir() {
+		return fileVersion(info), nil
+	}
+
+	hash := sha256.New()
+	err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(p, ".tf") {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(hash, "%s\x00%s\n", p, fileVersion(info))
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func fileVersion(info fs.FileInfo) string {
+	return fmt.Sprintf("%d-%d", info.ModTime().UnixNano(), info.Size())
+}
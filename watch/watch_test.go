@@ -0,0 +1,162 @@
+package watch
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	flog "firefly-ec2-drift-detector/logger"
+	"firefly-ec2-drift-detector/models"
+	"firefly-ec2-drift-detector/output"
+)
+
+// newRun returns a run checking InstanceType of i-1, drifted to actual
+// unless actual is empty.
+func newRun(id, actual string) *output.Run {
+	report := &models.DriftReport{
+		InstanceID:   "i-1",
+		ResourceType: models.ResourceTypeInstance,
+		CheckedAttrs: []string{"InstanceType"},
+	}
+	if actual != "" {
+		report.AddAttributeDrift(models.AttributeDrift{
+			AttributeName: "InstanceType",
+			ExpectedValue: "t3.micro",
+			ActualValue:   actual,
+			DriftType:     models.DriftTypeValueMismatch,
+		})
+	}
+	return &output.Run{ID: id, Reports: []*models.DriftReport{report}}
+}
+
+func writeState(t *testing.T, path, content string, modTime time.Time) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWatcher_EmitsOnlyChanges(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), "terraform.tfstate")
+	modTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	writeState(t, statePath, "{}", modTime)
+
+	runs := []*output.Run{
+		newRun("run-1", "t3.large"),
+		newRun("run-2", "t3.large"),
+		newRun("run-3", "t3.xlarge"),
+		nil, // fails
+		newRun("run-5", ""),
+	}
+	var versions []string
+	scanFn := func(ctx context.Context, version string) (*output.Run, error) {
+		versions = append(versions, version)
+		run := runs[len(versions)-1]
+		if run == nil {
+			return nil, errors.New("state is being written")
+		}
+		return run, nil
+	}
+
+	var scans []*Scan
+	w := New(statePath, time.Minute, scanFn, func(s *Scan) { scans = append(scans, s) }, flog.NewTestLogger())
+	ctx := context.Background()
+
+	for i := range runs {
+		if i == 2 {
+			writeState(t, statePath, "{ }", modTime.Add(time.Minute))
+		}
+		err := w.tick(ctx)
+		if (err != nil) != (runs[i] == nil) {
+			t.Fatalf("scan %d: unexpected error %v", i+1, err)
+		}
+	}
+
+	if len(scans) != 4 {
+		t.Fatalf("expected 4 scans to be reported, got %d", len(scans))
+	}
+	if versions[0] == "" || versions[0] != versions[1] || versions[1] == versions[2] {
+		t.Errorf("expected the version to change with the state file only, got %q", versions)
+	}
+	if !scans[0].StateChanged || scans[1].StateChanged || !scans[2].StateChanged {
+		t.Errorf("unexpected StateChanged: %v, %v, %v", scans[0].StateChanged, scans[1].StateChanged, scans[2].StateChanged)
+	}
+
+	for i, want := range []string{EventNew, "", EventChanged, EventResolved} {
+		events := scans[i].Events()
+		switch {
+		case want == "" && len(events) != 0:
+			t.Errorf("scan %d: expected no events, got %+v", scans[i].Number, events)
+		case want != "" && (len(events) != 1 || events[0].Type != want):
+			t.Errorf("scan %d: expected one %s event, got %+v", scans[i].Number, want, events)
+		}
+	}
+	if events := scans[3].Events(); len(events) == 1 && (events[0].RunID != "run-5" || events[0].Scan != 5) {
+		t.Errorf("expected the event to come from scan 5, got %+v", events[0])
+	}
+}
+
+func TestWatcher_RunStopsOnCancel(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), "terraform.tfstate")
+	writeState(t, statePath, "{}", time.Now())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	scans := 0
+	scanFn := func(ctx context.Context, version string) (*output.Run, error) {
+		return newRun("run", ""), nil
+	}
+	onScan := func(s *Scan) {
+		if scans++; scans == 3 {
+			cancel()
+		}
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- New(statePath, time.Millisecond, scanFn, onScan, flog.NewTestLogger()).Run(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("expected no error on cancellation, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("watcher did not stop")
+	}
+	if scans != 3 {
+		t.Errorf("expected 3 scans, got %d", scans)
+	}
+}
+
+func TestStateVersion_Directory(t *testing.T) {
+	dir := t.TempDir()
+	modTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	writeState(t, filepath.Join(dir, "main.tf"), "", modTime)
+	writeState(t, filepath.Join(dir, "README.md"), "", modTime)
+
+	before, err := StateVersion(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	writeState(t, filepath.Join(dir, "README.md"), "changed", modTime.Add(time.Minute))
+	if after, _ := StateVersion(dir); after != before {
+		t.Error("expected files other than .tf not to change the version")
+	}
+
+	writeState(t, filepath.Join(dir, "main.tf"), "changed", modTime.Add(time.Minute))
+	if after, _ := StateVersion(dir); after == before {
+		t.Error("expected a changed .tf file to change the version")
+	}
+
+	if _, err := StateVersion(filepath.Join(dir, "missing")); err == nil {
+		t.Error("expected an error for a missing path")
+	}
+}